@@ -4,11 +4,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -16,6 +20,25 @@ import (
 	"github.com/Colstuwjx/job/errs"
 	"github.com/Colstuwjx/job/models"
 	"github.com/Colstuwjx/job/opm"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	// deadJobsActionDelete is the action of deleting the dead jobs
+	deadJobsActionDelete = "delete"
+
+	// queueActionPause is the action of pausing the job queue
+	queueActionPause = "pause"
+	// queueActionResume is the action of resuming the job queue
+	queueActionResume = "resume"
+
+	// scheduleFormatICal is the format of the upcoming schedule in iCalendar
+	scheduleFormatICal = "ical"
+	// maxScheduleWindow is the max time window (seconds) of listing the upcoming schedule
+	maxScheduleWindow = 31 * 24 * 3600
+
+	// idempotencyKeyHeader is the header carrying the idempotency key of the job submission
+	idempotencyKeyHeader = "Idempotency-Key"
 )
 
 // Handler defines approaches to handle the http requests.
@@ -23,12 +46,54 @@ type Handler interface {
 	// HandleLaunchJobReq is used to handle the job submission request.
 	HandleLaunchJobReq(w http.ResponseWriter, req *http.Request)
 
+	// HandleLaunchBatchReq is used to handle the batch job submission request.
+	HandleLaunchBatchReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetBatchReq is used to handle the batch status query request.
+	HandleGetBatchReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleGetPartitionReq is used to handle the partition backlog query request.
+	HandleGetPartitionReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleListJobsReq is used to handle the archived jobs listing request.
+	HandleListJobsReq(w http.ResponseWriter, req *http.Request)
+
 	// HandleGetJobReq is used to handle the job stats query request.
 	HandleGetJobReq(w http.ResponseWriter, req *http.Request)
 
+	// HandleJobResultReq is used to handle the job result query request.
+	HandleJobResultReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleJobCheckInsReq is used to handle the job check in history query request.
+	HandleJobCheckInsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleJobTimelineReq is used to handle the job status timeline query request.
+	HandleJobTimelineReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleUpdateJobReq is used to handle the request of changing the job which has not started yet.
+	HandleUpdateJobReq(w http.ResponseWriter, req *http.Request)
+
 	// HandleJobActionReq is used to handle the job action requests (stop/retry).
 	HandleJobActionReq(w http.ResponseWriter, req *http.Request)
 
+	// HandleListDeadJobsReq is used to handle the dead jobs listing request.
+	HandleListDeadJobsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleDeadJobsActionReq is used to handle the batch action requests (retry/delete) of the dead jobs.
+	HandleDeadJobsActionReq(w http.ResponseWriter, req *http.Request)
+
+	// HandlePurgeDeadJobsReq is used to handle the request of purging the old dead jobs.
+	HandlePurgeDeadJobsReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleScheduleReq is used to handle the upcoming schedule listing request.
+	HandleScheduleReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleQueuesReq is used to handle the job queues inspection request.
+	HandleQueuesReq(w http.ResponseWriter, req *http.Request)
+
+	// HandleQueueActionReq is used to handle the job queue action requests (pause/resume).
+	HandleQueueActionReq(w http.ResponseWriter, req *http.Request)
+
 	// HandleCheckStatusReq is used to handle the job service healthy status checking request.
 	HandleCheckStatusReq(w http.ResponseWriter, req *http.Request)
 
@@ -67,10 +132,32 @@ func (dh *DefaultHandler) HandleLaunchJobReq(w http.ResponseWriter, req *http.Re
 		return
 	}
 
+	// The idempotency key can be provided by the header or the job metadata
+	if key := req.Header.Get(idempotencyKeyHeader); !utils.IsEmptyStr(key) && jobReq.Job != nil && jobReq.Job.Metadata != nil {
+		if !utils.IsEmptyStr(jobReq.Job.Metadata.IdempotencyKey) && jobReq.Job.Metadata.IdempotencyKey != key {
+			dh.handleError(w, http.StatusBadRequest, errs.LaunchJobError(fmt.Errorf("header '%s' conflicts with 'idempotency_key' of the job metadata", idempotencyKeyHeader)))
+			return
+		}
+		jobReq.Job.Metadata.IdempotencyKey = key
+	}
+
 	// Pass request to the controller for the follow-up.
 	jobStats, err := dh.controller.LaunchJob(jobReq)
 	if err != nil {
-		dh.handleError(w, http.StatusInternalServerError, errs.LaunchJobError(err))
+		code := http.StatusInternalServerError
+		backErr := errs.LaunchJobError(err)
+
+		if errs.IsInvalidParametersError(err) {
+			code = http.StatusBadRequest
+			backErr = err
+		}
+
+		if errs.IsIdempotencyConflictError(err) {
+			code = http.StatusConflict
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
 		return
 	}
 
@@ -83,6 +170,153 @@ func (dh *DefaultHandler) HandleLaunchJobReq(w http.ResponseWriter, req *http.Re
 	w.Write(data)
 }
 
+// HandleLaunchBatchReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleLaunchBatchReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	// unmarshal data
+	batchReq := models.JobBatchRequest{}
+	if err = json.Unmarshal(data, &batchReq); err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		return
+	}
+
+	batch, err := dh.controller.LaunchBatch(batchReq)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.LaunchBatchError(err)
+
+		if errs.IsInvalidParametersError(err) {
+			code = http.StatusBadRequest
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, batch)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(data)
+}
+
+// HandleGetBatchReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetBatchReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	batchID := vars["batch_id"]
+
+	batch, err := dh.controller.GetBatch(batchID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.GetBatchError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, batch)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleGetPartitionReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleGetPartitionReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	partitionKey := vars["partition_key"]
+
+	page, pageSize, err := parsePaging(req.URL.Query())
+	if err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.GetPartitionError(err))
+		return
+	}
+
+	partition, err := dh.controller.GetPartition(partitionKey, page, pageSize)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.GetPartitionError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, partition)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleListJobsReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleListJobsReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	query, err := parseJobQuery(req)
+	if err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.ListJobsError(err))
+		return
+	}
+
+	jobs, err := dh.controller.ListJobs(query)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.ListJobsError(err)
+
+		if errs.IsArchiveDisabledError(err) {
+			code = http.StatusNotImplemented
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, jobs)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // HandleGetJobReq is implementation of method defined in interface 'Handler'
 func (dh *DefaultHandler) HandleGetJobReq(w http.ResponseWriter, req *http.Request) {
 	if !dh.preCheck(w) {
@@ -115,6 +349,157 @@ func (dh *DefaultHandler) HandleGetJobReq(w http.ResponseWriter, req *http.Reque
 	w.Write(data)
 }
 
+// HandleJobResultReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleJobResultReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	jobID := vars["job_id"]
+
+	jobResult, err := dh.controller.GetJobResult(jobID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.GetJobResultError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, jobResult)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleJobCheckInsReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleJobCheckInsReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	jobID := vars["job_id"]
+
+	history, err := dh.controller.GetJobCheckIns(jobID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.GetJobCheckInsError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, history)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleJobTimelineReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleJobTimelineReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	jobID := vars["job_id"]
+
+	timeline, err := dh.controller.GetJobTimeline(jobID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.GetJobTimelineError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, timeline)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleUpdateJobReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleUpdateJobReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	jobID := vars["job_id"]
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	// unmarshal data
+	updateReq := models.JobUpdateRequest{}
+	if err = json.Unmarshal(data, &updateReq); err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		return
+	}
+
+	if err := validJobUpdateReq(updateReq); err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.UpdateJobError(err))
+		return
+	}
+
+	jobStats, err := dh.controller.UpdateJob(jobID, updateReq)
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.UpdateJobError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		if errs.IsInvalidParametersError(err) {
+			code = http.StatusBadRequest
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, jobStats)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // HandleJobActionReq is implementation of method defined in interface 'Handler'
 func (dh *DefaultHandler) HandleJobActionReq(w http.ResponseWriter, req *http.Request) {
 	if !dh.preCheck(w) {
@@ -185,6 +570,242 @@ func (dh *DefaultHandler) HandleJobActionReq(w http.ResponseWriter, req *http.Re
 	w.WriteHeader(http.StatusNoContent) // only header, no content returned
 }
 
+// HandleListDeadJobsReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleListDeadJobsReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	values := req.URL.Query()
+	filter := &models.DeadJobFilter{
+		Name: values.Get("name"),
+	}
+
+	olderThan, err := parseInt64Param(values, "older_than")
+	if err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.ListDeadJobsError(err))
+		return
+	}
+	filter.OlderThan = olderThan
+
+	page, pageSize, err := parsePaging(values)
+	if err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.ListDeadJobsError(err))
+		return
+	}
+
+	deadJobs, err := dh.controller.ListDeadJobs(filter, page, pageSize)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.ListDeadJobsError(err))
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, deadJobs)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleDeadJobsActionReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleDeadJobsActionReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	// unmarshal data
+	actionReq := models.DeadJobsActionRequest{}
+	if err = json.Unmarshal(data, &actionReq); err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		return
+	}
+
+	if len(actionReq.JobIDs) == 0 && actionReq.Filter == nil {
+		dh.handleError(w, http.StatusBadRequest, errs.DeadJobsActionError(errors.New("either 'job_ids' or 'filter' should be specified")))
+		return
+	}
+
+	if actionReq.Filter != nil && actionReq.Filter.OlderThan < 0 {
+		dh.handleError(w, http.StatusBadRequest, errs.DeadJobsActionError(fmt.Errorf("'older_than' should not be negative but got %d", actionReq.Filter.OlderThan)))
+		return
+	}
+
+	var result models.DeadJobsActionResult
+	switch actionReq.Action {
+	case opm.CtlCommandRetry:
+		result, err = dh.controller.RetryDeadJobs(actionReq.JobIDs, actionReq.Filter)
+	case deadJobsActionDelete:
+		result, err = dh.controller.DeleteDeadJobs(actionReq.JobIDs, actionReq.Filter)
+	default:
+		dh.handleError(w, http.StatusNotImplemented, errs.UnknownActionNameError(fmt.Errorf("%s", actionReq.Action)))
+		return
+	}
+
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.DeadJobsActionError(err))
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, result)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandlePurgeDeadJobsReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandlePurgeDeadJobsReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	values := req.URL.Query()
+	if len(values.Get("older_than")) == 0 {
+		dh.handleError(w, http.StatusBadRequest, errs.DeadJobsActionError(errors.New("'older_than' is required")))
+		return
+	}
+
+	olderThan, err := parseInt64Param(values, "older_than")
+	if err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.DeadJobsActionError(err))
+		return
+	}
+
+	result, err := dh.controller.PurgeDeadJobs(olderThan)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.DeadJobsActionError(err))
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, result)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleScheduleReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleScheduleReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	values := req.URL.Query()
+	from, to, err := parseScheduleWindow(values)
+	if err != nil {
+		dh.handleError(w, http.StatusBadRequest, errs.GetScheduleError(err))
+		return
+	}
+
+	format := values.Get("format")
+	if len(format) > 0 && format != scheduleFormatICal {
+		dh.handleError(w, http.StatusBadRequest, errs.GetScheduleError(fmt.Errorf("format '%s' is not supported", format)))
+		return
+	}
+
+	schedule, err := dh.controller.GetSchedule(from, to)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.GetScheduleError(err))
+		return
+	}
+
+	if format == scheduleFormatICal {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(encodeICalendar(schedule, time.Now()))
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, schedule)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleQueuesReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleQueuesReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	queues, err := dh.controller.GetQueues()
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.GetQueuesError(err))
+		return
+	}
+
+	data, ok := dh.handleJSONData(w, queues)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleQueueActionReq is implementation of method defined in interface 'Handler'
+func (dh *DefaultHandler) HandleQueueActionReq(w http.ResponseWriter, req *http.Request) {
+	if !dh.preCheck(w) {
+		return
+	}
+
+	vars := mux.Vars(req)
+	jobName := vars["job_name"]
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.ReadRequestBodyError(err))
+		return
+	}
+
+	// unmarshal data
+	actionReq := models.JobActionRequest{}
+	if err = json.Unmarshal(data, &actionReq); err != nil {
+		dh.handleError(w, http.StatusInternalServerError, errs.HandleJSONDataError(err))
+		return
+	}
+
+	switch actionReq.Action {
+	case queueActionPause:
+		err = dh.controller.PauseQueue(jobName)
+	case queueActionResume:
+		err = dh.controller.ResumeQueue(jobName)
+	default:
+		dh.handleError(w, http.StatusNotImplemented, errs.UnknownActionNameError(fmt.Errorf("%s", actionReq.Action)))
+		return
+	}
+
+	if err != nil {
+		code := http.StatusInternalServerError
+		backErr := errs.QueueActionError(err)
+
+		if errs.IsObjectNotFoundError(err) {
+			code = http.StatusNotFound
+			backErr = err
+		}
+
+		dh.handleError(w, code, backErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent) // only header, no content returned
+}
+
 // HandleCheckStatusReq is implementation of method defined in interface 'Handler'
 func (dh *DefaultHandler) HandleCheckStatusReq(w http.ResponseWriter, req *http.Request) {
 	if !dh.preCheck(w) {
@@ -238,6 +859,109 @@ func (dh *DefaultHandler) HandleJobLogReq(w http.ResponseWriter, req *http.Reque
 	w.Write(logData)
 }
 
+// validJobUpdateReq checks if the job update request is well-formed
+func validJobUpdateReq(req models.JobUpdateRequest) error {
+	if req.Parameters == nil && req.RunAt == 0 && req.ScheduleDelay == 0 {
+		return errors.New("at least one of 'parameters', 'run_at' and 'schedule_delay' should be specified")
+	}
+
+	if req.RunAt != 0 && req.ScheduleDelay > 0 {
+		return errors.New("'run_at' and 'schedule_delay' can not be specified at the same time")
+	}
+
+	if req.RunAt != 0 && req.RunAt <= time.Now().Unix() {
+		return fmt.Errorf("'run_at' %d should be in the future", req.RunAt)
+	}
+
+	return nil
+}
+
+// parseScheduleWindow reads the time window of listing the upcoming schedule from the url parameters
+func parseScheduleWindow(values url.Values) (int64, int64, error) {
+	from, err := parseInt64Param(values, "from")
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := parseInt64Param(values, "to")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := from
+	if start == 0 {
+		start = time.Now().Unix()
+	}
+
+	if to > 0 {
+		if start > to {
+			return 0, 0, fmt.Errorf("'from' %d is after 'to' %d", start, to)
+		}
+
+		if to-start > maxScheduleWindow {
+			return 0, 0, fmt.Errorf("time window should not exceed %d seconds", maxScheduleWindow)
+		}
+	}
+
+	return from, to, nil
+}
+
+// parseJobQuery reads the query conditions of listing jobs from the url parameters
+func parseJobQuery(req *http.Request) (models.JobQuery, error) {
+	values := req.URL.Query()
+	query := models.JobQuery{
+		Name:   values.Get("name"),
+		Kind:   values.Get("kind"),
+		Status: values.Get("status"),
+	}
+
+	var err error
+	if query.Since, err = parseInt64Param(values, "since"); err != nil {
+		return query, err
+	}
+	if query.Until, err = parseInt64Param(values, "until"); err != nil {
+		return query, err
+	}
+
+	if query.Since > 0 && query.Until > 0 && query.Since > query.Until {
+		return query, fmt.Errorf("'since' %d is after 'until' %d", query.Since, query.Until)
+	}
+
+	query.Page, query.PageSize, err = parsePaging(values)
+
+	return query, err
+}
+
+// parseInt64Param reads the non-negative integer parameter, 0 if not set
+func parseInt64Param(values url.Values, name string) (int64, error) {
+	raw := values.Get(name)
+	if len(raw) == 0 {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("'%s' should be a non-negative integer but got '%s'", name, raw)
+	}
+
+	return n, nil
+}
+
+// parsePaging reads the 'page' and 'page_size' parameters, 0 if not set
+func parsePaging(values url.Values) (uint, uint, error) {
+	paging := make([]uint, 2)
+	for i, name := range []string{"page", "page_size"} {
+		if raw := values.Get(name); len(raw) > 0 {
+			n, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil || n == 0 {
+				return 0, 0, fmt.Errorf("'%s' should be a positive integer but got '%s'", name, raw)
+			}
+			paging[i] = uint(n)
+		}
+	}
+
+	return paging[0], paging[1], nil
+}
+
 func (dh *DefaultHandler) handleJSONData(w http.ResponseWriter, object interface{}) ([]byte, bool) {
 	data, err := json.Marshal(object)
 	if err != nil {
@@ -16,10 +16,15 @@ import (
 	"time"
 
 	"github.com/Colstuwjx/job/env"
+	"github.com/Colstuwjx/job/errs"
 	"github.com/Colstuwjx/job/models"
 )
 
-const fakeSecret = "I'mfakesecret"
+const (
+	fakeSecret = "I'mfakesecret"
+	// The secret is sent as it is without any prefix
+	secretPrefix = ""
+)
 
 var testingAuthProvider = &SecretAuthenticator{}
 var testingHandler = NewDefaultHandler(&fakeController{})
@@ -93,6 +98,154 @@ func TestLaunchJobSucceed(t *testing.T) {
 	ctx.WG.Wait()
 }
 
+func TestLaunchJobWithIdempotencyKey(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	url := fmt.Sprintf("http://localhost:%d/api/v1/jobs", port)
+	_, code, err := postReqWithIdempotencyKey(url, createJobReq(true), "conflicted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusConflict {
+		t.Fatalf("expect status code %d but got %d\n", http.StatusConflict, code)
+	}
+
+	jobReq := models.JobRequest{}
+	if err := json.Unmarshal(createJobReq(true), &jobReq); err != nil {
+		t.Fatal(err)
+	}
+	jobReq.Job.Metadata.IdempotencyKey = "another"
+	data, err := json.Marshal(&jobReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, code, err = postReqWithIdempotencyKey(url, data, "conflicted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusBadRequest {
+		t.Fatalf("expect status code %d but got %d\n", http.StatusBadRequest, code)
+	}
+
+	_, code, err = postReqWithIdempotencyKey(url, createJobReq(true), "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusAccepted {
+		t.Fatalf("expect status code %d but got %d\n", http.StatusAccepted, code)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestLaunchBatch(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	url := fmt.Sprintf("http://localhost:%d/api/v1/batches", port)
+	data, err := json.Marshal(&models.JobBatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postReq(url, data); err == nil {
+		t.Fatal("expect error of empty batch but got nil")
+	}
+
+	jobReq := models.JobRequest{}
+	if err := json.Unmarshal(createJobReq(true), &jobReq); err != nil {
+		t.Fatal(err)
+	}
+	data, err = json.Marshal(&models.JobBatchRequest{
+		Jobs: []*models.JobData{jobReq.Job, jobReq.Job},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := postReq(url, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := models.JobBatch{}
+	if err := json.Unmarshal(res, &batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.BatchID != "fake_batch_ok" || batch.Total != 2 {
+		t.Fatalf("expect batch 'fake_batch_ok' with 2 jobs but got %+v\n", batch)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestGetBatch(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	if _, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/batches/fake_batch", port)); err == nil {
+		t.Fatal("expect error of unknown batch but got nil")
+	}
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/batches/fake_batch_ok", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := models.JobBatch{}
+	if err := json.Unmarshal(res, &batch); err != nil {
+		t.Fatal(err)
+	}
+	if !batch.Completed || batch.Counts["Success"] != 1 {
+		t.Fatalf("expect completed batch with 1 succeeded job but got %+v\n", batch)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestGetPartition(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	if _, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/partitions/fake_partition", port)); err == nil {
+		t.Fatal("expect error of unknown partition but got nil")
+	}
+
+	if _, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/partitions/library/photon?page=x", port)); err == nil {
+		t.Fatal("expect error of invalid page but got nil")
+	}
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/partitions/library/photon", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partition := models.JobPartition{}
+	if err := json.Unmarshal(res, &partition); err != nil {
+		t.Fatal(err)
+	}
+	if partition.PartitionKey != "library/photon" || partition.Running != "fake_ID" || partition.Total != 1 {
+		t.Fatalf("expect partition 'library/photon' with 1 pending job but got %+v\n", partition)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
 func TestGetJobFailed(t *testing.T) {
 	exportUISecret(fakeSecret)
 
@@ -134,6 +287,169 @@ func TestGetJobSucceed(t *testing.T) {
 	ctx.WG.Wait()
 }
 
+func TestGetJobResult(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs/fake_job_ok/result", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := models.JobResult{}
+	if err := json.Unmarshal(res, &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if obj.JobID != "fake_job_ok" || string(obj.Result) != `{"count":1}` {
+		t.Fatalf("expect result of job 'fake_job_ok' but got '%s'\n", res)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestGetJobCheckIns(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs/fake_job_ok/checkins", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := models.JobCheckIns{}
+	if err := json.Unmarshal(res, &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(obj.CheckIns) != 1 || obj.CheckIns[0].Message != "30%" {
+		t.Fatalf("expect check in '30%%' of job 'fake_job_ok' but got '%s'\n", res)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestGetJobTimeline(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs/fake_job_ok/timeline", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := models.JobStatusTimeline{}
+	if err := json.Unmarshal(res, &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(obj.Timeline) != 1 || obj.Timeline[0].Status != "Pending" {
+		t.Fatalf("expect status 'Pending' in timeline of job 'fake_job_ok' but got '%s'\n", res)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestListJobs(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs?status=Error&page=1&page_size=10", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := models.JobList{}
+	if err := json.Unmarshal(res, &obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if obj.Total != 1 || len(obj.Jobs) != 1 || obj.Jobs[0].LastError != "boom" {
+		t.Fatalf("expect 1 failed job with error details but got '%s'\n", res)
+	}
+
+	if _, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs?since=100&until=10", port)); err == nil {
+		t.Fatal("expect error for the invalid time range but got nil")
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestDeadJobs(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	res, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/dead?name=fake_job&page=1", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := models.DeadJobList{}
+	if err := json.Unmarshal(res, &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Jobs[0].LastError != "boom" {
+		t.Fatalf("expect 1 dead job with error but got '%s'\n", res)
+	}
+
+	data, err := json.Marshal(&models.DeadJobsActionRequest{
+		Action: "retry",
+		JobIDs: []string{"fake_job_ok"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err = postReq(fmt.Sprintf("http://localhost:%d/api/v1/dead", port), data); err != nil {
+		t.Fatal(err)
+	}
+
+	result := models.DeadJobsActionResult{}
+	if err := json.Unmarshal(res, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Action != "retry" || result.Succeeded != 1 {
+		t.Fatalf("expect 1 dead job retried but got '%s'\n", res)
+	}
+
+	// Neither job IDs nor filter
+	if _, err = postReq(fmt.Sprintf("http://localhost:%d/api/v1/dead", port), []byte(`{"action":"delete"}`)); err == nil {
+		t.Fatal("expect error for the unselective action but got nil")
+	}
+
+	if _, err = deleteReq(fmt.Sprintf("http://localhost:%d/api/v1/dead", port)); err == nil {
+		t.Fatal("expect error for purging without age but got nil")
+	}
+
+	if res, err = deleteReq(fmt.Sprintf("http://localhost:%d/api/v1/dead?older_than=3600", port)); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(res, &result); err != nil || result.Succeeded != 2 {
+		t.Fatalf("expect 2 dead jobs purged but got '%s'\n", res)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
 func TestJobActionFailed(t *testing.T) {
 	exportUISecret(fakeSecret)
 
@@ -210,6 +526,146 @@ func TestJobActionSucceed(t *testing.T) {
 	ctx.WG.Wait()
 }
 
+func TestUpdateJob(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	runAt := time.Now().Unix() + 60
+	updateReq, err := json.Marshal(models.JobUpdateRequest{RunAt: runAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resData, code, err := patchReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs/fake_job_ok", port), updateReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("expect status code 200 but got %d", code)
+	}
+
+	jobStats := &models.JobStats{}
+	if err := json.Unmarshal(resData, jobStats); err != nil {
+		t.Fatal(err)
+	}
+	if jobStats.Stats.RunAt != runAt {
+		t.Fatalf("expect run at %d but got %d", runAt, jobStats.Stats.RunAt)
+	}
+
+	if _, code, _ = patchReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs/fake_job_ok", port), []byte("{}")); code != http.StatusBadRequest {
+		t.Fatalf("expect status code 400 for empty update but got %d", code)
+	}
+
+	if _, code, _ = patchReq(fmt.Sprintf("http://localhost:%d/api/v1/jobs/fake_job_nil", port), updateReq); code != http.StatusNotFound {
+		t.Fatalf("expect status code 404 but got %d", code)
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestGetSchedule(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	resData, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/schedule?from=100&to=200", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := &models.JobSchedule{}
+	if err := json.Unmarshal(resData, schedule); err != nil {
+		t.Fatal(err)
+	}
+	if schedule.From != 100 || schedule.To != 200 || len(schedule.Entries) != 1 {
+		t.Fatalf("expect 1 entry in [100, 200] but got %d in [%d, %d]", len(schedule.Entries), schedule.From, schedule.To)
+	}
+
+	resData, err = getReq(fmt.Sprintf("http://localhost:%d/api/v1/schedule?format=ical", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resData), "UID:fake_policy-1539164986@jobservice\r\n") {
+		t.Fatalf("expect the event of the periodic policy in the calendar but got %s", resData)
+	}
+
+	if _, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/schedule?from=200&to=100", port)); err == nil {
+		t.Fatal("expect error for the reversed time window but got nil")
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestGetQueues(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	resData, err := getReq(fmt.Sprintf("http://localhost:%d/api/v1/queues", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queues := &models.JobQueues{}
+	if err := json.Unmarshal(resData, queues); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queues.Queues) != 1 || queues.Queues[0].Pending != 2 || !queues.Queues[0].Paused {
+		t.Fatalf("expect 1 paused queue with 2 pending jobs but got %d queues", len(queues.Queues))
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
+func TestQueueAction(t *testing.T) {
+	exportUISecret(fakeSecret)
+
+	server, port, ctx := createServer()
+	server.Start()
+	<-time.After(200 * time.Millisecond)
+
+	for _, action := range []string{"pause", "resume"} {
+		actionReq, err := createJobActionReq(action)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := postReq(fmt.Sprintf("http://localhost:%d/api/v1/queues/fake_job", port), actionReq); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actionReq, err := createJobActionReq("pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postReq(fmt.Sprintf("http://localhost:%d/api/v1/queues/unknown_job", port), actionReq); err == nil {
+		t.Fatal("expect error for unknown job name but got nil")
+	}
+
+	actionReq, err = createJobActionReq("drain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postReq(fmt.Sprintf("http://localhost:%d/api/v1/queues/fake_job", port), actionReq); err == nil {
+		t.Fatal("expect error for unknown action but got nil")
+	}
+
+	server.Stop()
+	ctx.WG.Wait()
+}
+
 func TestCheckStatus(t *testing.T) {
 	exportUISecret(fakeSecret)
 
@@ -390,6 +846,80 @@ func getReq(url string) ([]byte, error) {
 	return data, nil
 }
 
+func postReqWithIdempotencyKey(url string, data []byte, key string) ([]byte, int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(data)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set(authHeader, fmt.Sprintf("%s %s", secretPrefix, fakeSecret))
+	req.Header.Set(idempotencyKeyHeader, key)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer res.Body.Close()
+
+	resData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resData, res.StatusCode, nil
+}
+
+func patchReq(url string, data []byte) ([]byte, int, error) {
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(string(data)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set(authHeader, fmt.Sprintf("%s %s", secretPrefix, fakeSecret))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer res.Body.Close()
+
+	resData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resData, res.StatusCode, nil
+}
+
+func deleteReq(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(authHeader, fmt.Sprintf("%s %s", secretPrefix, fakeSecret))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return data, fmt.Errorf("expect status code '200', but got '%d'", res.StatusCode)
+	}
+
+	return data, nil
+}
+
 func exportUISecret(secret string) {
 	os.Setenv("CORE_SECRET", secret)
 }
@@ -400,10 +930,53 @@ func (fc *fakeController) LaunchJob(req models.JobRequest) (models.JobStats, err
 	if req.Job.Name != "fake_job_ok" || req.Job.Metadata == nil {
 		return models.JobStats{}, errors.New("failed")
 	}
+	if req.Job.Metadata.IdempotencyKey == "conflicted" {
+		return models.JobStats{}, errs.IdempotencyConflictError("conflicted", "already used by a different request")
+	}
 
 	return createJobStats(req.Job.Name, req.Job.Metadata.JobKind, req.Job.Metadata.Cron), nil
 }
 
+func (fc *fakeController) LaunchBatch(req models.JobBatchRequest) (models.JobBatch, error) {
+	if len(req.Jobs) == 0 {
+		return models.JobBatch{}, errs.InvalidParametersError("empty batch")
+	}
+
+	return models.JobBatch{
+		BatchID: "fake_batch_ok",
+		Total:   int64(len(req.Jobs)),
+		Counts:  map[string]int64{"Pending": int64(len(req.Jobs))},
+	}, nil
+}
+
+func (fc *fakeController) GetPartition(partitionKey string, page, pageSize uint) (models.JobPartition, error) {
+	if partitionKey != "library/photon" {
+		return models.JobPartition{}, errs.NoObjectFoundError(partitionKey)
+	}
+
+	return models.JobPartition{
+		PartitionKey: partitionKey,
+		Running:      "fake_ID",
+		Total:        1,
+		Jobs: []*models.PartitionedJob{
+			{JobID: "fake_ID_2", JobName: "fake_job"},
+		},
+	}, nil
+}
+
+func (fc *fakeController) GetBatch(batchID string) (models.JobBatch, error) {
+	if batchID != "fake_batch_ok" {
+		return models.JobBatch{}, errs.NoObjectFoundError(batchID)
+	}
+
+	return models.JobBatch{
+		BatchID:   batchID,
+		Total:     1,
+		Completed: true,
+		Counts:    map[string]int64{"Success": 1},
+	}, nil
+}
+
 func (fc *fakeController) GetJob(jobID string) (models.JobStats, error) {
 	if jobID != "fake_job_ok" {
 		return models.JobStats{}, errors.New("failed")
@@ -412,6 +985,101 @@ func (fc *fakeController) GetJob(jobID string) (models.JobStats, error) {
 	return createJobStats("testing", "Generic", ""), nil
 }
 
+func (fc *fakeController) GetJobResult(jobID string) (models.JobResult, error) {
+	if jobID != "fake_job_ok" {
+		return models.JobResult{}, errors.New("failed")
+	}
+
+	return models.JobResult{
+		JobID:  jobID,
+		Result: []byte(`{"count":1}`),
+	}, nil
+}
+
+func (fc *fakeController) GetJobCheckIns(jobID string) (models.JobCheckIns, error) {
+	if jobID != "fake_job_ok" {
+		return models.JobCheckIns{}, errors.New("failed")
+	}
+
+	return models.JobCheckIns{
+		JobID: jobID,
+		CheckIns: []*models.JobCheckIn{
+			{
+				Message:   "30%",
+				CheckInAt: time.Now().Unix(),
+			},
+		},
+	}, nil
+}
+
+func (fc *fakeController) GetJobTimeline(jobID string) (models.JobStatusTimeline, error) {
+	if jobID != "fake_job_ok" {
+		return models.JobStatusTimeline{}, errors.New("failed")
+	}
+
+	return models.JobStatusTimeline{
+		JobID: jobID,
+		Timeline: []*models.JobStatusTransition{
+			{
+				Status:   "Pending",
+				Revision: 1,
+				Time:     time.Now().Unix(),
+			},
+		},
+	}, nil
+}
+
+func (fc *fakeController) ListJobs(query models.JobQuery) (models.JobList, error) {
+	return models.JobList{
+		Total: 1,
+		Jobs: []*models.JobStatData{
+			{
+				JobID:     "fake_job_ok",
+				Status:    query.Status,
+				LastError: "boom",
+			},
+		},
+	}, nil
+}
+
+func (fc *fakeController) ListDeadJobs(filter *models.DeadJobFilter, page, pageSize uint) (models.DeadJobList, error) {
+	return models.DeadJobList{
+		Total: 1,
+		Jobs: []*models.DeadJob{
+			{
+				JobID:     "fake_job_ok",
+				Name:      filter.Name,
+				Fails:     4,
+				LastError: "boom",
+			},
+		},
+	}, nil
+}
+
+func (fc *fakeController) RetryDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	return models.DeadJobsActionResult{
+		Action:    "retry",
+		Matched:   len(jobIDs),
+		Succeeded: len(jobIDs),
+	}, nil
+}
+
+func (fc *fakeController) DeleteDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	return models.DeadJobsActionResult{
+		Action:    "delete",
+		Matched:   len(jobIDs),
+		Succeeded: len(jobIDs),
+	}, nil
+}
+
+func (fc *fakeController) PurgeDeadJobs(olderThan int64) (models.DeadJobsActionResult, error) {
+	return models.DeadJobsActionResult{
+		Action:    "delete",
+		Matched:   2,
+		Succeeded: 2,
+	}, nil
+}
+
 func (fc *fakeController) StopJob(jobID string) error {
 	if jobID == "fake_job_ok" {
 		return nil
@@ -436,6 +1104,61 @@ func (fc *fakeController) CancelJob(jobID string) error {
 	return errors.New("failed")
 }
 
+func (fc *fakeController) UpdateJob(jobID string, req models.JobUpdateRequest) (models.JobStats, error) {
+	if jobID != "fake_job_ok" {
+		return models.JobStats{}, errs.NoObjectFoundError(jobID)
+	}
+
+	return models.JobStats{
+		Stats: &models.JobStatData{
+			JobID:  jobID,
+			Status: "Scheduled",
+			RunAt:  req.RunAt,
+		},
+	}, nil
+}
+
+func (fc *fakeController) GetSchedule(from, to int64) (models.JobSchedule, error) {
+	return models.JobSchedule{
+		From: from,
+		To:   to,
+		Entries: []*models.ScheduleEntry{{
+			RunAt:        1539164986,
+			JobName:      "fake_job",
+			JobKind:      "Periodic",
+			PolicyID:     "fake_policy",
+			ParamsDigest: "fake_digest",
+		}},
+	}, nil
+}
+
+func (fc *fakeController) GetQueues() (models.JobQueues, error) {
+	return models.JobQueues{
+		Queues: []*models.JobQueue{{
+			JobName: "fake_job",
+			Pending: 2,
+			Latency: 10,
+			Paused:  true,
+		}},
+	}, nil
+}
+
+func (fc *fakeController) PauseQueue(jobName string) error {
+	if jobName == "fake_job" {
+		return nil
+	}
+
+	return errs.NoObjectFoundError(jobName)
+}
+
+func (fc *fakeController) ResumeQueue(jobName string) error {
+	if jobName == "fake_job" {
+		return nil
+	}
+
+	return errs.NoObjectFoundError(jobName)
+}
+
 func (fc *fakeController) CheckStatus() (models.JobPoolStats, error) {
 	return models.JobPoolStats{
 		Pools: []*models.JobPoolStatsData{{
@@ -0,0 +1,83 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Colstuwjx/job/models"
+)
+
+const (
+	icalTimeFormat = "20060102T150405Z"
+	// The max octets of one content line excluding the line break
+	icalLineLimit = 75
+)
+
+var icalTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\n", `\n`,
+)
+
+// encodeICalendar renders the upcoming runs as the iCalendar (RFC 5545) data,
+// each run is one event without duration.
+func encodeICalendar(schedule models.JobSchedule, now time.Time) []byte {
+	buf := &bytes.Buffer{}
+	stamp := now.UTC().Format(icalTimeFormat)
+
+	writeICalLine(buf, "BEGIN:VCALENDAR")
+	writeICalLine(buf, "VERSION:2.0")
+	writeICalLine(buf, "PRODID:-//jobservice//schedule//EN")
+	writeICalLine(buf, "CALSCALE:GREGORIAN")
+
+	for _, e := range schedule.Entries {
+		id := e.JobID
+		if len(id) == 0 {
+			id = e.PolicyID
+		}
+
+		desc := fmt.Sprintf("kind: %s\nparams digest: %s", e.JobKind, e.ParamsDigest)
+		if len(e.PolicyID) > 0 {
+			desc = fmt.Sprintf("%s\npolicy: %s", desc, e.PolicyID)
+		}
+
+		writeICalLine(buf, "BEGIN:VEVENT")
+		writeICalLine(buf, fmt.Sprintf("UID:%s-%d@jobservice", id, e.RunAt))
+		writeICalLine(buf, "DTSTAMP:"+stamp)
+		writeICalLine(buf, "DTSTART:"+time.Unix(e.RunAt, 0).UTC().Format(icalTimeFormat))
+		writeICalLine(buf, "SUMMARY:"+icalTextEscaper.Replace(e.JobName))
+		writeICalLine(buf, "DESCRIPTION:"+icalTextEscaper.Replace(desc))
+		writeICalLine(buf, "END:VEVENT")
+	}
+
+	writeICalLine(buf, "END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+// writeICalLine writes the content line and folds it if it's too long,
+// the multi-octet characters are not split.
+func writeICalLine(buf *bytes.Buffer, line string) {
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of the continuation line takes one octet
+		limit = icalLineLimit - 1
+	}
+
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
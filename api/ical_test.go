@@ -0,0 +1,42 @@
+// Copyright Project Harbor Authors. All rights reserved.
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Colstuwjx/job/models"
+)
+
+func TestEncodeICalendar(t *testing.T) {
+	schedule := models.JobSchedule{
+		Entries: []*models.ScheduleEntry{
+			{
+				RunAt:        1539164986,
+				JobName:      "demo, nightly",
+				JobKind:      "Scheduled",
+				JobID:        "fake_job_ID",
+				ParamsDigest: strings.Repeat("a", 64),
+			},
+		},
+	}
+
+	data := string(encodeICalendar(schedule, time.Unix(1539164000, 0)))
+
+	if !strings.HasPrefix(data, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(data, "END:VCALENDAR\r\n") {
+		t.Fatalf("expect the calendar wrapped with VCALENDAR but got %s", data)
+	}
+	if !strings.Contains(data, "DTSTART:20181010T094946Z\r\n") {
+		t.Errorf("expect the start time in UTC but got %s", data)
+	}
+	if !strings.Contains(data, `SUMMARY:demo\, nightly`) {
+		t.Errorf("expect the comma escaped but got %s", data)
+	}
+
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Errorf("expect the line folded within %d octets but got %d", icalLineLimit, len(line))
+		}
+	}
+}
@@ -68,8 +68,23 @@ func (br *BaseRouter) registerRoutes() {
 	subRouter := br.router.PathPrefix(fmt.Sprintf("%s/%s", baseRoute, apiVersion)).Subrouter()
 
 	subRouter.HandleFunc("/jobs", br.handler.HandleLaunchJobReq).Methods(http.MethodPost)
+	subRouter.HandleFunc("/jobs", br.handler.HandleListJobsReq).Methods(http.MethodGet)
 	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleGetJobReq).Methods(http.MethodGet)
 	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleJobActionReq).Methods(http.MethodPost)
+	subRouter.HandleFunc("/jobs/{job_id}", br.handler.HandleUpdateJobReq).Methods(http.MethodPatch)
+	subRouter.HandleFunc("/jobs/{job_id}/result", br.handler.HandleJobResultReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/jobs/{job_id}/checkins", br.handler.HandleJobCheckInsReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/jobs/{job_id}/timeline", br.handler.HandleJobTimelineReq).Methods(http.MethodGet)
 	subRouter.HandleFunc("/jobs/{job_id}/log", br.handler.HandleJobLogReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/batches", br.handler.HandleLaunchBatchReq).Methods(http.MethodPost)
+	subRouter.HandleFunc("/batches/{batch_id}", br.handler.HandleGetBatchReq).Methods(http.MethodGet)
+	// The partition key like the repository name may contain '/'
+	subRouter.HandleFunc("/partitions/{partition_key:.+}", br.handler.HandleGetPartitionReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/dead", br.handler.HandleListDeadJobsReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/dead", br.handler.HandleDeadJobsActionReq).Methods(http.MethodPost)
+	subRouter.HandleFunc("/dead", br.handler.HandlePurgeDeadJobsReq).Methods(http.MethodDelete)
+	subRouter.HandleFunc("/schedule", br.handler.HandleScheduleReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/queues", br.handler.HandleQueuesReq).Methods(http.MethodGet)
+	subRouter.HandleFunc("/queues/{job_name}", br.handler.HandleQueueActionReq).Methods(http.MethodPost)
 	subRouter.HandleFunc("/stats", br.handler.HandleCheckStatusReq).Methods(http.MethodGet)
 }
@@ -0,0 +1,174 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package archive
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	// register the sqlite driver
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	sqliteDriver = "sqlite3"
+
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	createSchema = `
+CREATE TABLE IF NOT EXISTS job_history (
+  id           TEXT PRIMARY KEY,
+  name         TEXT NOT NULL,
+  kind         TEXT NOT NULL,
+  status       TEXT NOT NULL,
+  enqueue_time INTEGER NOT NULL DEFAULT 0,
+  update_time  INTEGER NOT NULL DEFAULT 0,
+  archived_at  INTEGER NOT NULL DEFAULT 0,
+  stats        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_job_history_update_time ON job_history (update_time);
+CREATE INDEX IF NOT EXISTS idx_job_history_name ON job_history (name);`
+)
+
+// SQLiteStore is the Store implementation based on the sqlite database file.
+// The searchable fields are kept in columns and the whole stats are kept as json.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens the sqlite database file and creates the schema if not existing.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if utils.IsEmptyStr(path) {
+		return nil, errors.New("empty file path of the sqlite archive")
+	}
+
+	// Wait for the lock instead of failing immediately if the file is being written
+	db, err := sql.Open(sqliteDriver, path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite allows only one writer at the same time
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(createSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{
+		db: db,
+	}, nil
+}
+
+// Save is implementation of same method in Store interface.
+func (ss *SQLiteStore) Save(stats *models.JobStatData) error {
+	if stats == nil || utils.IsEmptyStr(stats.JobID) {
+		return errors.New("malformed job stats object")
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	_, err = ss.db.Exec(
+		`INSERT OR REPLACE INTO job_history (id, name, kind, status, enqueue_time, update_time, archived_at, stats)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		stats.JobID,
+		stats.JobName,
+		stats.JobKind,
+		stats.Status,
+		stats.EnqueueTime,
+		stats.UpdateTime,
+		time.Now().Unix(),
+		string(data),
+	)
+
+	return err
+}
+
+// List is implementation of same method in Store interface.
+func (ss *SQLiteStore) List(query models.JobQuery) (models.JobList, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if !utils.IsEmptyStr(query.Name) {
+		conditions = append(conditions, "name = ?")
+		args = append(args, query.Name)
+	}
+	if !utils.IsEmptyStr(query.Kind) {
+		conditions = append(conditions, "kind = ? COLLATE NOCASE")
+		args = append(args, query.Kind)
+	}
+	if !utils.IsEmptyStr(query.Status) {
+		conditions = append(conditions, "status = ? COLLATE NOCASE")
+		args = append(args, query.Status)
+	}
+	if query.Since > 0 {
+		conditions = append(conditions, "update_time >= ?")
+		args = append(args, query.Since)
+	}
+	if query.Until > 0 {
+		conditions = append(conditions, "update_time <= ?")
+		args = append(args, query.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	list := models.JobList{
+		Jobs: make([]*models.JobStatData, 0),
+	}
+
+	if err := ss.db.QueryRow("SELECT COUNT(*) FROM job_history"+where, args...).Scan(&list.Total); err != nil {
+		return list, err
+	}
+
+	page, pageSize := query.Page, query.PageSize
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	args = append(args, pageSize, (page-1)*pageSize)
+	rows, err := ss.db.Query("SELECT stats FROM job_history"+where+" ORDER BY update_time DESC, id LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return list, err
+		}
+
+		stats := &models.JobStatData{}
+		if err := json.Unmarshal([]byte(data), stats); err != nil {
+			return list, err
+		}
+		list.Jobs = append(list.Jobs, stats)
+	}
+
+	return list, rows.Err()
+}
+
+// Close is implementation of same method in Store interface.
+func (ss *SQLiteStore) Close() error {
+	return ss.db.Close()
+}
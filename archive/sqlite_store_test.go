@@ -0,0 +1,75 @@
+// Copyright Project Harbor Authors. All rights reserved.
+package archive
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Colstuwjx/job/models"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewSQLiteStore(filepath.Join(dir, "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for i := 0; i < 5; i++ {
+		status := "Success"
+		if i%2 == 1 {
+			status = "Error"
+		}
+
+		if err := store.Save(&models.JobStatData{
+			JobID:      fmt.Sprintf("id_%d", i),
+			JobName:    "DEMO",
+			JobKind:    "Generic",
+			Status:     status,
+			UpdateTime: int64(1000 + i),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replace the existing record
+	if err := store.Save(&models.JobStatData{
+		JobID:        "id_1",
+		JobName:      "DEMO",
+		JobKind:      "Generic",
+		Status:       "Error",
+		UpdateTime:   1001,
+		LastError:    "boom",
+		HookDelivery: "failed",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := store.List(models.JobQuery{Status: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 2 || len(list.Jobs) != 2 {
+		t.Fatalf("expect 2 failed jobs but got %d", list.Total)
+	}
+	if list.Jobs[0].JobID != "id_3" || list.Jobs[1].LastError != "boom" {
+		t.Errorf("expect the recently updated job first and the failure details kept but got %+v", list.Jobs[1])
+	}
+
+	list, err = store.List(models.JobQuery{Name: "DEMO", Since: 1001, Page: 2, PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 4 || len(list.Jobs) != 1 || list.Jobs[0].JobID != "id_1" {
+		t.Errorf("expect the last one of 4 jobs on page 2 but got %d of %d", len(list.Jobs), list.Total)
+	}
+}
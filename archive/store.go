@@ -0,0 +1,49 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+// Package archive provides the durable store of the job history.
+package archive
+
+import (
+	"fmt"
+
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/models"
+)
+
+// Store defines the methods of the durable store which keeps the stats of the ended jobs
+// after they are expired from the backend of the worker pool.
+type Store interface {
+	// Save the stats of the job, the existing record with the same job ID is replaced.
+	//
+	// stats *models.JobStatData : the job stats to archive
+	//
+	// Returns:
+	//  error : error if meet any problems
+	Save(stats *models.JobStatData) error
+
+	// List the archived jobs matching the query, the recently updated ones come first.
+	//
+	// query models.JobQuery : the query conditions
+	//
+	// Returns:
+	//  models.JobList : the total count and the jobs of the requested page
+	//  error          : error if meet any problems
+	List(query models.JobQuery) (models.JobList, error)
+
+	// Close the store
+	Close() error
+}
+
+// NewStore creates the store with the specified backend.
+func NewStore(cfg *config.ArchiveConfig) (Store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("nil archive config")
+	}
+
+	switch cfg.Backend {
+	case config.ArchiveBackendSQLite:
+		return NewSQLiteStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf("archive backend %s does not support", cfg.Backend)
+	}
+}
@@ -15,8 +15,11 @@ import (
 // Client wraps interface to access jobservice.
 type Client interface {
 	SubmitJob(*models.JobData) (string, error)
+	SubmitBatch(*models.JobBatchRequest) (*models.JobBatch, error)
+	GetBatch(batchID string) (*models.JobBatch, error)
 	GetJobLog(uuid string) ([]byte, error)
 	PostAction(uuid, action string) error
+	GetQueues() ([]*models.JobQueue, error)
 	// TODO Redirect joblog when we see there's memory issue.
 }
 
@@ -89,6 +92,60 @@ func (d *DefaultClient) SubmitJob(jd *models.JobData) (string, error) {
 	return stats.Stats.JobID, nil
 }
 
+// SubmitBatch call jobservice API to submit many jobs in one request and returns the batch.
+func (d *DefaultClient) SubmitBatch(br *models.JobBatchRequest) (*models.JobBatch, error) {
+	url := d.endpoint + "/api/v1/batches"
+
+	b, err := json.Marshal(br)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, &commonhttp.Error{
+			Code:    resp.StatusCode,
+			Message: string(data),
+		}
+	}
+
+	batch := &models.JobBatch{}
+	if err := json.Unmarshal(data, batch); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// GetBatch call jobservice API to get the aggregate status of the batch.
+func (d *DefaultClient) GetBatch(batchID string) (*models.JobBatch, error) {
+	url := d.endpoint + "/api/v1/batches/" + batchID
+
+	batch := &models.JobBatch{}
+	if err := d.client.Get(url, batch); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
 // GetJobLog call jobserivce API to get the log of a job.  It only accepts the UUID of the job
 func (d *DefaultClient) GetJobLog(uuid string) ([]byte, error) {
 	url := d.endpoint + "/api/v1/jobs/" + uuid + "/log"
@@ -130,3 +187,15 @@ func (d *DefaultClient) PostAction(uuid, action string) error {
 
 	return d.client.Post(url, req)
 }
+
+// GetQueues call jobservice's API to get the status of the queues of all the registered job names
+func (d *DefaultClient) GetQueues() ([]*models.JobQueue, error) {
+	url := d.endpoint + "/api/v1/queues"
+
+	queues := &models.JobQueues{}
+	if err := d.client.Get(url, queues); err != nil {
+		return nil, err
+	}
+
+	return queues.Queues, nil
+}
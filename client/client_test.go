@@ -14,7 +14,10 @@ var (
 	testClient Client
 )
 
-const ID = "u-1234-5678-9012"
+const (
+	ID      = "u-1234-5678-9012"
+	batchID = "b-1234-5678-9012"
+)
 
 func TestMain(m *testing.M) {
 	mockServer := mockserver.NewJobServiceServer()
@@ -57,3 +60,38 @@ func TestPostAction(t *testing.T) {
 	err2 := testClient.PostAction(ID, "stop")
 	assert.Nil(err2)
 }
+
+func TestGetQueues(t *testing.T) {
+	assert := assert.New(t)
+	queues, err := testClient.GetQueues()
+	assert.Nil(err)
+	assert.Len(queues, 1)
+	assert.Equal("replication", queues[0].JobName)
+	assert.Equal(int64(1), queues[0].Pending)
+}
+
+func TestSubmitBatch(t *testing.T) {
+	assert := assert.New(t)
+	br := &models.JobBatchRequest{
+		Jobs: []*models.JobData{
+			{Name: "replication"},
+			{Name: "replication"},
+		},
+	}
+	batch, err := testClient.SubmitBatch(br)
+	assert.Nil(err)
+	assert.Equal(batchID, batch.BatchID)
+	assert.Equal(int64(2), batch.Total)
+	assert.Len(batch.Jobs, 2)
+}
+
+func TestGetBatch(t *testing.T) {
+	assert := assert.New(t)
+	_, err := testClient.GetBatch("non")
+	assert.NotNil(err)
+
+	batch, err := testClient.GetBatch(batchID)
+	assert.Nil(err)
+	assert.True(batch.Completed)
+	assert.Equal(int64(1), batch.Counts["Success"])
+}
@@ -29,6 +29,14 @@ const (
 	jobServiceLoggerLevel         = "JOB_SERVICE_LOGGER_LEVEL"
 	jobServiceLoggerArchivePeriod = "JOB_SERVICE_LOGGER_ARCHIVE_PERIOD"
 	jobServiceAuthSecret          = "JOBSERVICE_SECRET"
+	jobServiceStatsWorkers        = "JOB_SERVICE_STATS_MANAGER_WORKERS"
+	jobServiceStatsQueueSize      = "JOB_SERVICE_STATS_MANAGER_QUEUE_SIZE"
+	jobServiceStatsBatchSize      = "JOB_SERVICE_STATS_MANAGER_BATCH_SIZE"
+	jobServiceStatsOverflowPolicy = "JOB_SERVICE_STATS_MANAGER_OVERFLOW_POLICY"
+	jobServiceStatsTTL            = "JOB_SERVICE_STATS_TTL"
+	jobServiceIdempotencyKeyTTL   = "JOB_SERVICE_IDEMPOTENCY_KEY_TTL"
+	jobServiceArchiveBackend      = "JOB_SERVICE_ARCHIVE_BACKEND"
+	jobServiceArchivePath         = "JOB_SERVICE_ARCHIVE_PATH"
 
 	// JobServiceProtocolHTTPS points to the 'https' protocol
 	JobServiceProtocolHTTPS = "https"
@@ -39,6 +47,32 @@ const (
 	// JobServicePoolBackendRedis represents redis backend
 	JobServicePoolBackendRedis = "redis"
 
+	// StatsOverflowPolicyBlock blocks the caller until the stats process queue has room
+	StatsOverflowPolicyBlock = "block"
+
+	// StatsOverflowPolicyDropNew drops the incoming item if the stats process queue is full
+	StatsOverflowPolicyDropNew = "drop_new"
+
+	// StatsOverflowPolicyDropOldest drops the oldest queued item if the stats process queue is full
+	StatsOverflowPolicyDropOldest = "drop_oldest"
+
+	// Defaults of the stats manager
+	defaultStatsWorkers   = 8
+	defaultStatsQueueSize = 1024
+	defaultStatsBatchSize = 64
+
+	// Default TTL (seconds) of the job stats
+	defaultStatsTTL = 24 * 60 * 60
+
+	// Default TTL (seconds) of the idempotency keys of the job submissions
+	defaultIdempotencyKeyTTL = 24 * 60 * 60
+
+	// Default period (seconds) of the rate limit
+	defaultRateLimitPeriod = 60
+
+	// ArchiveBackendSQLite represents the sqlite file backend of the job history archive
+	ArchiveBackendSQLite = "sqlite"
+
 	// secret of UI
 	uiAuthSecret = "CORE_SECRET"
 
@@ -65,6 +99,17 @@ type Configuration struct {
 
 	// Logger configurations
 	LoggerConfig *LoggerConfig `yaml:"logger,omitempty"`
+
+	// Configurations of the job history archive
+	ArchiveConfig *ArchiveConfig `yaml:"archive,omitempty"`
+}
+
+// ArchiveConfig keeps the configurations of the durable store which archives the stats of the ended jobs.
+type ArchiveConfig struct {
+	// Backend of the archive, so far only 'sqlite' supported
+	Backend string `yaml:"backend"`
+	// File path of the sqlite database
+	Path string `yaml:"path"`
 }
 
 // HTTPSConfig keeps additional configurations when using https protocol
@@ -82,9 +127,50 @@ type RedisPoolConfig struct {
 // PoolConfig keeps worker pool configurations.
 type PoolConfig struct {
 	// Worker concurrency
-	WorkerCount  uint             `yaml:"workers"`
-	Backend      string           `yaml:"backend"`
-	RedisPoolCfg *RedisPoolConfig `yaml:"redis_pool,omitempty"`
+	WorkerCount     uint                `yaml:"workers"`
+	Backend         string              `yaml:"backend"`
+	RedisPoolCfg    *RedisPoolConfig    `yaml:"redis_pool,omitempty"`
+	StatsManagerCfg *StatsManagerConfig `yaml:"stats_manager,omitempty"`
+	RetentionCfg    *RetentionConfig    `yaml:"stats_retention,omitempty"`
+	// TTL (seconds) of the idempotency keys of the job submissions
+	IdempotencyKeyTTL int64 `yaml:"idempotency_key_ttl,omitempty"`
+	// Max number of the running jobs per job name across all the pools,
+	// overrides the one declared by the job
+	MaxConcurrency map[string]uint `yaml:"max_concurrency,omitempty"`
+	// Rate limits of starting the jobs per job name across all the pools
+	RateLimits map[string]*RateLimitConfig `yaml:"rate_limits,omitempty"`
+}
+
+// RateLimitConfig keeps the token bucket settings of limiting the starts of the jobs.
+type RateLimitConfig struct {
+	// Number of the starts allowed in the period
+	Rate uint `yaml:"rate"`
+	// Period (seconds), default 60
+	Period int64 `yaml:"period,omitempty"`
+	// Max number of the starts allowed in a burst, default the rate
+	Burst uint `yaml:"burst,omitempty"`
+}
+
+// RetentionConfig keeps the TTL (seconds) settings of the job stats.
+type RetentionConfig struct {
+	// Default TTL of the job stats
+	TTL int64 `yaml:"ttl"`
+	// TTL per job kind, overrides the default one
+	Kinds map[string]int64 `yaml:"kinds,omitempty"`
+	// TTL per final status, applied when the job ends with the status
+	Statuses map[string]int64 `yaml:"statuses,omitempty"`
+}
+
+// StatsManagerConfig keeps the configurations of the job stats process pipeline.
+type StatsManagerConfig struct {
+	// Number of the workers processing the queued stats updates
+	Workers uint `yaml:"workers"`
+	// Capacity of the process queue
+	QueueSize uint `yaml:"queue_size"`
+	// Max number of the items written to backend in one batch
+	BatchSize uint `yaml:"batch_size"`
+	// What to do when the process queue is full: block, drop_new or drop_oldest
+	OverflowPolicy string `yaml:"overflow_policy"`
 }
 
 // LoggerConfig keeps logger configurations.
@@ -167,6 +253,133 @@ func GetLogArchivePeriod() uint {
 	return 1 // return default
 }
 
+// GetStatsManagerWorkers returns the number of the stats process workers
+func GetStatsManagerWorkers() uint {
+	if cfg := statsManagerConfig(); cfg != nil && cfg.Workers > 0 {
+		return cfg.Workers
+	}
+
+	return defaultStatsWorkers // return default
+}
+
+// GetStatsManagerQueueSize returns the capacity of the stats process queue
+func GetStatsManagerQueueSize() uint {
+	if cfg := statsManagerConfig(); cfg != nil && cfg.QueueSize > 0 {
+		return cfg.QueueSize
+	}
+
+	return defaultStatsQueueSize // return default
+}
+
+// GetStatsManagerBatchSize returns the max size of the stats writing batch
+func GetStatsManagerBatchSize() uint {
+	if cfg := statsManagerConfig(); cfg != nil && cfg.BatchSize > 0 {
+		return cfg.BatchSize
+	}
+
+	return defaultStatsBatchSize // return default
+}
+
+// GetStatsManagerOverflowPolicy returns the overflow policy of the stats process queue
+func GetStatsManagerOverflowPolicy() string {
+	if cfg := statsManagerConfig(); cfg != nil && !utils.IsEmptyStr(cfg.OverflowPolicy) {
+		return cfg.OverflowPolicy
+	}
+
+	return StatsOverflowPolicyBlock // return default
+}
+
+// GetStatsTTL returns the TTL (seconds) of the stats of the job with the specified kind
+func GetStatsTTL(kind string) int64 {
+	if DefaultConfig.PoolConfig != nil && DefaultConfig.PoolConfig.RetentionCfg != nil {
+		cfg := DefaultConfig.PoolConfig.RetentionCfg
+		if ttl, ok := lookupTTL(cfg.Kinds, kind); ok {
+			return ttl
+		}
+
+		if cfg.TTL > 0 {
+			return cfg.TTL
+		}
+	}
+
+	return defaultStatsTTL // return default
+}
+
+// GetStatsTTLOfStatus returns the TTL (seconds) of the stats of the job ended with the specified status.
+// The bool flag is false if no TTL is configured for the status.
+func GetStatsTTLOfStatus(status string) (int64, bool) {
+	if DefaultConfig.PoolConfig != nil && DefaultConfig.PoolConfig.RetentionCfg != nil {
+		return lookupTTL(DefaultConfig.PoolConfig.RetentionCfg.Statuses, status)
+	}
+
+	return 0, false
+}
+
+// GetIdempotencyKeyTTL returns the TTL (seconds) of the idempotency keys of the job submissions.
+func GetIdempotencyKeyTTL() int64 {
+	if DefaultConfig.PoolConfig != nil && DefaultConfig.PoolConfig.IdempotencyKeyTTL > 0 {
+		return DefaultConfig.PoolConfig.IdempotencyKeyTTL
+	}
+
+	return defaultIdempotencyKeyTTL // return default
+}
+
+// GetMaxConcurrency returns the configured max number of the running jobs of the job name across all the pools
+func GetMaxConcurrency(jobName string) (uint, bool) {
+	if DefaultConfig.PoolConfig != nil && DefaultConfig.PoolConfig.MaxConcurrency != nil {
+		n, ok := DefaultConfig.PoolConfig.MaxConcurrency[jobName]
+		return n, ok
+	}
+
+	return 0, false
+}
+
+// GetRateLimit returns the rate limit of starting the jobs of the job name with the defaults applied
+func GetRateLimit(jobName string) (*RateLimitConfig, bool) {
+	if DefaultConfig.PoolConfig == nil || DefaultConfig.PoolConfig.RateLimits == nil {
+		return nil, false
+	}
+
+	cfg, ok := DefaultConfig.PoolConfig.RateLimits[jobName]
+	if !ok || cfg == nil {
+		return nil, false
+	}
+
+	limit := *cfg
+	if limit.Period <= 0 {
+		limit.Period = defaultRateLimitPeriod
+	}
+	if limit.Burst == 0 {
+		limit.Burst = limit.Rate
+	}
+
+	return &limit, true
+}
+
+// GetArchiveConfig returns the config of the job history archive, nil if the archive is not enabled
+func GetArchiveConfig() *ArchiveConfig {
+	return DefaultConfig.ArchiveConfig
+}
+
+// lookupTTL finds the TTL with the case-insensitive name
+func lookupTTL(ttls map[string]int64, name string) (int64, bool) {
+	for k, ttl := range ttls {
+		if strings.EqualFold(k, name) && ttl > 0 {
+			return ttl, true
+		}
+	}
+
+	return 0, false
+}
+
+func statsManagerConfig() *StatsManagerConfig {
+	if DefaultConfig.PoolConfig != nil {
+		return DefaultConfig.PoolConfig.StatsManagerCfg
+	}
+
+	return nil
+}
+
 // GetUIAuthSecret get the auth secret of UI side
 func GetUIAuthSecret() string {
 	return utils.ReadEnv(uiAuthSecret)
@@ -247,6 +460,65 @@ func (c *Configuration) loadEnvs() {
 		}
 	}
 
+	// stats manager
+	if c.PoolConfig != nil {
+		if sw := utils.ReadEnv(jobServiceStatsWorkers); !utils.IsEmptyStr(sw) {
+			if count, err := strconv.Atoi(sw); err == nil {
+				c.ensureStatsManagerConfig().Workers = uint(count)
+			}
+		}
+
+		if qs := utils.ReadEnv(jobServiceStatsQueueSize); !utils.IsEmptyStr(qs) {
+			if size, err := strconv.Atoi(qs); err == nil {
+				c.ensureStatsManagerConfig().QueueSize = uint(size)
+			}
+		}
+
+		if bs := utils.ReadEnv(jobServiceStatsBatchSize); !utils.IsEmptyStr(bs) {
+			if size, err := strconv.Atoi(bs); err == nil {
+				c.ensureStatsManagerConfig().BatchSize = uint(size)
+			}
+		}
+
+		if op := utils.ReadEnv(jobServiceStatsOverflowPolicy); !utils.IsEmptyStr(op) {
+			c.ensureStatsManagerConfig().OverflowPolicy = op
+		}
+
+		if ttl := utils.ReadEnv(jobServiceStatsTTL); !utils.IsEmptyStr(ttl) {
+			if seconds, err := strconv.ParseInt(ttl, 10, 64); err == nil {
+				if c.PoolConfig.RetentionCfg == nil {
+					c.PoolConfig.RetentionCfg = &RetentionConfig{}
+				}
+				c.PoolConfig.RetentionCfg.TTL = seconds
+			}
+		}
+
+		if ttl := utils.ReadEnv(jobServiceIdempotencyKeyTTL); !utils.IsEmptyStr(ttl) {
+			if seconds, err := strconv.ParseInt(ttl, 10, 64); err == nil {
+				c.PoolConfig.IdempotencyKeyTTL = seconds
+			}
+		}
+	}
+
+	// archive
+	ab := utils.ReadEnv(jobServiceArchiveBackend)
+	if !utils.IsEmptyStr(ab) {
+		if c.ArchiveConfig == nil {
+			c.ArchiveConfig = &ArchiveConfig{}
+		}
+		c.ArchiveConfig.Backend = ab
+	}
+
+	ap := utils.ReadEnv(jobServiceArchivePath)
+	if !utils.IsEmptyStr(ap) {
+		if c.ArchiveConfig == nil {
+			c.ArchiveConfig = &ArchiveConfig{
+				Backend: ArchiveBackendSQLite,
+			}
+		}
+		c.ArchiveConfig.Path = ap
+	}
+
 	// logger
 	loggerPath := utils.ReadEnv(jobServiceLoggerBasePath)
 	if !utils.IsEmptyStr(loggerPath) {
@@ -275,6 +547,15 @@ func (c *Configuration) loadEnvs() {
 	}
 }
 
+// ensureStatsManagerConfig returns the stats manager config of the pool, create it if not existing
+func (c *Configuration) ensureStatsManagerConfig() *StatsManagerConfig {
+	if c.PoolConfig.StatsManagerCfg == nil {
+		c.PoolConfig.StatsManagerCfg = &StatsManagerConfig{}
+	}
+
+	return c.PoolConfig.StatsManagerCfg
+}
+
 // Check if the configurations are valid settings.
 func (c *Configuration) validate() error {
 	if c.Protocol != JobServiceProtocolHTTPS &&
@@ -332,6 +613,54 @@ func (c *Configuration) validate() error {
 		}
 	}
 
+	if c.PoolConfig.StatsManagerCfg != nil {
+		switch c.PoolConfig.StatsManagerCfg.OverflowPolicy {
+		case "", StatsOverflowPolicyBlock, StatsOverflowPolicyDropNew, StatsOverflowPolicyDropOldest:
+		default:
+			return fmt.Errorf("overflow policy of stats manager can only be one of: %s, %s, %s",
+				StatsOverflowPolicyBlock,
+				StatsOverflowPolicyDropNew,
+				StatsOverflowPolicyDropOldest)
+		}
+	}
+
+	if c.PoolConfig.RetentionCfg != nil {
+		if c.PoolConfig.RetentionCfg.TTL < 0 {
+			return fmt.Errorf("TTL of job stats should not be negative, but current is %d", c.PoolConfig.RetentionCfg.TTL)
+		}
+
+		for _, ttls := range []map[string]int64{c.PoolConfig.RetentionCfg.Kinds, c.PoolConfig.RetentionCfg.Statuses} {
+			for name, ttl := range ttls {
+				if ttl <= 0 {
+					return fmt.Errorf("TTL of job stats for '%s' should be a positive integer, but current is %d", name, ttl)
+				}
+			}
+		}
+	}
+
+	for name, limit := range c.PoolConfig.RateLimits {
+		if limit == nil || limit.Rate == 0 {
+			return fmt.Errorf("rate of the rate limit of job %s should be greater than 0", name)
+		}
+		if limit.Period < 0 {
+			return fmt.Errorf("period of the rate limit of job %s should not be negative, but current is %d", name, limit.Period)
+		}
+	}
+
+	if c.PoolConfig.IdempotencyKeyTTL < 0 {
+		return fmt.Errorf("TTL of idempotency keys should not be negative, but current is %d", c.PoolConfig.IdempotencyKeyTTL)
+	}
+
+	if c.ArchiveConfig != nil {
+		if c.ArchiveConfig.Backend != ArchiveBackendSQLite {
+			return fmt.Errorf("archive backend %s does not support", c.ArchiveConfig.Backend)
+		}
+
+		if utils.IsEmptyStr(c.ArchiveConfig.Path) {
+			return errors.New("file path of the archive is required")
+		}
+	}
+
 	if c.LoggerConfig == nil {
 		return errors.New("missing logger config")
 	}
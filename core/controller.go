@@ -3,15 +3,21 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"strings"
+	"time"
 
 	"github.com/robfig/cron"
 
 	"github.com/Colstuwjx/job/config"
 	"github.com/Colstuwjx/job/errs"
 	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
 	"github.com/Colstuwjx/job/models"
 	"github.com/Colstuwjx/job/pool"
 	"github.com/Colstuwjx/job/utils"
@@ -20,6 +26,18 @@ import (
 const (
 	hookActivated   = "activated"
 	hookDeactivated = "error"
+
+	// The default time window (seconds) of listing the upcoming runs
+	defaultScheduleWindow = 24 * 3600
+
+	// The max length of the idempotency key
+	maxIdempotencyKeyLen = 255
+
+	// The max length of the partition key
+	maxPartitionKeyLen = 255
+
+	// The max number of the jobs in one batch
+	maxBatchSize = 5000
 )
 
 // Controller implement the core interface and provides related job handle methods.
@@ -53,6 +71,29 @@ func (c *Controller) LaunchJob(req models.JobRequest) (models.JobStats, error) {
 		return models.JobStats{}, err
 	}
 
+	// Validate the follow-up jobs
+	if err := c.validCallbacks(req.Job); err != nil {
+		return models.JobStats{}, err
+	}
+
+	// Replay the original submission with the same idempotency key
+	idempotencyKey := req.Job.Metadata.IdempotencyKey
+	digest := ""
+	if !utils.IsEmptyStr(idempotencyKey) {
+		var err error
+		if digest, err = requestDigest(req); err != nil {
+			return models.JobStats{}, err
+		}
+
+		original, err := c.backendPool.ClaimIdempotencyKey(idempotencyKey, digest)
+		if err != nil {
+			return models.JobStats{}, err
+		}
+		if original != nil {
+			return c.replayJob(original), nil
+		}
+	}
+
 	// Enqueue job regarding of the kind
 	var (
 		res models.JobStats
@@ -60,19 +101,14 @@ func (c *Controller) LaunchJob(req models.JobRequest) (models.JobStats, error) {
 	)
 
 	switch req.Job.Metadata.JobKind {
-	case job.JobKindScheduled:
-		res, err = c.backendPool.Schedule(
-			req.Job.Name,
-			req.Job.Parameters,
-			req.Job.Metadata.ScheduleDelay,
-			req.Job.Metadata.IsUnique)
 	case job.JobKindPeriodic:
 		res, err = c.backendPool.PeriodicallyEnqueue(
 			req.Job.Name,
 			req.Job.Parameters,
 			req.Job.Metadata.Cron)
 	default:
-		res, err = c.backendPool.Enqueue(req.Job.Name, req.Job.Parameters, req.Job.Metadata.IsUnique)
+		// The settings of the generic and scheduled jobs are kept when they're enqueued
+		res, err = c.backendPool.EnqueueJob(req.Job)
 	}
 
 	// Register status hook?
@@ -86,9 +122,105 @@ func (c *Controller) LaunchJob(req models.JobRequest) (models.JobStats, error) {
 		}
 	}
 
+	if !utils.IsEmptyStr(idempotencyKey) {
+		if err == nil {
+			if e := c.backendPool.SaveIdempotencyKey(idempotencyKey, digest, res); e != nil {
+				logger.Errorf("Failed to save idempotency key %s of job %s: %s", idempotencyKey, res.Stats.JobID, e)
+			}
+		} else {
+			if e := c.backendPool.ReleaseIdempotencyKey(idempotencyKey); e != nil {
+				logger.Errorf("Failed to release idempotency key %s: %s", idempotencyKey, e)
+			}
+		}
+	}
+
 	return res, err
 }
 
+// replayJob returns the current stats of the job launched by the original submission
+// with the same idempotency key. The stats kept with the key are returned if the current
+// ones are gone.
+func (c *Controller) replayJob(original *models.JobStats) models.JobStats {
+	if original.Stats == nil || utils.IsEmptyStr(original.Stats.JobID) {
+		return *original
+	}
+
+	current, err := c.backendPool.GetJobStats(original.Stats.JobID)
+	if err != nil {
+		if !errs.IsObjectNotFoundError(err) {
+			logger.Errorf("Failed to get stats of the replayed job %s: %s", original.Stats.JobID, err)
+		}
+		return *original
+	}
+
+	return current
+}
+
+// LaunchBatch is implementation of same method in core interface.
+func (c *Controller) LaunchBatch(req models.JobBatchRequest) (models.JobBatch, error) {
+	if len(req.Jobs) == 0 {
+		return models.JobBatch{}, errors.New("empty batch request is not allowed")
+	}
+
+	if len(req.Jobs) > maxBatchSize {
+		return models.JobBatch{}, fmt.Errorf("batch should not exceed %d jobs but got %d", maxBatchSize, len(req.Jobs))
+	}
+
+	if !utils.IsEmptyStr(req.StatusHook) && !utils.IsValidURL(req.StatusHook) {
+		return models.JobBatch{}, errors.New("invalid status hook of the batch")
+	}
+
+	// Validate all the jobs before any of them is enqueued
+	violations := make([]string, 0)
+	for i, jd := range req.Jobs {
+		if err := c.validBatchJob(jd); err != nil {
+			violations = append(violations, fmt.Sprintf("jobs[%d]: %s", i, errs.Details(err)))
+		}
+	}
+	if len(violations) > 0 {
+		return models.JobBatch{}, errs.InvalidParametersError(strings.Join(violations, "; "))
+	}
+
+	batch, err := c.backendPool.EnqueueBatch(req.Jobs, req.StatusHook)
+	if err != nil {
+		return models.JobBatch{}, err
+	}
+
+	// Register status hooks of the jobs
+	for i, jd := range req.Jobs {
+		if utils.IsEmptyStr(jd.StatusHook) || i >= len(batch.Jobs) {
+			continue
+		}
+
+		stats := batch.Jobs[i]
+		if err := c.backendPool.RegisterHook(stats.JobID, jd.StatusHook); err != nil {
+			stats.HookStatus = hookDeactivated
+		} else {
+			stats.HookStatus = hookActivated
+		}
+	}
+
+	return batch, nil
+}
+
+// GetBatch is implementation of same method in core interface.
+func (c *Controller) GetBatch(batchID string) (models.JobBatch, error) {
+	if utils.IsEmptyStr(batchID) {
+		return models.JobBatch{}, errors.New("empty batch ID")
+	}
+
+	return c.backendPool.GetBatch(batchID)
+}
+
+// GetPartition is implementation of same method in core interface.
+func (c *Controller) GetPartition(partitionKey string, page, pageSize uint) (models.JobPartition, error) {
+	if utils.IsEmptyStr(partitionKey) {
+		return models.JobPartition{}, errors.New("empty partition key")
+	}
+
+	return c.backendPool.GetPartition(partitionKey, page, pageSize)
+}
+
 // GetJob is implementation of same method in core interface.
 func (c *Controller) GetJob(jobID string) (models.JobStats, error) {
 	if utils.IsEmptyStr(jobID) {
@@ -98,6 +230,38 @@ func (c *Controller) GetJob(jobID string) (models.JobStats, error) {
 	return c.backendPool.GetJobStats(jobID)
 }
 
+// GetJobResult is implementation of same method in core interface.
+func (c *Controller) GetJobResult(jobID string) (models.JobResult, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobResult{}, errors.New("empty job ID")
+	}
+
+	return c.backendPool.GetJobResult(jobID)
+}
+
+// GetJobCheckIns is implementation of same method in core interface.
+func (c *Controller) GetJobCheckIns(jobID string) (models.JobCheckIns, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobCheckIns{}, errors.New("empty job ID")
+	}
+
+	return c.backendPool.GetJobCheckIns(jobID)
+}
+
+// GetJobTimeline is implementation of same method in core interface.
+func (c *Controller) GetJobTimeline(jobID string) (models.JobStatusTimeline, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobStatusTimeline{}, errors.New("empty job ID")
+	}
+
+	return c.backendPool.GetJobTimeline(jobID)
+}
+
+// ListJobs is implementation of same method in core interface.
+func (c *Controller) ListJobs(query models.JobQuery) (models.JobList, error) {
+	return c.backendPool.ListJobs(query)
+}
+
 // StopJob is implementation of same method in core interface.
 func (c *Controller) StopJob(jobID string) error {
 	if utils.IsEmptyStr(jobID) {
@@ -116,6 +280,41 @@ func (c *Controller) CancelJob(jobID string) error {
 	return c.backendPool.CancelJob(jobID)
 }
 
+// UpdateJob is implementation of same method in core interface.
+func (c *Controller) UpdateJob(jobID string, req models.JobUpdateRequest) (models.JobStats, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobStats{}, errors.New("empty job ID")
+	}
+
+	if err := validJobUpdateReq(req); err != nil {
+		return models.JobStats{}, errs.InvalidParametersError(err.Error())
+	}
+
+	runAt := req.RunAt
+	if req.ScheduleDelay > 0 {
+		runAt = time.Now().Unix() + int64(req.ScheduleDelay)
+	}
+
+	if req.Parameters != nil {
+		theJob, err := c.backendPool.GetJobStats(jobID)
+		if err != nil {
+			return models.JobStats{}, err
+		}
+
+		// Revalidate the parameters as launching the job
+		jobType, isKnownJob := c.backendPool.IsKnownJob(theJob.Stats.JobName)
+		if !isKnownJob {
+			return models.JobStats{}, fmt.Errorf("job with name '%s' is unknown", theJob.Stats.JobName)
+		}
+
+		if err := c.backendPool.ValidateJobParameters(jobType, req.Parameters); err != nil {
+			return models.JobStats{}, err
+		}
+	}
+
+	return c.backendPool.UpdateJob(jobID, req.Parameters, runAt)
+}
+
 // RetryJob is implementation of same method in core interface.
 func (c *Controller) RetryJob(jobID string) error {
 	if utils.IsEmptyStr(jobID) {
@@ -125,6 +324,79 @@ func (c *Controller) RetryJob(jobID string) error {
 	return c.backendPool.RetryJob(jobID)
 }
 
+// ListDeadJobs is implementation of same method in core interface.
+func (c *Controller) ListDeadJobs(filter *models.DeadJobFilter, page, pageSize uint) (models.DeadJobList, error) {
+	return c.backendPool.ListDeadJobs(filter, page, pageSize)
+}
+
+// RetryDeadJobs is implementation of same method in core interface.
+func (c *Controller) RetryDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	if len(jobIDs) == 0 && filter == nil {
+		return models.DeadJobsActionResult{}, errors.New("either job IDs or filter should be specified")
+	}
+
+	return c.backendPool.RetryDeadJobs(jobIDs, filter)
+}
+
+// DeleteDeadJobs is implementation of same method in core interface.
+func (c *Controller) DeleteDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	if len(jobIDs) == 0 && filter == nil {
+		return models.DeadJobsActionResult{}, errors.New("either job IDs or filter should be specified")
+	}
+
+	return c.backendPool.DeleteDeadJobs(jobIDs, filter)
+}
+
+// PurgeDeadJobs is implementation of same method in core interface.
+func (c *Controller) PurgeDeadJobs(olderThan int64) (models.DeadJobsActionResult, error) {
+	if olderThan < 0 {
+		return models.DeadJobsActionResult{}, fmt.Errorf("age of the dead jobs should not be negative but got %d", olderThan)
+	}
+
+	return c.backendPool.DeleteDeadJobs(nil, &models.DeadJobFilter{
+		OlderThan: olderThan,
+	})
+}
+
+// GetSchedule is implementation of same method in core interface.
+func (c *Controller) GetSchedule(from, to int64) (models.JobSchedule, error) {
+	if from == 0 {
+		from = time.Now().Unix()
+	}
+	if to == 0 {
+		to = from + defaultScheduleWindow
+	}
+
+	if from > to {
+		return models.JobSchedule{}, fmt.Errorf("start %d of the time window is after the end %d", from, to)
+	}
+
+	return c.backendPool.ListSchedule(from, to)
+}
+
+// GetQueues is implementation of same method in core interface.
+func (c *Controller) GetQueues() (models.JobQueues, error) {
+	return c.backendPool.Queues()
+}
+
+// PauseQueue is implementation of same method in core interface.
+func (c *Controller) PauseQueue(jobName string) error {
+	if utils.IsEmptyStr(jobName) {
+		return errors.New("empty job name")
+	}
+
+	return c.backendPool.PauseQueue(jobName)
+}
+
+// ResumeQueue is implementation of same method in core interface.
+func (c *Controller) ResumeQueue(jobName string) error {
+	if utils.IsEmptyStr(jobName) {
+		return errors.New("empty job name")
+	}
+
+	return c.backendPool.ResumeQueue(jobName)
+}
+
 // GetJobLogData is used to return the log text data for the specified job if exists
 func (c *Controller) GetJobLogData(jobID string) ([]byte, error) {
 	if utils.IsEmptyStr(jobID) {
@@ -149,6 +421,24 @@ func (c *Controller) CheckStatus() (models.JobPoolStats, error) {
 	return c.backendPool.Stats()
 }
 
+// validJobUpdateReq checks if the job update request is well-formed.
+// The job can only be rescheduled to run in the future.
+func validJobUpdateReq(req models.JobUpdateRequest) error {
+	if req.Parameters == nil && req.RunAt == 0 && req.ScheduleDelay == 0 {
+		return errors.New("at least one of 'parameters', 'run_at' and 'schedule_delay' should be specified")
+	}
+
+	if req.RunAt != 0 && req.ScheduleDelay > 0 {
+		return errors.New("'run_at' and 'schedule_delay' can not be specified at the same time")
+	}
+
+	if req.RunAt != 0 && req.RunAt <= time.Now().Unix() {
+		return fmt.Errorf("'run_at' %d should be in the future", req.RunAt)
+	}
+
+	return nil
+}
+
 func validJobReq(req models.JobRequest) error {
 	if req.Job == nil {
 		return errors.New("empty job request is not allowed")
@@ -162,6 +452,32 @@ func validJobReq(req models.JobRequest) error {
 		return errors.New("metadata of job is missing")
 	}
 
+	if len(req.Job.Metadata.IdempotencyKey) > maxIdempotencyKeyLen {
+		return fmt.Errorf("'idempotency_key' should not exceed %d characters", maxIdempotencyKeyLen)
+	}
+
+	if !utils.IsEmptyStr(req.Job.Metadata.PartitionKey) {
+		if len(req.Job.Metadata.PartitionKey) > maxPartitionKeyLen {
+			return fmt.Errorf("'partition_key' should not exceed %d characters", maxPartitionKeyLen)
+		}
+
+		if req.Job.Metadata.JobKind != job.JobKindGeneric {
+			return fmt.Errorf("'partition_key' is only supported by the job kind '%s'", job.JobKindGeneric)
+		}
+
+		if req.Job.Metadata.IsUnique {
+			return errors.New("'partition_key' is not supported by the unique job")
+		}
+	}
+
+	if err := validRetryPolicy(req.Job.Metadata); err != nil {
+		return err
+	}
+
+	if err := validExpiry(req.Job.Metadata); err != nil {
+		return err
+	}
+
 	if req.Job.Metadata.JobKind != job.JobKindGeneric &&
 		req.Job.Metadata.JobKind != job.JobKindPeriodic &&
 		req.Job.Metadata.JobKind != job.JobKindScheduled {
@@ -190,3 +506,154 @@ func validJobReq(req models.JobRequest) error {
 
 	return nil
 }
+
+// validRetryPolicy validates the retry policy in the metadata of job if have.
+func validRetryPolicy(metadata *models.JobMetadata) error {
+	policy := metadata.RetryPolicy
+	if policy == nil {
+		return nil
+	}
+
+	if metadata.JobKind == job.JobKindPeriodic {
+		return fmt.Errorf("'retry_policy' is not supported by the job kind '%s'", job.JobKindPeriodic)
+	}
+
+	switch policy.Backoff {
+	case "", job.RetryBackoffExponential, job.RetryBackoffLinear, job.RetryBackoffFixed:
+	default:
+		return fmt.Errorf(
+			"backoff '%s' of 'retry_policy' is not supported, only support '%s','%s','%s'",
+			policy.Backoff,
+			job.RetryBackoffExponential,
+			job.RetryBackoffLinear,
+			job.RetryBackoffFixed)
+	}
+
+	if policy.Delay < 0 || policy.MaxDelay < 0 {
+		return errors.New("'delay' and 'max_delay' of 'retry_policy' should not be negative")
+	}
+
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		return errors.New("'jitter' of 'retry_policy' should be between 0 and 1")
+	}
+
+	return nil
+}
+
+// validExpiry validates the expiry time in the metadata of job if have.
+func validExpiry(metadata *models.JobMetadata) error {
+	if metadata.ExpireAt == 0 && metadata.MaxQueueTime == 0 {
+		return nil
+	}
+
+	if metadata.JobKind == job.JobKindPeriodic {
+		return fmt.Errorf("'expire_at' and 'max_queue_time' are not supported by the job kind '%s'", job.JobKindPeriodic)
+	}
+
+	if metadata.ExpireAt < 0 || metadata.MaxQueueTime < 0 {
+		return errors.New("'expire_at' and 'max_queue_time' should not be negative")
+	}
+
+	if metadata.ExpireAt > 0 {
+		runAt := time.Now().Unix()
+		if metadata.JobKind == job.JobKindScheduled {
+			runAt += int64(metadata.ScheduleDelay)
+		}
+
+		if metadata.ExpireAt <= runAt {
+			return errors.New("'expire_at' should be later than the time the job is due to run")
+		}
+	}
+
+	return nil
+}
+
+// validBatchJob validates the job of the batch like the single submitted job.
+// Only the generic and scheduled jobs without uniqueness are supported in the batch.
+func (c *Controller) validBatchJob(jd *models.JobData) error {
+	if err := validJobReq(models.JobRequest{Job: jd}); err != nil {
+		return err
+	}
+
+	if jd.Metadata.JobKind == job.JobKindPeriodic {
+		return fmt.Errorf("job kind '%s' is not supported in batch", job.JobKindPeriodic)
+	}
+
+	if jd.Metadata.IsUnique {
+		return errors.New("unique job is not supported in batch")
+	}
+
+	if !utils.IsEmptyStr(jd.Metadata.IdempotencyKey) {
+		return errors.New("'idempotency_key' is not supported in batch")
+	}
+
+	if !utils.IsEmptyStr(jd.Metadata.PartitionKey) {
+		return errors.New("'partition_key' is not supported in batch")
+	}
+
+	if !utils.IsEmptyStr(jd.StatusHook) && !utils.IsValidURL(jd.StatusHook) {
+		return errors.New("invalid status hook")
+	}
+
+	jobType, isKnownJob := c.backendPool.IsKnownJob(jd.Name)
+	if !isKnownJob {
+		return fmt.Errorf("job with name '%s' is unknown", jd.Name)
+	}
+
+	if err := c.backendPool.ValidateJobParameters(jobType, jd.Parameters); err != nil {
+		return err
+	}
+
+	return c.validCallbacks(jd)
+}
+
+// validCallbacks validates the follow-up jobs of the job.
+// The parameters are validated when the follow-up jobs are enqueued as the status and result
+// of the ended job are injected then.
+func (c *Controller) validCallbacks(jd *models.JobData) error {
+	callbacks := []struct {
+		event string
+		cb    *models.JobCallback
+	}{
+		{"on_success", jd.OnSuccess},
+		{"on_failure", jd.OnFailure},
+		{"on_complete", jd.OnComplete},
+	}
+
+	for _, item := range callbacks {
+		if item.cb == nil {
+			continue
+		}
+
+		if jd.Metadata.JobKind == job.JobKindPeriodic {
+			return fmt.Errorf("'%s' is not supported by the job kind '%s'", item.event, job.JobKindPeriodic)
+		}
+
+		if utils.IsEmptyStr(item.cb.Name) {
+			return fmt.Errorf("job name of '%s' is empty", item.event)
+		}
+
+		if _, isKnownJob := c.backendPool.IsKnownJob(item.cb.Name); !isKnownJob {
+			return fmt.Errorf("job with name '%s' of '%s' is unknown", item.cb.Name, item.event)
+		}
+	}
+
+	return nil
+}
+
+// requestDigest returns the sha256 digest of the json of the job request without the idempotency key,
+// the replayed request must have the same digest as the original one.
+func requestDigest(req models.JobRequest) (string, error) {
+	theJob := *req.Job
+	metadata := *theJob.Metadata
+	metadata.IdempotencyKey = ""
+	theJob.Metadata = &metadata
+
+	data, err := json.Marshal(&theJob)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -3,7 +3,10 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Colstuwjx/job/env"
 	"github.com/Colstuwjx/job/errs"
@@ -24,6 +27,135 @@ func TestLaunchGenericJob(t *testing.T) {
 	}
 }
 
+func TestLaunchPartitionedJob(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	req := createJobReq("Generic", false, false)
+	req.Job.Metadata.PartitionKey = "library/photon"
+	res, err := c.LaunchJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Stats.JobID != "fake_ID_Partitioned" || res.Stats.PartitionKey != "library/photon" {
+		t.Fatalf("expect partitioned job 'fake_ID_Partitioned' but got %+v\n", res.Stats)
+	}
+
+	req = createJobReq("Scheduled", false, false)
+	req.Job.Metadata.PartitionKey = "library/photon"
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of partitioned scheduled job but got nil")
+	}
+
+	req = createJobReq("Generic", true, false)
+	req.Job.Metadata.PartitionKey = "library/photon"
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of partitioned unique job but got nil")
+	}
+
+	partition, err := c.GetPartition("library/photon", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partition.Running != "fake_ID_Partitioned" || partition.Total != 1 {
+		t.Fatalf("expect partition with running job and 1 pending job but got %+v\n", partition)
+	}
+}
+
+func TestLaunchJobWithCallbacks(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	req := createJobReq("Generic", false, false)
+	req.Job.OnFailure = &models.JobCallback{
+		Name:       "cleanup",
+		Parameters: models.Parameters{"repository": "library/photon"},
+	}
+	req.Job.OnComplete = &models.JobCallback{Name: "notify"}
+	res, err := c.LaunchJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Stats.JobID != "fake_ID" {
+		t.Fatalf("expect enqueued job ID 'fake_ID' but got '%s'\n", res.Stats.JobID)
+	}
+
+	req = createJobReq("Generic", false, false)
+	req.Job.OnSuccess = &models.JobCallback{}
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of callback without job name but got nil")
+	}
+
+	req = createJobReq("Periodic", false, false)
+	req.Job.OnSuccess = &models.JobCallback{Name: "notify"}
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of periodic job with callback but got nil")
+	}
+}
+
+func TestLaunchJobWithRetryPolicy(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	req := createJobReq("Generic", false, false)
+	req.Job.Metadata.RetryPolicy = &models.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     "linear",
+		Delay:       10,
+		MaxDelay:    60,
+		Jitter:      0.2,
+	}
+	if _, err := c.LaunchJob(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Job.Metadata.RetryPolicy.Backoff = "random"
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of unknown backoff but got nil")
+	}
+
+	req.Job.Metadata.RetryPolicy.Backoff = "fixed"
+	req.Job.Metadata.RetryPolicy.Jitter = 1.5
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of invalid jitter but got nil")
+	}
+
+	req = createJobReq("Periodic", false, false)
+	req.Job.Metadata.RetryPolicy = &models.RetryPolicy{MaxAttempts: 5}
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of periodic job with retry policy but got nil")
+	}
+}
+
+func TestLaunchJobWithExpiry(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	now := time.Now().Unix()
+	req := createJobReq("Generic", false, false)
+	req.Job.Metadata.ExpireAt = now + 3600
+	req.Job.Metadata.MaxQueueTime = 600
+	if _, err := c.LaunchJob(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = createJobReq("Scheduled", false, false)
+	req.Job.Metadata.ExpireAt = now + 10
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of job expired before running but got nil")
+	}
+
+	req = createJobReq("Generic", false, false)
+	req.Job.Metadata.MaxQueueTime = -1
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of negative max queue time but got nil")
+	}
+
+	req = createJobReq("Periodic", false, false)
+	req.Job.Metadata.MaxQueueTime = 600
+	if _, err := c.LaunchJob(req); err == nil {
+		t.Fatal("expect error of periodic job with expiry but got nil")
+	}
+}
+
 func TestLaunchGenericJobUnique(t *testing.T) {
 	pool := &fakePool{}
 	c := NewController(pool)
@@ -52,6 +184,124 @@ func TestLaunchGenericJobWithHook(t *testing.T) {
 	}
 }
 
+func TestLaunchJobWithIdempotencyKey(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	req := createJobReq("Generic", false, false)
+	req.Job.Metadata.IdempotencyKey = "new"
+	res, err := c.LaunchJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.JobID != "fake_ID" {
+		t.Fatalf("expect enqueued job ID 'fake_ID' but got '%s'\n", res.Stats.JobID)
+	}
+
+	req.Job.Metadata.IdempotencyKey = "replayed"
+	res, err = c.LaunchJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.JobID != "original_ID" || res.Stats.Status != "running" {
+		t.Fatalf("expect current stats of replayed job 'original_ID' but got %+v\n", res.Stats)
+	}
+
+	// The kept stats are replayed if the job stats are gone
+	req.Job.Metadata.IdempotencyKey = "expired"
+	res, err = c.LaunchJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.JobID != "expired_ID" || res.Stats.Status != "pending" {
+		t.Fatalf("expect kept stats of replayed job 'expired_ID' but got %+v\n", res.Stats)
+	}
+
+	req.Job.Metadata.IdempotencyKey = "conflicted"
+	if _, err := c.LaunchJob(req); !errs.IsIdempotencyConflictError(err) {
+		t.Fatalf("expect idempotency conflict error but got %v\n", err)
+	}
+}
+
+func TestRequestDigest(t *testing.T) {
+	req := createJobReq("Generic", false, false)
+	d1, err := requestDigest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Job.Metadata.IdempotencyKey = "key"
+	d2, err := requestDigest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d1 != d2 {
+		t.Fatal("expect the idempotency key not counted in the request digest")
+	}
+	if req.Job.Metadata.IdempotencyKey != "key" {
+		t.Fatal("expect the request not changed by computing the digest")
+	}
+
+	req.Job.Parameters["name"] = "another"
+	d3, err := requestDigest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d3 == d1 {
+		t.Fatal("expect different digests of the different requests")
+	}
+}
+
+func TestLaunchBatch(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	req := models.JobBatchRequest{
+		Jobs: []*models.JobData{
+			createJobReq("Generic", false, true).Job,
+			createJobReq("Scheduled", false, false).Job,
+		},
+		StatusHook: "http://localhost:9090",
+	}
+	batch, err := c.LaunchBatch(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.BatchID != "fake_batch_ID" || len(batch.Jobs) != 2 {
+		t.Fatalf("expect batch 'fake_batch_ID' with 2 jobs but got %+v\n", batch)
+	}
+	if batch.Jobs[0].HookStatus != hookActivated || batch.Jobs[1].HookStatus != "" {
+		t.Fatalf("expect hook of the first job activated but got '%s' and '%s'\n", batch.Jobs[0].HookStatus, batch.Jobs[1].HookStatus)
+	}
+
+	if _, err := c.LaunchBatch(models.JobBatchRequest{}); err == nil {
+		t.Fatal("expect error of empty batch but got nil")
+	}
+
+	// All the invalid jobs are reported
+	req.Jobs = []*models.JobData{
+		createJobReq("Periodic", false, false).Job,
+		createJobReq("Generic", false, false).Job,
+		createJobReq("Generic", true, false).Job,
+	}
+	_, err = c.LaunchBatch(req)
+	if !errs.IsInvalidParametersError(err) {
+		t.Fatalf("expect invalid parameters error but got %v\n", err)
+	}
+	details := errs.Details(err)
+	if !strings.Contains(details, "jobs[0]") || !strings.Contains(details, "jobs[2]") || strings.Contains(details, "jobs[1]") {
+		t.Fatalf("expect violations of jobs[0] and jobs[2] but got '%s'\n", details)
+	}
+
+	b, err := c.GetBatch("fake_batch_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Completed {
+		t.Fatal("expect batch completed")
+	}
+}
+
 func TestLaunchScheduledJob(t *testing.T) {
 	pool := &fakePool{}
 	c := NewController(pool)
@@ -107,6 +357,84 @@ func TestGetJobStats(t *testing.T) {
 	}
 }
 
+func TestGetJobResult(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	res, err := c.GetJobResult("fake_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(res.Result) != `{"count":1}` {
+		t.Fatalf("expect result '{\"count\":1}' but got '%s'\n", res.Result)
+	}
+
+	if _, err := c.GetJobResult(""); err == nil {
+		t.Fatal("expect error for empty job ID but got nil")
+	}
+}
+
+func TestGetJobCheckIns(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	history, err := c.GetJobCheckIns("fake_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.CheckIns) != 2 || history.CheckIns[0].Message != "60%" {
+		t.Fatalf("expect 2 check ins with the latest '60%%' but got %d\n", len(history.CheckIns))
+	}
+}
+
+func TestGetJobTimeline(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	timeline, err := c.GetJobTimeline("fake_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(timeline.Timeline) != 2 || timeline.Timeline[1].Status != "Running" {
+		t.Fatalf("expect 2 transitions with the last 'Running' but got %d\n", len(timeline.Timeline))
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+	list, err := c.ListJobs(models.JobQuery{Status: "Success"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Total != 1 || list.Jobs[0].JobID != "fake_ID" {
+		t.Fatalf("expect 1 archived job 'fake_ID' but got %d\n", list.Total)
+	}
+}
+
+func TestDeadJobs(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	if _, err := c.RetryDeadJobs(nil, nil); err == nil {
+		t.Fatal("expect error when neither job IDs nor filter specified but got nil")
+	}
+
+	res, err := c.RetryDeadJobs([]string{"fake_ID"}, nil)
+	if err != nil || res.Succeeded != 1 {
+		t.Fatalf("expect 1 dead job retried but got %d: %v\n", res.Succeeded, err)
+	}
+
+	if _, err := c.PurgeDeadJobs(-1); err == nil {
+		t.Fatal("expect error for negative age but got nil")
+	}
+
+	if res, err = c.PurgeDeadJobs(3600); err != nil || res.Succeeded != 2 {
+		t.Fatalf("expect 2 dead jobs purged but got %d: %v\n", res.Succeeded, err)
+	}
+}
+
 func TestJobActions(t *testing.T) {
 	pool := &fakePool{}
 	c := NewController(pool)
@@ -155,6 +483,87 @@ func TestCheckStatus(t *testing.T) {
 	}
 }
 
+func TestGetSchedule(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	schedule, err := c.GetSchedule(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.From == 0 || schedule.To-schedule.From != defaultScheduleWindow {
+		t.Fatalf("expect the default time window of one day but got [%d, %d]\n", schedule.From, schedule.To)
+	}
+
+	if _, err := c.GetSchedule(200, 100); err == nil {
+		t.Fatal("expect error for the reversed time window but got nil")
+	}
+}
+
+func TestGetQueues(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	queues, err := c.GetQueues()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queues.Queues) != 1 || queues.Queues[0].JobName != "fake_job" {
+		t.Fatalf("expect the queue of 'fake_job' but got %d queues\n", len(queues.Queues))
+	}
+}
+
+func TestPauseQueue(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	if err := c.PauseQueue(""); err == nil {
+		t.Fatal("expect error for empty job name but got nil")
+	}
+	if err := c.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ResumeQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateJob(t *testing.T) {
+	pool := &fakePool{}
+	c := NewController(pool)
+
+	if _, err := c.UpdateJob("", models.JobUpdateRequest{RunAt: 100}); err == nil {
+		t.Fatal("expect error for empty job ID but got nil")
+	}
+
+	invalidReqs := map[string]models.JobUpdateRequest{
+		"empty":             {},
+		"past run_at":       {RunAt: time.Now().Unix() - 60},
+		"now run_at":        {RunAt: time.Now().Unix()},
+		"negative":          {RunAt: -1, Parameters: models.Parameters{"name": "testing:v2"}},
+		"run_at with delay": {RunAt: time.Now().Unix() + 60, ScheduleDelay: 60},
+	}
+	for name, req := range invalidReqs {
+		if _, err := c.UpdateJob("fake_ID", req); !errs.IsInvalidParametersError(err) {
+			t.Errorf("expect invalid parameters error for %s request but got %v\n", name, err)
+		}
+	}
+
+	now := time.Now().Unix()
+	res, err := c.UpdateJob("fake_ID", models.JobUpdateRequest{
+		Parameters:    models.Parameters{"name": "testing:v2"},
+		ScheduleDelay: 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Stats.RunAt < now+60 {
+		t.Fatalf("expect the job rescheduled after %d but got %d\n", now+60, res.Stats.RunAt)
+	}
+}
+
 func TestInvalidCheck(t *testing.T) {
 	pool := &fakePool{}
 	c := NewController(pool)
@@ -260,6 +669,33 @@ func (f *fakePool) Stats() (models.JobPoolStats, error) {
 	}, nil
 }
 
+func (f *fakePool) ListSchedule(from, to int64) (models.JobSchedule, error) {
+	return models.JobSchedule{
+		From:    from,
+		To:      to,
+		Entries: []*models.ScheduleEntry{},
+	}, nil
+}
+
+func (f *fakePool) Queues() (models.JobQueues, error) {
+	return models.JobQueues{
+		Queues: []*models.JobQueue{
+			{
+				JobName: "fake_job",
+				Pending: 1,
+			},
+		},
+	}, nil
+}
+
+func (f *fakePool) PauseQueue(jobName string) error {
+	return nil
+}
+
+func (f *fakePool) ResumeQueue(jobName string) error {
+	return nil
+}
+
 func (f *fakePool) IsKnownJob(name string) (interface{}, bool) {
 	return (*fakeJob)(nil), true
 }
@@ -269,14 +705,110 @@ func (f *fakePool) ValidateJobParameters(jobType interface{}, params map[string]
 }
 
 func (f *fakePool) GetJobStats(jobID string) (models.JobStats, error) {
+	if jobID == "expired_ID" {
+		return models.JobStats{}, errs.NoObjectFoundError(jobID)
+	}
+
 	return models.JobStats{
 		Stats: &models.JobStatData{
-			JobID:  "fake_ID",
+			JobID:  jobID,
 			Status: "running",
 		},
 	}, nil
 }
 
+func (f *fakePool) GetJobResult(jobID string) (models.JobResult, error) {
+	if jobID != "fake_ID" {
+		return models.JobResult{}, errs.NoObjectFoundError(jobID)
+	}
+
+	return models.JobResult{
+		JobID:  jobID,
+		Result: []byte(`{"count":1}`),
+	}, nil
+}
+
+func (f *fakePool) GetJobCheckIns(jobID string) (models.JobCheckIns, error) {
+	return models.JobCheckIns{
+		JobID: jobID,
+		CheckIns: []*models.JobCheckIn{
+			{
+				Message:   "60%",
+				CheckInAt: 1539164889,
+			},
+			{
+				Message:   "30%",
+				CheckInAt: 1539164879,
+			},
+		},
+	}, nil
+}
+
+func (f *fakePool) GetJobTimeline(jobID string) (models.JobStatusTimeline, error) {
+	return models.JobStatusTimeline{
+		JobID: jobID,
+		Timeline: []*models.JobStatusTransition{
+			{
+				Status:   "Pending",
+				Revision: 1,
+			},
+			{
+				Status:   "Running",
+				From:     "Pending",
+				Revision: 2,
+			},
+		},
+	}, nil
+}
+
+func (f *fakePool) ListJobs(query models.JobQuery) (models.JobList, error) {
+	return models.JobList{
+		Total: 1,
+		Jobs: []*models.JobStatData{
+			{
+				JobID:  "fake_ID",
+				Status: "Success",
+			},
+		},
+	}, nil
+}
+
+func (f *fakePool) ListDeadJobs(filter *models.DeadJobFilter, page, pageSize uint) (models.DeadJobList, error) {
+	return models.DeadJobList{
+		Total: 1,
+		Jobs: []*models.DeadJob{
+			{
+				JobID: "fake_ID",
+				Fails: 4,
+			},
+		},
+	}, nil
+}
+
+func (f *fakePool) RetryDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	return models.DeadJobsActionResult{
+		Action:    "retry",
+		Matched:   len(jobIDs),
+		Succeeded: len(jobIDs),
+	}, nil
+}
+
+func (f *fakePool) DeleteDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	if filter != nil && filter.OlderThan > 0 {
+		return models.DeadJobsActionResult{
+			Action:    "delete",
+			Matched:   2,
+			Succeeded: 2,
+		}, nil
+	}
+
+	return models.DeadJobsActionResult{
+		Action:    "delete",
+		Matched:   len(jobIDs),
+		Succeeded: len(jobIDs),
+	}, nil
+}
+
 func (f *fakePool) StopJob(jobID string) error {
 	return nil
 }
@@ -285,6 +817,16 @@ func (f *fakePool) CancelJob(jobID string) error {
 	return nil
 }
 
+func (f *fakePool) UpdateJob(jobID string, params models.Parameters, runAt int64) (models.JobStats, error) {
+	return models.JobStats{
+		Stats: &models.JobStatData{
+			JobID:  jobID,
+			Status: "Scheduled",
+			RunAt:  runAt,
+		},
+	}, nil
+}
+
 func (f *fakePool) RetryJob(jobID string) error {
 	return nil
 }
@@ -293,6 +835,105 @@ func (f *fakePool) RegisterHook(jobID string, hookURL string) error {
 	return nil
 }
 
+func (f *fakePool) EnqueueJob(jd *models.JobData) (models.JobStats, error) {
+	if jd.Metadata.JobKind == "Scheduled" {
+		return f.Schedule(jd.Name, jd.Parameters, jd.Metadata.ScheduleDelay, jd.Metadata.IsUnique)
+	}
+
+	if jd.Metadata.PartitionKey != "" {
+		return f.EnqueuePartitioned(jd.Name, jd.Parameters, jd.Metadata.PartitionKey)
+	}
+
+	return f.Enqueue(jd.Name, jd.Parameters, jd.Metadata.IsUnique)
+}
+
+func (f *fakePool) EnqueueBatch(jobs []*models.JobData, hookURL string) (models.JobBatch, error) {
+	batch := models.JobBatch{
+		BatchID:    "fake_batch_ID",
+		Total:      int64(len(jobs)),
+		StatusHook: hookURL,
+		Counts:     map[string]int64{"Pending": int64(len(jobs))},
+	}
+	for i := range jobs {
+		batch.Jobs = append(batch.Jobs, &models.JobStatData{
+			JobID:   fmt.Sprintf("fake_ID_%d", i),
+			Status:  "Pending",
+			BatchID: batch.BatchID,
+		})
+	}
+
+	return batch, nil
+}
+
+func (f *fakePool) EnqueuePartitioned(jobName string, params models.Parameters, partitionKey string) (models.JobStats, error) {
+	return models.JobStats{
+		Stats: &models.JobStatData{
+			JobID:        "fake_ID_Partitioned",
+			PartitionKey: partitionKey,
+		},
+	}, nil
+}
+
+func (f *fakePool) GetPartition(partitionKey string, page, pageSize uint) (models.JobPartition, error) {
+	return models.JobPartition{
+		PartitionKey: partitionKey,
+		Running:      "fake_ID_Partitioned",
+		Total:        1,
+		Jobs: []*models.PartitionedJob{
+			{JobID: "fake_ID_Pending", JobName: "DEMO"},
+		},
+	}, nil
+}
+
+func (f *fakePool) EnqueueChild(parentID string, jobName string, params models.Parameters, runAfterSeconds uint64, await bool) (models.JobStats, error) {
+	return models.JobStats{
+		Stats: &models.JobStatData{
+			JobID:    "fake_ID_Child",
+			ParentID: parentID,
+		},
+	}, nil
+}
+
+func (f *fakePool) GetBatch(batchID string) (models.JobBatch, error) {
+	return models.JobBatch{
+		BatchID:   batchID,
+		Total:     1,
+		Completed: true,
+		Counts:    map[string]int64{"Success": 1},
+	}, nil
+}
+
+func (f *fakePool) ClaimIdempotencyKey(key string, digest string) (*models.JobStats, error) {
+	switch key {
+	case "replayed":
+		return &models.JobStats{
+			Stats: &models.JobStatData{
+				JobID:  "original_ID",
+				Status: "pending",
+			},
+		}, nil
+	case "expired":
+		return &models.JobStats{
+			Stats: &models.JobStatData{
+				JobID:  "expired_ID",
+				Status: "pending",
+			},
+		}, nil
+	case "conflicted":
+		return nil, errs.IdempotencyConflictError(key, "already used by a different request")
+	default:
+		return nil, nil
+	}
+}
+
+func (f *fakePool) SaveIdempotencyKey(key string, digest string, stats models.JobStats) error {
+	return nil
+}
+
+func (f *fakePool) ReleaseIdempotencyKey(key string) error {
+	return nil
+}
+
 type fakeJob struct{}
 
 func (j *fakeJob) MaxFails() uint {
@@ -18,6 +18,36 @@ type Interface interface {
 	//  error   : Error returned if failed to launch the specified job.
 	LaunchJob(req models.JobRequest) (models.JobStats, error)
 
+	// LaunchBatch is used to handle the batch job submission request.
+	// All the jobs are validated before any of them is enqueued.
+	//
+	// req  JobBatchRequest: The jobs of the batch and the optional hook of the batch completion.
+	//
+	// Returns:
+	//  JobBatch: The batch with ID and the stats of the enqueued jobs.
+	//  error   : Error returned if failed to launch the batch.
+	LaunchBatch(req models.JobBatchRequest) (models.JobBatch, error)
+
+	// GetBatch is used to handle the batch status query request.
+	//
+	// batchID  string: ID of the batch.
+	//
+	// Returns:
+	//  JobBatch: The job counts by status and the completion of the batch.
+	//  error   : Error returned if failed to get the specified batch.
+	GetBatch(batchID string) (models.JobBatch, error)
+
+	// GetPartition is used to inspect the in-flight job and the backlog of the partition key.
+	//
+	// partitionKey string: The partition key of the jobs.
+	// page         uint  : The page number starting from 1.
+	// pageSize     uint  : The page size.
+	//
+	// Returns:
+	//  JobPartition: The in-flight job and the jobs of the requested page in the backlog.
+	//  error       : Error returned if failed to get the partition.
+	GetPartition(partitionKey string, page, pageSize uint) (models.JobPartition, error)
+
 	// GetJob is used to handle the job stats query request.
 	//
 	// jobID    string: ID of job.
@@ -27,6 +57,42 @@ type Interface interface {
 	//  error   : Error returned if failed to get the specified job.
 	GetJob(jobID string) (models.JobStats, error)
 
+	// GetJobResult is used to handle the job result query request.
+	//
+	// jobID    string: ID of job.
+	//
+	// Returns:
+	//  JobResult: Job result set by the job if existing.
+	//  error    : Error returned if failed to get the result of the specified job.
+	GetJobResult(jobID string) (models.JobResult, error)
+
+	// GetJobCheckIns is used to handle the job check in history query request.
+	//
+	// jobID    string: ID of job.
+	//
+	// Returns:
+	//  JobCheckIns: The check in history of the job, the latest one comes first.
+	//  error      : Error returned if failed to get the check in history of the specified job.
+	GetJobCheckIns(jobID string) (models.JobCheckIns, error)
+
+	// GetJobTimeline is used to handle the job status timeline query request.
+	//
+	// jobID    string: ID of job.
+	//
+	// Returns:
+	//  JobStatusTimeline: The accepted status transitions of the job in order.
+	//  error            : Error returned if failed to get the status timeline of the specified job.
+	GetJobTimeline(jobID string) (models.JobStatusTimeline, error)
+
+	// ListJobs is used to handle the archived jobs listing request.
+	//
+	// query    JobQuery: The query conditions.
+	//
+	// Returns:
+	//  JobList: The total count and the archived jobs of the requested page.
+	//  error  : Error returned if failed to list the archived jobs.
+	ListJobs(query models.JobQuery) (models.JobList, error)
+
 	// StopJob is used to handle the job stopping request.
 	//
 	// jobID    string: ID of job.
@@ -51,6 +117,90 @@ type Interface interface {
 	//  error           : error returned if meet any problems
 	CancelJob(jobID string) error
 
+	// UpdateJob is used to change the parameters and/or the run time of the job
+	// which is still pending or scheduled.
+	//
+	// jobID string                 : ID of the enqueued job.
+	// req   models.JobUpdateRequest: The changes of the job, the omitted fields are kept unchanged.
+	//
+	// Returns:
+	//  JobStats: The updated job stats.
+	//  error   : Error returned if failed to update the job.
+	UpdateJob(jobID string, req models.JobUpdateRequest) (models.JobStats, error)
+
+	// ListDeadJobs is used to handle the dead jobs listing request.
+	//
+	// filter   *DeadJobFilter: The optional filter of the dead jobs.
+	// page     uint          : The page number starting from 1.
+	// pageSize uint          : The page size.
+	//
+	// Returns:
+	//  DeadJobList: The total count and the dead jobs of the requested page.
+	//  error      : Error returned if failed to list the dead jobs.
+	ListDeadJobs(filter *models.DeadJobFilter, page, pageSize uint) (models.DeadJobList, error)
+
+	// RetryDeadJobs is used to handle the batch retrying request of the dead jobs.
+	//
+	// jobIDs   []string      : The IDs of the dead jobs, empty for all the ones matching the filter.
+	// filter   *DeadJobFilter: The optional filter of the dead jobs.
+	//
+	// Returns:
+	//  DeadJobsActionResult: The result of each selected dead job.
+	//  error               : Error returned if failed to retry the dead jobs.
+	RetryDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error)
+
+	// DeleteDeadJobs is used to handle the batch deleting request of the dead jobs.
+	//
+	// jobIDs   []string      : The IDs of the dead jobs, empty for all the ones matching the filter.
+	// filter   *DeadJobFilter: The optional filter of the dead jobs.
+	//
+	// Returns:
+	//  DeadJobsActionResult: The result of each selected dead job.
+	//  error               : Error returned if failed to delete the dead jobs.
+	DeleteDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error)
+
+	// PurgeDeadJobs is used to remove the dead jobs which died before the specified age.
+	//
+	// olderThan int64: The age (seconds) of the dead jobs, 0 for all.
+	//
+	// Returns:
+	//  DeadJobsActionResult: The result of each purged dead job.
+	//  error               : Error returned if failed to purge the dead jobs.
+	PurgeDeadJobs(olderThan int64) (models.DeadJobsActionResult, error)
+
+	// GetSchedule is used to list the upcoming runs of the scheduled jobs and the periodic job policies.
+	//
+	// from int64: The start of the time window (unix seconds), 0 for now.
+	// to   int64: The end of the time window (unix seconds), 0 for one day after the start.
+	//
+	// Returns:
+	//  JobSchedule: The upcoming runs in the time window, the earliest one comes first.
+	//  error      : Error returned if failed to list the upcoming runs.
+	GetSchedule(from, to int64) (models.JobSchedule, error)
+
+	// GetQueues is used to inspect the queues of all the registered job names.
+	//
+	// Returns:
+	//  JobQueues: The depth, latency and paused state of the queue of each job name.
+	//  error    : Error returned if failed to inspect the queues.
+	GetQueues() (models.JobQueues, error)
+
+	// PauseQueue is used to pause the processing of the jobs with the specified name.
+	//
+	// jobName string: The name of the registered job.
+	//
+	// Returns:
+	//  error: Error returned if failed to pause the queue.
+	PauseQueue(jobName string) error
+
+	// ResumeQueue is used to resume the processing of the jobs with the specified name.
+	//
+	// jobName string: The name of the registered job.
+	//
+	// Returns:
+	//  error: Error returned if failed to resume the queue.
+	ResumeQueue(jobName string) error
+
 	// CheckStatus is used to handle the job service healthy status checking request.
 	CheckStatus() (models.JobPoolStats, error)
 
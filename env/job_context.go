@@ -4,6 +4,7 @@ package env
 
 import (
 	"context"
+	"time"
 
 	"github.com/Colstuwjx/job/logger"
 )
@@ -44,6 +45,114 @@ type JobContext interface {
 	//  error if meet any problems
 	Checkin(status string) error
 
+	// SetResult sets the structured result object of the job, like the digest a replication produced.
+	// The result will be serialized to json and kept with the job stats for a while.
+	// Calling it again overrides the previous result.
+	//
+	// result interface{} : the result object which can be serialized to json
+	//
+	// Returns:
+	//  error if meet any problems like the result size exceeds the limit
+	SetResult(result interface{}) error
+
+	// ReportProgress reports the structured progress of the job.
+	// The ETA is calculated from the progress rate, and the reporting via hook is throttled.
+	//
+	// current uint64 : the finished units
+	// total uint64   : the total units, 0 if unknown
+	// phase string   : name of the current phase, the rate is re-calculated when phase changed
+	// message string : optional message
+	//
+	// Returns:
+	//  error if meet any problems
+	ReportProgress(current, total uint64, phase, message string) error
+
+	// SaveCheckpoint persists the checkpoint of the job with the specified key.
+	// The checkpoints survive the retries of the job and are cleared once the job succeeds.
+	//
+	// key string         : key of the checkpoint
+	// value interface{}  : the checkpoint value which can be serialized to json
+	//
+	// Returns:
+	//  error if meet any problems
+	SaveCheckpoint(key string, value interface{}) error
+
+	// LoadCheckpoint loads the checkpoint saved by the previous attempts of the job.
+	//
+	// key string        : key of the checkpoint
+	// value interface{} : pointer to receive the checkpoint value
+	//
+	// Returns:
+	//  bool to indicate if the checkpoint existing
+	//  error if meet any problems
+	LoadCheckpoint(key string, value interface{}) (bool, error)
+
+	// AcquireLock tries to acquire the named lock shared by all the jobs without blocking.
+	// The lock is kept alive while the job is running and released automatically when the job exits.
+	// If the job is gone without releasing it, the lock expires after the ttl.
+	//
+	// name string       : name of the lock, like the resource the job touches
+	// ttl time.Duration : the ttl of the lock, default 30s and at least 3s
+	//
+	// Returns:
+	//  bool to indicate if the lock is acquired
+	//  error if meet any problems
+	AcquireLock(name string, ttl time.Duration) (bool, error)
+
+	// ReleaseLock releases the named lock held by the job.
+	//
+	// name string : name of the lock
+	//
+	// Returns:
+	//  error if meet any problems
+	ReleaseLock(name string) error
+
+	// AcquireSemaphore tries to acquire the named counting semaphore shared by all the jobs without blocking.
+	// It works like AcquireLock but allows at most limit holders at the same time.
+	// The lock is a semaphore with the limit 1, so they should not share names.
+	//
+	// name string       : name of the semaphore
+	// limit uint        : the max number of the holders
+	// ttl time.Duration : the ttl of the semaphore, default 30s and at least 3s
+	//
+	// Returns:
+	//  bool to indicate if the semaphore is acquired
+	//  error if meet any problems
+	AcquireSemaphore(name string, limit uint, ttl time.Duration) (bool, error)
+
+	// ReleaseSemaphore releases the named semaphore held by the job.
+	//
+	// name string : name of the semaphore
+	//
+	// Returns:
+	//  error if meet any problems
+	ReleaseSemaphore(name string) error
+
+	// Enqueue enqueues the child job which records this job as its parent.
+	// If await is true, this job is kept running after its Run returns until all the awaited
+	// child jobs are finished, then it succeeds only if all of them succeed.
+	//
+	// jobName string               : name of the registered job
+	// params map[string]interface{} : parameters of the child job
+	// await bool                   : whether to wait for the child job
+	//
+	// Returns:
+	//  ID of the child job
+	//  error if meet any problems like the job is unknown or the parameters are invalid
+	Enqueue(jobName string, params map[string]interface{}, await bool) (string, error)
+
+	// Schedule enqueues the child job to run after the specified seconds, like Enqueue.
+	//
+	// jobName string               : name of the registered job
+	// params map[string]interface{} : parameters of the child job
+	// runAfterSeconds uint64       : the delay (seconds), must be greater than 0
+	// await bool                   : whether to wait for the child job
+	//
+	// Returns:
+	//  ID of the child job
+	//  error if meet any problems
+	Schedule(jobName string, params map[string]interface{}, runAfterSeconds uint64, await bool) (string, error)
+
 	// OPCommand return the control operational command like stop/cancel if have
 	//
 	// Returns:
@@ -5,6 +5,8 @@ package errs
 
 import (
 	"encoding/json"
+	"fmt"
+	"time"
 )
 
 const (
@@ -52,6 +54,63 @@ const (
 
 	// UnAuthorizedErrorCode is code for the error of unauthorized accessing
 	UnAuthorizedErrorCode
+
+	// InvalidParametersErrorCode is code for the error of invalid job parameters
+	InvalidParametersErrorCode
+
+	// GetJobResultErrorCode is code for the error of getting job result
+	GetJobResultErrorCode
+
+	// GetJobCheckInsErrorCode is code for the error of getting job check in history
+	GetJobCheckInsErrorCode
+
+	// GetJobTimelineErrorCode is code for the error of getting job status timeline
+	GetJobTimelineErrorCode
+
+	// ListJobsErrorCode is code for the error of listing the archived jobs
+	ListJobsErrorCode
+
+	// ArchiveDisabledErrorCode is code for the error of accessing the disabled job archive
+	ArchiveDisabledErrorCode
+
+	// ListDeadJobsErrorCode is code for the error of listing the dead jobs
+	ListDeadJobsErrorCode
+
+	// DeadJobsActionErrorCode is code for the error of retrying, deleting or purging the dead jobs
+	DeadJobsActionErrorCode
+
+	// GetQueuesErrorCode is code for the error of inspecting the job queues
+	GetQueuesErrorCode
+
+	// QueueActionErrorCode is code for the error of pausing or resuming the job queue
+	QueueActionErrorCode
+
+	// UpdateJobErrorCode is code for the error of updating the job which has not started yet
+	UpdateJobErrorCode
+
+	// GetScheduleErrorCode is code for the error of getting the upcoming schedule
+	GetScheduleErrorCode
+
+	// IdempotencyConflictErrorCode is code for the error of reusing the idempotency key with a different request
+	IdempotencyConflictErrorCode
+
+	// LaunchBatchErrorCode is code for the error of launching the batch of jobs
+	LaunchBatchErrorCode
+
+	// GetBatchErrorCode is code for the error of getting the batch status
+	GetBatchErrorCode
+
+	// GetPartitionErrorCode is code for the error of getting the partition backlog
+	GetPartitionErrorCode
+
+	// JobPermanentErrorCode is code for jobPermanentError
+	JobPermanentErrorCode
+
+	// JobRetryAfterErrorCode is code for jobRetryAfterError
+	JobRetryAfterErrorCode
+
+	// JobRateLimitedErrorCode is code for jobRateLimitedError
+	JobRateLimitedErrorCode
 )
 
 // baseError ...
@@ -70,6 +129,26 @@ func (be baseError) Error() string {
 	return "{}"
 }
 
+func (be baseError) details() string {
+	if len(be.Description) > 0 {
+		return be.Description
+	}
+
+	return be.Err
+}
+
+// Details returns the detailed description of the customized error,
+// or the message of the other errors.
+func Details(err error) string {
+	if e, ok := err.(interface {
+		details() string
+	}); ok {
+		return e.details()
+	}
+
+	return err.Error()
+}
+
 // New customized errors
 func New(code uint16, err string, description string) error {
 	return baseError{
@@ -134,6 +213,71 @@ func GetJobLogError(err error) error {
 	return New(GetJobLogErrorCode, "Failed to get the job log", err.Error())
 }
 
+// GetJobResultError is error for the case of getting job result failed
+func GetJobResultError(err error) error {
+	return New(GetJobResultErrorCode, "Failed to get the job result", err.Error())
+}
+
+// GetJobCheckInsError is error for the case of getting job check in history failed
+func GetJobCheckInsError(err error) error {
+	return New(GetJobCheckInsErrorCode, "Failed to get the job check in history", err.Error())
+}
+
+// GetJobTimelineError is error for the case of getting job status timeline failed
+func GetJobTimelineError(err error) error {
+	return New(GetJobTimelineErrorCode, "Failed to get the job status timeline", err.Error())
+}
+
+// ListJobsError is error for the case of listing the archived jobs failed
+func ListJobsError(err error) error {
+	return New(ListJobsErrorCode, "Failed to list jobs", err.Error())
+}
+
+// ListDeadJobsError is error for the case of listing the dead jobs failed
+func ListDeadJobsError(err error) error {
+	return New(ListDeadJobsErrorCode, "Failed to list dead jobs", err.Error())
+}
+
+// DeadJobsActionError is error for the case of retrying, deleting or purging the dead jobs failed
+func DeadJobsActionError(err error) error {
+	return New(DeadJobsActionErrorCode, "Failed to handle dead jobs", err.Error())
+}
+
+// GetQueuesError is error for the case of inspecting the job queues failed
+func GetQueuesError(err error) error {
+	return New(GetQueuesErrorCode, "Failed to get job queues", err.Error())
+}
+
+// QueueActionError is error for the case of pausing or resuming the job queue failed
+func QueueActionError(err error) error {
+	return New(QueueActionErrorCode, "Failed to handle job queue", err.Error())
+}
+
+// UpdateJobError is error for the case of updating the job failed
+func UpdateJobError(err error) error {
+	return New(UpdateJobErrorCode, "Failed to update job", err.Error())
+}
+
+// GetScheduleError is error for the case of getting the upcoming schedule failed
+func GetScheduleError(err error) error {
+	return New(GetScheduleErrorCode, "Failed to get schedule", err.Error())
+}
+
+// LaunchBatchError is error for the case of launching the batch of jobs failed
+func LaunchBatchError(err error) error {
+	return New(LaunchBatchErrorCode, "Failed to launch batch", err.Error())
+}
+
+// GetBatchError is error for the case of getting the batch status failed
+func GetBatchError(err error) error {
+	return New(GetBatchErrorCode, "Failed to get batch", err.Error())
+}
+
+// GetPartitionError is error for the case of getting the partition backlog failed
+func GetPartitionError(err error) error {
+	return New(GetPartitionErrorCode, "Failed to get partition", err.Error())
+}
+
 // UnauthorizedError is error for the case of unauthorized accessing
 func UnauthorizedError(err error) error {
 	return New(UnAuthorizedErrorCode, "Unauthorized", err.Error())
@@ -169,6 +313,69 @@ func JobCancelledError() error {
 	}
 }
 
+// jobPermanentError is designed for the failure of job which can not be fixed by retrying.
+type jobPermanentError struct {
+	baseError
+}
+
+// PermanentError is error wrapper for the failure of job which can not be fixed by retrying,
+// like the authentication failure. The job dies without retrying.
+func PermanentError(err error) error {
+	return jobPermanentError{
+		baseError{
+			Code:        JobPermanentErrorCode,
+			Err:         "Job failed permanently",
+			Description: errDescription(err),
+		},
+	}
+}
+
+// jobRetryAfterError is designed for the failure of job which should be retried after the delay.
+type jobRetryAfterError struct {
+	baseError
+	delay time.Duration
+}
+
+// RetryAfterError is error wrapper for the failure of job which should be retried after the delay,
+// like the transient timeout. It's counted as a failure of the job.
+func RetryAfterError(err error, delay time.Duration) error {
+	return jobRetryAfterError{
+		baseError{
+			Code:        JobRetryAfterErrorCode,
+			Err:         "Job failed and should be retried later",
+			Description: errDescription(err),
+		},
+		delay,
+	}
+}
+
+// jobRateLimitedError is designed for the job throttled by the services it depends on.
+type jobRateLimitedError struct {
+	baseError
+	delay time.Duration
+}
+
+// RateLimitedError is error wrapper for the job throttled by the services it depends on.
+// The job is run again after the delay and it's not counted as a failure.
+func RateLimitedError(err error, delay time.Duration) error {
+	return jobRateLimitedError{
+		baseError{
+			Code:        JobRateLimitedErrorCode,
+			Err:         "Job is rate limited",
+			Description: errDescription(err),
+		},
+		delay,
+	}
+}
+
+func errDescription(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
 // objectNotFoundError is designed for the case of no object found
 type objectNotFoundError struct {
 	baseError
@@ -185,6 +392,54 @@ func NoObjectFoundError(object string) error {
 	}
 }
 
+// invalidParametersError is designed for the case of job parameters not matching the expected ones
+type invalidParametersError struct {
+	baseError
+}
+
+// InvalidParametersError is error wrapper for the case of invalid job parameters
+func InvalidParametersError(description string) error {
+	return invalidParametersError{
+		baseError{
+			Code:        InvalidParametersErrorCode,
+			Err:         "Invalid job parameters",
+			Description: description,
+		},
+	}
+}
+
+// archiveDisabledError is designed for the case of accessing the disabled job archive
+type archiveDisabledError struct {
+	baseError
+}
+
+// ArchiveDisabledError is error wrapper for the case of accessing the disabled job archive
+func ArchiveDisabledError() error {
+	return archiveDisabledError{
+		baseError{
+			Code: ArchiveDisabledErrorCode,
+			Err:  "job archive is not enabled",
+		},
+	}
+}
+
+// idempotencyConflictError is designed for the case of reusing the idempotency key with a different request
+type idempotencyConflictError struct {
+	baseError
+}
+
+// IdempotencyConflictError is error wrapper for the case of reusing the idempotency key with a different request
+// or while the original request is still in progress
+func IdempotencyConflictError(key string, reason string) error {
+	return idempotencyConflictError{
+		baseError{
+			Code:        IdempotencyConflictErrorCode,
+			Err:         "idempotency key conflict",
+			Description: fmt.Sprintf("key '%s': %s", key, reason),
+		},
+	}
+}
+
 // IsJobStoppedError return true if the error is jobStoppedError
 func IsJobStoppedError(err error) bool {
 	_, ok := err.(jobStoppedError)
@@ -197,8 +452,57 @@ func IsJobCancelledError(err error) bool {
 	return ok
 }
 
+// IsPermanentError return true if the error is jobPermanentError
+func IsPermanentError(err error) bool {
+	_, ok := err.(jobPermanentError)
+	return ok
+}
+
+// IsRetryAfterError return true if the error is jobRetryAfterError
+func IsRetryAfterError(err error) bool {
+	_, ok := err.(jobRetryAfterError)
+	return ok
+}
+
+// IsRateLimitedError return true if the error is jobRateLimitedError
+func IsRateLimitedError(err error) bool {
+	_, ok := err.(jobRateLimitedError)
+	return ok
+}
+
+// RetryDelay returns the delay of jobRetryAfterError or jobRateLimitedError.
+// The bool flag is false for the other errors.
+func RetryDelay(err error) (time.Duration, bool) {
+	switch e := err.(type) {
+	case jobRetryAfterError:
+		return e.delay, true
+	case jobRateLimitedError:
+		return e.delay, true
+	default:
+		return 0, false
+	}
+}
+
 // IsObjectNotFoundError return true if the error is objectNotFoundError
 func IsObjectNotFoundError(err error) bool {
 	_, ok := err.(objectNotFoundError)
 	return ok
 }
+
+// IsInvalidParametersError return true if the error is invalidParametersError
+func IsInvalidParametersError(err error) bool {
+	_, ok := err.(invalidParametersError)
+	return ok
+}
+
+// IsArchiveDisabledError return true if the error is archiveDisabledError
+func IsArchiveDisabledError(err error) bool {
+	_, ok := err.(archiveDisabledError)
+	return ok
+}
+
+// IsIdempotencyConflictError return true if the error is idempotencyConflictError
+func IsIdempotencyConflictError(err error) bool {
+	_, ok := err.(idempotencyConflictError)
+	return ok
+}
@@ -4,9 +4,11 @@ package impl
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/Colstuwjx/job/config"
 	"github.com/Colstuwjx/job/env"
@@ -33,6 +35,23 @@ type Context struct {
 	// checkin func
 	checkInFunc job.CheckInFunc
 
+	// result func
+	resultFunc job.ResultFunc
+
+	// progress func
+	progressFunc job.ProgressFunc
+
+	// checkpoint funcs
+	saveCheckpointFunc job.SaveCheckpointFunc
+	loadCheckpointFunc job.LoadCheckpointFunc
+
+	// lock funcs
+	acquireLockFunc job.AcquireLockFunc
+	releaseLockFunc job.ReleaseLockFunc
+
+	// enqueue func
+	enqueueFunc job.EnqueueFunc
+
 	// other required information
 	properties map[string]interface{}
 }
@@ -98,6 +117,90 @@ func (c *Context) Build(dep env.JobData) (env.JobContext, error) {
 		return nil, errors.New("failed to inject checkInFunc")
 	}
 
+	if resultFunc, ok := dep.ExtraData["resultFunc"]; ok {
+		if reflect.TypeOf(resultFunc).Kind() == reflect.Func {
+			if funcRef, ok := resultFunc.(job.ResultFunc); ok {
+				jContext.resultFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.resultFunc == nil {
+		return nil, errors.New("failed to inject resultFunc")
+	}
+
+	if progressFunc, ok := dep.ExtraData["progressFunc"]; ok {
+		if reflect.TypeOf(progressFunc).Kind() == reflect.Func {
+			if funcRef, ok := progressFunc.(job.ProgressFunc); ok {
+				jContext.progressFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.progressFunc == nil {
+		return nil, errors.New("failed to inject progressFunc")
+	}
+
+	if saveCheckpointFunc, ok := dep.ExtraData["saveCheckpointFunc"]; ok {
+		if reflect.TypeOf(saveCheckpointFunc).Kind() == reflect.Func {
+			if funcRef, ok := saveCheckpointFunc.(job.SaveCheckpointFunc); ok {
+				jContext.saveCheckpointFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.saveCheckpointFunc == nil {
+		return nil, errors.New("failed to inject saveCheckpointFunc")
+	}
+
+	if loadCheckpointFunc, ok := dep.ExtraData["loadCheckpointFunc"]; ok {
+		if reflect.TypeOf(loadCheckpointFunc).Kind() == reflect.Func {
+			if funcRef, ok := loadCheckpointFunc.(job.LoadCheckpointFunc); ok {
+				jContext.loadCheckpointFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.loadCheckpointFunc == nil {
+		return nil, errors.New("failed to inject loadCheckpointFunc")
+	}
+
+	if acquireLockFunc, ok := dep.ExtraData["acquireLockFunc"]; ok {
+		if reflect.TypeOf(acquireLockFunc).Kind() == reflect.Func {
+			if funcRef, ok := acquireLockFunc.(job.AcquireLockFunc); ok {
+				jContext.acquireLockFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.acquireLockFunc == nil {
+		return nil, errors.New("failed to inject acquireLockFunc")
+	}
+
+	if releaseLockFunc, ok := dep.ExtraData["releaseLockFunc"]; ok {
+		if reflect.TypeOf(releaseLockFunc).Kind() == reflect.Func {
+			if funcRef, ok := releaseLockFunc.(job.ReleaseLockFunc); ok {
+				jContext.releaseLockFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.releaseLockFunc == nil {
+		return nil, errors.New("failed to inject releaseLockFunc")
+	}
+
+	if enqueueFunc, ok := dep.ExtraData["enqueueFunc"]; ok {
+		if reflect.TypeOf(enqueueFunc).Kind() == reflect.Func {
+			if funcRef, ok := enqueueFunc.(job.EnqueueFunc); ok {
+				jContext.enqueueFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.enqueueFunc == nil {
+		return nil, errors.New("failed to inject enqueueFunc")
+	}
+
 	return jContext, nil
 }
 
@@ -123,6 +226,104 @@ func (c *Context) Checkin(status string) error {
 	return nil
 }
 
+// SetResult sets the structured result object of the job
+func (c *Context) SetResult(result interface{}) error {
+	if c.resultFunc == nil {
+		return errors.New("nil result function")
+	}
+
+	return c.resultFunc(result)
+}
+
+// ReportProgress reports the structured progress of the job
+func (c *Context) ReportProgress(current, total uint64, phase, message string) error {
+	if c.progressFunc == nil {
+		return errors.New("nil progress function")
+	}
+
+	return c.progressFunc(current, total, phase, message)
+}
+
+// SaveCheckpoint persists the checkpoint of the job with the specified key
+func (c *Context) SaveCheckpoint(key string, value interface{}) error {
+	if c.saveCheckpointFunc == nil {
+		return errors.New("nil save checkpoint function")
+	}
+
+	return c.saveCheckpointFunc(key, value)
+}
+
+// LoadCheckpoint loads the checkpoint saved by the previous attempts of the job
+func (c *Context) LoadCheckpoint(key string, value interface{}) (bool, error) {
+	if c.loadCheckpointFunc == nil {
+		return false, errors.New("nil load checkpoint function")
+	}
+
+	data, err := c.loadCheckpointFunc(key)
+	if err != nil {
+		return false, err
+	}
+
+	if data == nil {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AcquireLock tries to acquire the named lock without blocking
+func (c *Context) AcquireLock(name string, ttl time.Duration) (bool, error) {
+	return c.AcquireSemaphore(name, 1, ttl)
+}
+
+// ReleaseLock releases the named lock held by the job
+func (c *Context) ReleaseLock(name string) error {
+	return c.ReleaseSemaphore(name)
+}
+
+// AcquireSemaphore tries to acquire the named counting semaphore without blocking
+func (c *Context) AcquireSemaphore(name string, limit uint, ttl time.Duration) (bool, error) {
+	if c.acquireLockFunc == nil {
+		return false, errors.New("nil acquire lock function")
+	}
+
+	return c.acquireLockFunc(name, limit, ttl)
+}
+
+// ReleaseSemaphore releases the named semaphore held by the job
+func (c *Context) ReleaseSemaphore(name string) error {
+	if c.releaseLockFunc == nil {
+		return errors.New("nil release lock function")
+	}
+
+	return c.releaseLockFunc(name)
+}
+
+// Enqueue enqueues the child job
+func (c *Context) Enqueue(jobName string, params map[string]interface{}, await bool) (string, error) {
+	if c.enqueueFunc == nil {
+		return "", errors.New("nil enqueue function")
+	}
+
+	return c.enqueueFunc(jobName, params, 0, await)
+}
+
+// Schedule enqueues the child job to run after the specified seconds
+func (c *Context) Schedule(jobName string, params map[string]interface{}, runAfterSeconds uint64, await bool) (string, error) {
+	if c.enqueueFunc == nil {
+		return "", errors.New("nil enqueue function")
+	}
+	if runAfterSeconds == 0 {
+		return "", errors.New("delay of the scheduled job should be greater than 0")
+	}
+
+	return c.enqueueFunc(jobName, params, runAfterSeconds, await)
+}
+
 // OPCommand return the control operational command like stop/cancel if have
 func (c *Context) OPCommand() (string, bool) {
 	if c.opCommandFunc != nil {
@@ -3,19 +3,28 @@
 package impl
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/Colstuwjx/job/env"
 	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/impl/job"
 	"github.com/Colstuwjx/job/opm"
 )
 
+// demoPhaseCheckpoint is the checkpoint key of the finished phases of DemoJob.
+const demoPhaseCheckpoint = "phase"
+
 // DemoJob is the job to demostrate the job interface.
 type DemoJob struct{}
 
+// demoParams keeps the typed parameters of DemoJob.
+type demoParams struct {
+	Image string        `param:"image,required"`
+	Hold  time.Duration `param:"hold" default:"15s"`
+}
+
 // MaxFails is implementation of same method in Interface.
 func (dj *DemoJob) MaxFails() uint {
 	return 3
@@ -28,17 +37,13 @@ func (dj *DemoJob) ShouldRetry() bool {
 
 // Validate is implementation of same method in Interface.
 func (dj *DemoJob) Validate(params map[string]interface{}) error {
-	if params == nil || len(params) == 0 {
-		return errors.New("parameters required for replication job")
-	}
-
-	name, ok := params["image"]
-	if !ok {
-		return errors.New("missing parameter 'image'")
+	p := &demoParams{}
+	if err := job.DecodeParameters(params, p); err != nil {
+		return err
 	}
 
-	if !strings.HasPrefix(name.(string), "demo") {
-		return fmt.Errorf("expected '%s' but got '%s'", "demo steven", name)
+	if !strings.HasPrefix(p.Image, "demo") {
+		return errs.InvalidParametersError(fmt.Sprintf("'image' expected '%s' but got '%s'", "demo steven", p.Image))
 	}
 
 	return nil
@@ -48,6 +53,11 @@ func (dj *DemoJob) Validate(params map[string]interface{}) error {
 func (dj *DemoJob) Run(ctx env.JobContext, params map[string]interface{}) error {
 	logger := ctx.GetLogger()
 
+	p := &demoParams{}
+	if err := job.DecodeParameters(params, p); err != nil {
+		return err
+	}
+
 	defer func() {
 		logger.Info("I'm finished, exit!")
 		fmt.Println("I'm finished, exit!")
@@ -68,19 +78,35 @@ func (dj *DemoJob) Run(ctx env.JobContext, params map[string]interface{}) error
 	// var runtime_err error = nil
 	// fmt.Println(runtime_err.Error())
 
-	logger.Info("check in 30%")
-	ctx.Checkin("30%")
-	time.Sleep(2 * time.Second)
-	logger.Warning("check in 60%")
-	ctx.Checkin("60%")
-	time.Sleep(2 * time.Second)
-	logger.Debug("check in 100%")
-	ctx.Checkin("100%")
-	time.Sleep(1 * time.Second)
+	// Skip the phases finished by the previous attempts
+	finished := 0
+	if ok, err := ctx.LoadCheckpoint(demoPhaseCheckpoint, &finished); err != nil {
+		logger.Warningf("failed to load checkpoint: %s", err)
+	} else if ok {
+		logger.Infof("resume from phase %d", finished)
+	}
+
+	phases := []string{"prepare", "transfer", "verify"}
+	for i, phase := range phases {
+		if i < finished {
+			logger.Infof("skip finished phase %d", i)
+			continue
+		}
+
+		logger.Infof("running phase %s", phase)
+		time.Sleep(2 * time.Second)
+		if err := ctx.ReportProgress(uint64(i+1), uint64(len(phases)), phase, ""); err != nil {
+			logger.Warningf("failed to report progress: %s", err)
+		}
+
+		if err := ctx.SaveCheckpoint(demoPhaseCheckpoint, i+1); err != nil {
+			logger.Warningf("failed to save checkpoint: %s", err)
+		}
+	}
 
 	// HOLD ON FOR A WHILE
-	logger.Error("Holding for 20 sec")
-	<-time.After(15 * time.Second)
+	logger.Errorf("Holding for %s", p.Hold)
+	<-time.After(p.Hold)
 	// logger.Fatal("I'm back, check if I'm stopped/cancelled")
 
 	if cmd, ok := ctx.OPCommand(); ok {
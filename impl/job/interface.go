@@ -3,7 +3,10 @@
 package job
 
 import (
+	"time"
+
 	"github.com/Colstuwjx/job/env"
+	"github.com/Colstuwjx/job/models"
 )
 
 // CheckOPCmdFunc is the function to check if the related operation commands
@@ -14,6 +17,32 @@ type CheckOPCmdFunc func() (string, bool)
 // CheckInFunc is designed for job to report more detailed progress info
 type CheckInFunc func(message string)
 
+// ResultFunc is designed for job to set the structured result object
+// which can be retrieved via the API after the job is done.
+type ResultFunc func(result interface{}) error
+
+// ProgressFunc is designed for job to report the structured progress
+type ProgressFunc func(current, total uint64, phase, message string) error
+
+// SaveCheckpointFunc is designed for job to persist the checkpoint of the specified key,
+// then the job can resume from the checkpoint when being retried.
+type SaveCheckpointFunc func(key string, value interface{}) error
+
+// LoadCheckpointFunc is designed for job to load the raw json data of the checkpoint
+// with the specified key. Nil data is returned if the checkpoint is not existing.
+type LoadCheckpointFunc func(key string) ([]byte, error)
+
+// AcquireLockFunc is designed for job to acquire the named semaphore allowing at most limit holders,
+// the lock is a semaphore with the limit 1. False is returned if the semaphore is exhausted.
+type AcquireLockFunc func(name string, limit uint, ttl time.Duration) (bool, error)
+
+// ReleaseLockFunc is designed for job to release the named lock or semaphore held by it.
+type ReleaseLockFunc func(name string) error
+
+// EnqueueFunc is designed for job to enqueue the child job which runs after the specified seconds.
+// If await is true, the parent job does not succeed until all the awaited child jobs are finished.
+type EnqueueFunc func(jobName string, params map[string]interface{}, runAfterSeconds uint64, await bool) (string, error)
+
 // Interface defines the related injection and run entry methods.
 type Interface interface {
 	// Declare how many times the job can be retried if failed.
@@ -46,3 +75,18 @@ type Interface interface {
 	//
 	Run(ctx env.JobContext, params map[string]interface{}) error
 }
+
+// ConcurrencyLimiter can be implemented by the job optionally to declare the max number of
+// the jobs of the type running at the same time across all the worker pools.
+// The jobs beyond the limit stay in the queue until the running ones end.
+type ConcurrencyLimiter interface {
+	// MaxConcurrency returns the max number of the running jobs of the type, 0 for no limit.
+	MaxConcurrency() uint
+}
+
+// Retrier can be implemented by the job optionally to declare how the failed jobs of the type are retried.
+// The retry policy in the metadata of the submitted job takes precedence over it.
+type Retrier interface {
+	// RetryPolicy returns the retry policy of the job type, nil for the default one.
+	RetryPolicy() *models.RetryPolicy
+}
@@ -23,4 +23,7 @@ const (
 
 	// JobStatusScheduled : job status scheduled
 	JobStatusScheduled = "Scheduled"
+
+	// JobStatusExpired   : job status expired
+	JobStatusExpired = "Expired"
 )
@@ -0,0 +1,334 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Colstuwjx/job/errs"
+)
+
+const (
+	paramTag   = "param"
+	defaultTag = "default"
+	requiredOp = "required"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DecodeParameters decodes the job parameters into the struct pointed by out.
+// It can be called in both the 'Validate' and the 'Run' method of the job.
+//
+// The struct fields are mapped with the tag 'param' like `param:"name,required"`.
+// If no 'param' tag is set, the name in the 'json' tag or the field name is used.
+// Fields with tag `param:"-"` are skipped.
+// The tag 'default' provides the value of the field if the parameter is missing,
+// e.g: `default:"3"`, `default:"30s"` or `default:"[\"a\",\"b\"]"`.
+//
+// The time.Duration fields accept the duration string like '1m30s' or a number of seconds.
+// The nested struct fields are decoded from the nested objects.
+//
+// All the violations are collected and returned as one error with the
+// errs.InvalidParametersError format, the same as the one returned by the API.
+func DecodeParameters(params map[string]interface{}, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("the decoding target must be a non-nil ptr of struct")
+	}
+
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+
+	d := &paramsDecoder{}
+	d.decodeStruct("", params, v.Elem())
+
+	if len(d.violations) > 0 {
+		return errs.InvalidParametersError(strings.Join(d.violations, "; "))
+	}
+
+	return nil
+}
+
+// paramsDecoder collects the violations during the decoding
+type paramsDecoder struct {
+	violations []string
+}
+
+func (d *paramsDecoder) fail(path string, format string, v ...interface{}) {
+	d.violations = append(d.violations, fmt.Sprintf("'%s' %s", path, fmt.Sprintf(format, v...)))
+}
+
+func (d *paramsDecoder) decodeStruct(path string, src map[string]interface{}, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		name, required, skip := parseParamTag(field)
+		if skip {
+			continue
+		}
+
+		fieldPath := name
+		if len(path) > 0 {
+			fieldPath = fmt.Sprintf("%s.%s", path, name)
+		}
+
+		raw, ok := src[name]
+		if !ok || raw == nil {
+			if def, has := field.Tag.Lookup(defaultTag); has {
+				d.decodeDefault(fieldPath, def, v.Field(i))
+				continue
+			}
+
+			if required {
+				d.fail(fieldPath, "is required")
+			}
+			continue
+		}
+
+		d.decodeValue(fieldPath, raw, v.Field(i))
+	}
+}
+
+func (d *paramsDecoder) decodeDefault(path string, def string, v reflect.Value) {
+	var raw interface{} = def
+
+	if v.Kind() != reflect.String {
+		// Numbers, bools and composite values are written in json format.
+		// Keep the raw string if it is not a json value, e.g: duration '30s'.
+		var jv interface{}
+		if err := json.Unmarshal([]byte(def), &jv); err == nil {
+			raw = jv
+		}
+	}
+
+	before := len(d.violations)
+	d.decodeValue(path, raw, v)
+	if len(d.violations) > before {
+		d.violations = append(d.violations[:before], fmt.Sprintf("'%s' has invalid default value '%s'", path, def))
+	}
+}
+
+func (d *paramsDecoder) decodeValue(path string, raw interface{}, v reflect.Value) {
+	if v.Type() == durationType {
+		dur, err := toDuration(raw)
+		if err != nil {
+			d.fail(path, "%s", err)
+			return
+		}
+		v.SetInt(int64(dur))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if raw == nil {
+			// The null decodes to the nil pointer
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		elem := reflect.New(v.Type().Elem())
+		before := len(d.violations)
+		d.decodeValue(path, raw, elem.Elem())
+		if len(d.violations) == before {
+			v.Set(elem)
+		}
+	case reflect.Interface:
+		if raw == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		if !reflect.TypeOf(raw).AssignableTo(v.Type()) {
+			d.fail(path, "expect %s but got %s", v.Type(), typeOf(raw))
+			return
+		}
+		v.Set(reflect.ValueOf(raw))
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			d.fail(path, "expect string but got %s", typeOf(raw))
+			return
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			d.fail(path, "expect bool but got %s", typeOf(raw))
+			return
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := toFloat(raw)
+		if !ok || f != math.Trunc(f) {
+			d.fail(path, "expect integer but got %s", typeOf(raw))
+			return
+		}
+		if v.OverflowInt(int64(f)) {
+			d.fail(path, "value %v overflows %s", raw, v.Type())
+			return
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := toFloat(raw)
+		if !ok || f != math.Trunc(f) || f < 0 {
+			d.fail(path, "expect non-negative integer but got %s", typeOf(raw))
+			return
+		}
+		if v.OverflowUint(uint64(f)) {
+			d.fail(path, "value %v overflows %s", raw, v.Type())
+			return
+		}
+		v.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(raw)
+		if !ok {
+			d.fail(path, "expect number but got %s", typeOf(raw))
+			return
+		}
+		v.SetFloat(f)
+	case reflect.Struct:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			d.fail(path, "expect object but got %s", typeOf(raw))
+			return
+		}
+		d.decodeStruct(path, m, v)
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			d.fail(path, "expect array but got %s", typeOf(raw))
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			d.decodeValue(fmt.Sprintf("%s[%d]", path, i), item, s.Index(i))
+		}
+		v.Set(s)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			d.fail(path, "only map with string keys is supported")
+			return
+		}
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			d.fail(path, "expect object but got %s", typeOf(raw))
+			return
+		}
+		mv := reflect.MakeMapWithSize(v.Type(), len(m))
+		for k, item := range m {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			d.decodeValue(fmt.Sprintf("%s.%s", path, k), item, elem)
+			mv.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		v.Set(mv)
+	default:
+		d.fail(path, "has unsupported type %s", v.Type())
+	}
+}
+
+// parseParamTag returns the parameter name, required flag and skip flag of the field
+func parseParamTag(field reflect.StructField) (string, bool, bool) {
+	name := field.Name
+	if jsonTag, ok := field.Tag.Lookup("json"); ok {
+		if n := strings.Split(jsonTag, ",")[0]; len(n) > 0 && n != "-" {
+			name = n
+		}
+	}
+
+	tag, ok := field.Tag.Lookup(paramTag)
+	if !ok {
+		return name, false, false
+	}
+
+	if tag == "-" {
+		return "", false, true
+	}
+
+	required := false
+	parts := strings.Split(tag, ",")
+	if len(parts[0]) > 0 {
+		name = parts[0]
+	}
+	for _, op := range parts[1:] {
+		if strings.TrimSpace(op) == requiredOp {
+			required = true
+		}
+	}
+
+	return name, required, false
+}
+
+// toFloat converts the json number to float64
+func toFloat(raw interface{}) (float64, bool) {
+	switch n := raw.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toDuration accepts duration string like '1m30s' or number of seconds
+func toDuration(raw interface{}) (time.Duration, error) {
+	if s, ok := raw.(string); ok {
+		dur, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("expect duration but got '%s'", s)
+		}
+		return dur, nil
+	}
+
+	if f, ok := toFloat(raw); ok {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+
+	return 0, fmt.Errorf("expect duration string or seconds but got %s", typeOf(raw))
+}
+
+func typeOf(raw interface{}) string {
+	switch raw.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case nil:
+		return "null"
+	}
+
+	if _, ok := toFloat(raw); ok {
+		return fmt.Sprintf("number %v", raw)
+	}
+
+	return fmt.Sprintf("%T", raw)
+}
@@ -0,0 +1,176 @@
+// Copyright Project Harbor Authors. All rights reserved.
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Colstuwjx/job/errs"
+)
+
+type fakeTarget struct {
+	URL      string `param:"url,required"`
+	Insecure bool   `param:"insecure" default:"true"`
+}
+
+type fakeParams struct {
+	Image    string            `param:"image,required"`
+	Retries  int               `param:"retries" default:"3"`
+	Ratio    float64           `param:"ratio"`
+	Timeout  time.Duration     `param:"timeout" default:"30s"`
+	Interval time.Duration     `param:"interval"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `param:"labels"`
+	Target   *fakeTarget       `param:"target,required"`
+	Ignored  string            `param:"-"`
+}
+
+func TestDecodeParameters(t *testing.T) {
+	raw := `{
+		"image": "demo/nginx",
+		"ratio": 0.5,
+		"interval": 90,
+		"tags": ["v1", "v2"],
+		"labels": {"team": "infra"},
+		"target": {"url": "http://localhost:9090"},
+		"Ignored": "should not be set"
+	}`
+
+	params := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &fakeParams{}
+	if err := DecodeParameters(params, p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Image != "demo/nginx" {
+		t.Errorf("expect image 'demo/nginx' but got '%s'", p.Image)
+	}
+	if p.Retries != 3 {
+		t.Errorf("expect default retries 3 but got %d", p.Retries)
+	}
+	if p.Ratio != 0.5 {
+		t.Errorf("expect ratio 0.5 but got %f", p.Ratio)
+	}
+	if p.Timeout != 30*time.Second {
+		t.Errorf("expect default timeout 30s but got %s", p.Timeout)
+	}
+	if p.Interval != 90*time.Second {
+		t.Errorf("expect interval 90s but got %s", p.Interval)
+	}
+	if len(p.Tags) != 2 || p.Tags[1] != "v2" {
+		t.Errorf("expect tags [v1 v2] but got %v", p.Tags)
+	}
+	if p.Labels["team"] != "infra" {
+		t.Errorf("expect label team=infra but got %v", p.Labels)
+	}
+	if p.Target == nil || p.Target.URL != "http://localhost:9090" || !p.Target.Insecure {
+		t.Errorf("expect nested target decoded but got %#v", p.Target)
+	}
+	if p.Ignored != "" {
+		t.Errorf("expect skipped field empty but got '%s'", p.Ignored)
+	}
+}
+
+func TestDecodeParametersViolations(t *testing.T) {
+	params := map[string]interface{}{
+		"retries":  1.5,
+		"interval": "soon",
+		"target":   map[string]interface{}{},
+	}
+
+	err := DecodeParameters(params, &fakeParams{})
+	if err == nil {
+		t.Fatal("expect error but got nil")
+	}
+
+	if !errs.IsInvalidParametersError(err) {
+		t.Fatalf("expect invalid parameters error but got %s", err)
+	}
+
+	for _, path := range []string{"'image' is required", "'retries'", "'interval'", "'target.url' is required"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("expect violation %s in error %s", path, err)
+		}
+	}
+}
+
+func TestDecodeParametersBadTarget(t *testing.T) {
+	if err := DecodeParameters(nil, fakeParams{}); err == nil {
+		t.Fatal("expect error for none ptr target but got nil")
+	}
+}
+
+type fakeNullableParams struct {
+	Extra   map[string]interface{} `param:"extra"`
+	Items   []interface{}          `param:"items"`
+	Targets []*fakeTarget          `param:"targets"`
+	Name    fmt.Stringer           `param:"name"`
+}
+
+func TestDecodeParametersWithNulls(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		invalid bool
+		check   func(p *fakeNullableParams) bool
+	}{
+		{
+			name: "null value of map",
+			raw:  `{"extra": {"key": null, "other": 1}}`,
+			check: func(p *fakeNullableParams) bool {
+				v, ok := p.Extra["key"]
+				return ok && v == nil && p.Extra["other"] == float64(1)
+			},
+		},
+		{
+			name: "null item of slice",
+			raw:  `{"items": [null, "a"]}`,
+			check: func(p *fakeNullableParams) bool {
+				return len(p.Items) == 2 && p.Items[0] == nil && p.Items[1] == "a"
+			},
+		},
+		{
+			name: "null item of pointer slice",
+			raw:  `{"targets": [null, {"url": "http://localhost:9090"}]}`,
+			check: func(p *fakeNullableParams) bool {
+				return len(p.Targets) == 2 && p.Targets[0] == nil && p.Targets[1] != nil && p.Targets[1].URL == "http://localhost:9090"
+			},
+		},
+		{
+			name:    "value not assignable to non-empty interface",
+			raw:     `{"name": "demo"}`,
+			invalid: true,
+		},
+	}
+
+	for _, c := range cases {
+		params := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(c.raw), &params); err != nil {
+			t.Fatal(err)
+		}
+
+		p := &fakeNullableParams{}
+		err := DecodeParameters(params, p)
+		if c.invalid {
+			if !errs.IsInvalidParametersError(err) {
+				t.Errorf("%s: expect invalid parameters error but got %v", c.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: expect nil error but got %s", c.name, err)
+			continue
+		}
+		if !c.check(p) {
+			t.Errorf("%s: unexpected decoded parameters %#v", c.name, p)
+		}
+	}
+}
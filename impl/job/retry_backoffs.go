@@ -0,0 +1,14 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package job
+
+const (
+	// RetryBackoffExponential : the delay doubles after each retry
+	RetryBackoffExponential = "exponential"
+
+	// RetryBackoffLinear : the delay increases by the base delay after each retry
+	RetryBackoffLinear = "linear"
+
+	// RetryBackoffFixed : the delay keeps the same
+	RetryBackoffFixed = "fixed"
+)
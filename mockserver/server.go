@@ -15,8 +15,11 @@ import (
 )
 
 const (
-	jobUUID    = "u-1234-5678-9012"
-	jobsPrefix = "/api/v1/jobs"
+	jobUUID      = "u-1234-5678-9012"
+	jobsPrefix   = "/api/v1/jobs"
+	queuesRoute  = "/api/v1/queues"
+	batchID      = "b-1234-5678-9012"
+	batchesRoute = "/api/v1/batches"
 )
 
 func currPath() string {
@@ -94,5 +97,74 @@ func NewJobServiceServer() *httptest.Server {
 				}
 			}
 		})
+	mux.HandleFunc(queuesRoute,
+		func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			respData := models.JobQueues{
+				Queues: []*models.JobQueue{
+					{
+						JobName: "replication",
+						Pending: 1,
+						Latency: 5,
+					},
+				},
+			}
+			b, _ := json.Marshal(respData)
+			rw.WriteHeader(http.StatusOK)
+			if _, err := rw.Write(b); err != nil {
+				panic(err)
+			}
+		})
+	mux.HandleFunc(batchesRoute,
+		func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodPost {
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			data, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+			batchReq := models.JobBatchRequest{}
+			json.Unmarshal(data, &batchReq)
+			respData := models.JobBatch{
+				BatchID: batchID,
+				Total:   int64(len(batchReq.Jobs)),
+				Counts:  map[string]int64{"Pending": int64(len(batchReq.Jobs))},
+			}
+			for range batchReq.Jobs {
+				respData.Jobs = append(respData.Jobs, &models.JobStatData{
+					JobID:   jobUUID,
+					Status:  "Pending",
+					BatchID: batchID,
+				})
+			}
+			b, _ := json.Marshal(respData)
+			rw.WriteHeader(http.StatusAccepted)
+			if _, err := rw.Write(b); err != nil {
+				panic(err)
+			}
+		})
+	mux.HandleFunc(fmt.Sprintf("%s/%s", batchesRoute, batchID),
+		func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			respData := models.JobBatch{
+				BatchID:   batchID,
+				Total:     1,
+				Completed: true,
+				Counts:    map[string]int64{"Success": 1},
+			}
+			b, _ := json.Marshal(respData)
+			rw.WriteHeader(http.StatusOK)
+			if _, err := rw.Write(b); err != nil {
+				panic(err)
+			}
+		})
 	return httptest.NewServer(mux)
 }
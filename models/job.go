@@ -2,6 +2,10 @@
 
 package models
 
+import (
+	"encoding/json"
+)
+
 // Parameters for job execution.
 type Parameters map[string]interface{}
 
@@ -16,6 +20,22 @@ type JobData struct {
 	Parameters Parameters   `json:"parameters"`
 	Metadata   *JobMetadata `json:"metadata"`
 	StatusHook string       `json:"status_hook"`
+	// The follow-up jobs enqueued when the job ends
+	JobCallbacks
+}
+
+// JobCallbacks keeps the follow-up jobs of the job regarding of its terminal status.
+type JobCallbacks struct {
+	OnSuccess  *JobCallback `json:"on_success,omitempty"`
+	OnFailure  *JobCallback `json:"on_failure,omitempty"`
+	OnComplete *JobCallback `json:"on_complete,omitempty"`
+}
+
+// JobCallback is the follow-up job with the parameter template.
+// The status and result of the ended job are injected into the parameters when it's enqueued.
+type JobCallback struct {
+	Name       string     `json:"name"`
+	Parameters Parameters `json:"parameters,omitempty"`
 }
 
 // JobMetadata stores the metadata of job.
@@ -24,6 +44,30 @@ type JobMetadata struct {
 	ScheduleDelay uint64 `json:"schedule_delay,omitempty"`
 	Cron          string `json:"cron_spec,omitempty"`
 	IsUnique      bool   `json:"unique"`
+	// The replayed submission with the same key returns the original job instead of enqueuing again
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// The jobs with the same key run one by one in the submission order
+	PartitionKey string `json:"partition_key,omitempty"`
+	// Overrides the retry policy of the job type
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	// The job expires if it has not started before the time (unix seconds)
+	ExpireAt int64 `json:"expire_at,omitempty"`
+	// The job expires if it has not started in the seconds after it's due to run
+	MaxQueueTime int64 `json:"max_queue_time,omitempty"`
+}
+
+// RetryPolicy decides how many times and when the failed job is retried.
+type RetryPolicy struct {
+	// The max number of the runs including the first one, 0 for the MaxFails of the job
+	MaxAttempts uint `json:"max_attempts,omitempty"`
+	// The backoff strategy, "exponential" (default), "linear" or "fixed"
+	Backoff string `json:"backoff,omitempty"`
+	// The delay (seconds) before the first retry, 0 for the default one
+	Delay int64 `json:"delay,omitempty"`
+	// The max delay (seconds) before each retry, 0 for no cap
+	MaxDelay int64 `json:"max_delay,omitempty"`
+	// The delay is randomized by the fraction (0-1) of it
+	Jitter float64 `json:"jitter,omitempty"`
 }
 
 // JobStats keeps the result of job launching.
@@ -33,25 +77,94 @@ type JobStats struct {
 
 // JobStatData keeps the stats of job
 type JobStatData struct {
-	JobID       string `json:"id"`
-	Status      string `json:"status"`
-	JobName     string `json:"name"`
-	JobKind     string `json:"kind"`
-	IsUnique    bool   `json:"unique"`
-	RefLink     string `json:"ref_link,omitempty"`
-	CronSpec    string `json:"cron_spec,omitempty"`
-	EnqueueTime int64  `json:"enqueue_time"`
-	UpdateTime  int64  `json:"update_time"`
-	RunAt       int64  `json:"run_at,omitempty"`
-	CheckIn     string `json:"check_in,omitempty"`
-	CheckInAt   int64  `json:"check_in_at,omitempty"`
-	DieAt       int64  `json:"die_at,omitempty"`
-	HookStatus  string `json:"hook_status,omitempty"`
+	JobID        string `json:"id"`
+	Status       string `json:"status"`
+	JobName      string `json:"name"`
+	JobKind      string `json:"kind"`
+	IsUnique     bool   `json:"unique"`
+	RefLink      string `json:"ref_link,omitempty"`
+	CronSpec     string `json:"cron_spec,omitempty"`
+	EnqueueTime  int64  `json:"enqueue_time"`
+	UpdateTime   int64  `json:"update_time"`
+	RunAt        int64  `json:"run_at,omitempty"`
+	NextRetryAt  int64  `json:"next_retry_at,omitempty"`
+	ExpireAt     int64  `json:"expire_at,omitempty"`
+	CheckIn      string `json:"check_in,omitempty"`
+	CheckInAt    int64  `json:"check_in_at,omitempty"`
+	DieAt        int64  `json:"die_at,omitempty"`
+	HookStatus   string `json:"hook_status,omitempty"`
+	Revision     int64  `json:"revision,omitempty"`
+	LastError    string `json:"last_error,omitempty"`
+	HookDelivery string `json:"hook_delivery,omitempty"`
+	HookError    string `json:"hook_error,omitempty"`
+	BatchID      string `json:"batch_id,omitempty"`
+	PartitionKey string `json:"partition_key,omitempty"`
+	// ID of the job which enqueued this one
+	ParentID string `json:"parent_id,omitempty"`
+
+	Progress *JobProgress `json:"progress,omitempty"`
+}
+
+// JobProgress keeps the structured progress reported by the running job.
+type JobProgress struct {
+	Current    uint64  `json:"current"`
+	Total      uint64  `json:"total"`
+	Percent    float64 `json:"percent"`
+	Phase      string  `json:"phase,omitempty"`
+	Message    string  `json:"message,omitempty"`
+	ETA        int64   `json:"eta,omitempty"`
+	UpdateTime int64   `json:"update_time"`
+}
+
+// JobCheckIn is one timestamped check in message of the job.
+type JobCheckIn struct {
+	Message   string `json:"message"`
+	CheckInAt int64  `json:"check_in_at"`
+}
+
+// JobCheckIns keeps the check in history of the job, the latest one comes first.
+type JobCheckIns struct {
+	JobID    string        `json:"job_id"`
+	CheckIns []*JobCheckIn `json:"checkins"`
+}
+
+// JobStatusTransition is one accepted status transition of the job.
+type JobStatusTransition struct {
+	Status   string `json:"status"`
+	From     string `json:"from,omitempty"`
+	Revision int64  `json:"revision"`
+	Time     int64  `json:"time"`
+}
+
+// JobStatusTimeline keeps the accepted status transitions of the job in order.
+type JobStatusTimeline struct {
+	JobID    string                 `json:"job_id"`
+	Timeline []*JobStatusTransition `json:"timeline"`
+}
+
+// JobResult keeps the structured result object set by the job.
+type JobResult struct {
+	JobID  string          `json:"job_id"`
+	Result json.RawMessage `json:"result"`
 }
 
 // JobPoolStats represents the healthy and status of all the running worker pools.
 type JobPoolStats struct {
-	Pools []*JobPoolStatsData `json:"worker_pools"`
+	Pools        []*JobPoolStatsData  `json:"worker_pools"`
+	StatsManager *StatsManagerMetrics `json:"stats_manager,omitempty"`
+}
+
+// StatsManagerMetrics represents the status of the job stats processing pipeline.
+type StatsManagerMetrics struct {
+	Workers        uint   `json:"workers"`
+	QueueCapacity  uint   `json:"queue_capacity"`
+	QueueDepth     uint   `json:"queue_depth"`
+	Retrying       uint   `json:"retrying"`
+	OverflowPolicy string `json:"overflow_policy"`
+	Processed      uint64 `json:"processed"`
+	Failed         uint64 `json:"failed"`
+	Coalesced      uint64 `json:"coalesced"`
+	Dropped        uint64 `json:"dropped"`
 }
 
 // JobPoolStatsData represent the healthy and status of the worker pool.
@@ -60,8 +173,160 @@ type JobPoolStatsData struct {
 	StartedAt    int64    `json:"started_at"`
 	HeartbeatAt  int64    `json:"heartbeat_at"`
 	JobNames     []string `json:"job_names"`
-	Concurrency  uint     `json:"concurrency"`
-	Status       string   `json:"status"`
+	// The job names of which the processing is paused
+	PausedJobNames []string `json:"paused_job_names,omitempty"`
+	Concurrency    uint     `json:"concurrency"`
+	Status         string   `json:"status"`
+}
+
+// JobQuery keeps the conditions of listing the archived jobs.
+type JobQuery struct {
+	Name   string
+	Kind   string
+	Status string
+	// Range of the job update time, 0 for unlimited
+	Since int64
+	Until int64
+	// Page starts from 1
+	Page     uint
+	PageSize uint
+}
+
+// JobList keeps the archived jobs matching the query.
+type JobList struct {
+	Total int64          `json:"total"`
+	Jobs  []*JobStatData `json:"jobs"`
+}
+
+// DeadJob keeps the info of the job in the dead queue.
+type DeadJob struct {
+	JobID     string     `json:"id"`
+	Name      string     `json:"name"`
+	Args      Parameters `json:"args,omitempty"`
+	Fails     int64      `json:"fails"`
+	LastError string     `json:"last_error,omitempty"`
+	FailedAt  int64      `json:"failed_at"`
+	DiedAt    int64      `json:"died_at"`
+}
+
+// DeadJobList keeps the dead jobs of the requested page.
+type DeadJobList struct {
+	Total int64      `json:"total"`
+	Jobs  []*DeadJob `json:"jobs"`
+}
+
+// DeadJobFilter selects the dead jobs.
+type DeadJobFilter struct {
+	Name string `json:"name,omitempty"`
+	// Only the jobs died at least the specified seconds ago
+	OlderThan int64 `json:"older_than,omitempty"`
+}
+
+// DeadJobsActionRequest defines for retrying or deleting the dead jobs in batch.
+// The dead jobs are selected by the IDs and/or the filter.
+type DeadJobsActionRequest struct {
+	Action string         `json:"action"`
+	JobIDs []string       `json:"job_ids,omitempty"`
+	Filter *DeadJobFilter `json:"filter,omitempty"`
+}
+
+// DeadJobsActionResult keeps the result of the batch action on the dead jobs.
+type DeadJobsActionResult struct {
+	Action    string `json:"action"`
+	Matched   int    `json:"matched"`
+	Succeeded int    `json:"succeeded"`
+	// The failed jobs with the error message
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// JobQueue keeps the status of the queue of the job name.
+type JobQueue struct {
+	JobName string `json:"job_name"`
+	Pending int64  `json:"pending"`
+	// Age (seconds) of the oldest pending job
+	Latency   int64 `json:"latency"`
+	Scheduled int64 `json:"scheduled"`
+	Retry     int64 `json:"retry"`
+	Dead      int64 `json:"dead"`
+	Paused    bool  `json:"paused"`
+	// Number of the running jobs across all the pools
+	Running int64 `json:"running"`
+	// Max number of the running jobs across all the pools, 0 for no limit
+	MaxConcurrency int64 `json:"max_concurrency,omitempty"`
+}
+
+// JobQueues keeps the status of the queues of all the registered job names.
+type JobQueues struct {
+	Queues []*JobQueue `json:"queues"`
+}
+
+// ScheduleEntry is one upcoming run of the scheduled job or the periodic job policy.
+type ScheduleEntry struct {
+	RunAt    int64  `json:"run_at"`
+	JobName  string `json:"job_name"`
+	JobKind  string `json:"kind"`
+	JobID    string `json:"id,omitempty"`
+	PolicyID string `json:"policy_id,omitempty"`
+	// Digest of the job parameters to tell the runs of the same job name apart
+	ParamsDigest string `json:"params_digest"`
+}
+
+// JobSchedule keeps the upcoming runs in the time window, the earliest one comes first.
+type JobSchedule struct {
+	From    int64            `json:"from"`
+	To      int64            `json:"to"`
+	Entries []*ScheduleEntry `json:"entries"`
+	// True if the entries exceeding the limit are dropped
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// JobUpdateRequest defines for changing the job which has not started yet.
+// The omitted fields are kept unchanged.
+type JobUpdateRequest struct {
+	Parameters    Parameters `json:"parameters,omitempty"`
+	RunAt         int64      `json:"run_at,omitempty"`
+	ScheduleDelay uint64     `json:"schedule_delay,omitempty"`
+}
+
+// JobBatchRequest defines for submitting many jobs in one request.
+// The hook is fired once when all the jobs of the batch are ended.
+type JobBatchRequest struct {
+	Jobs       []*JobData `json:"jobs"`
+	StatusHook string     `json:"status_hook,omitempty"`
+}
+
+// JobBatch keeps the aggregate status of the jobs submitted in one batch.
+type JobBatch struct {
+	BatchID    string `json:"id"`
+	Total      int64  `json:"total"`
+	StatusHook string `json:"status_hook,omitempty"`
+	RefLink    string `json:"ref_link,omitempty"`
+	CreateTime int64  `json:"create_time"`
+	// The time when all the jobs are ended, 0 if not completed
+	CompleteTime int64 `json:"complete_time,omitempty"`
+	Completed    bool  `json:"completed"`
+	// The job counts by status
+	Counts map[string]int64 `json:"counts"`
+	// Only returned when the batch is submitted
+	Jobs []*JobStatData `json:"jobs,omitempty"`
+}
+
+// PartitionedJob is the job waiting in the backlog of the partition.
+type PartitionedJob struct {
+	JobID       string `json:"id"`
+	JobName     string `json:"name"`
+	EnqueueTime int64  `json:"enqueue_time"`
+}
+
+// JobPartition keeps the in-flight job and the backlog of the partition key.
+type JobPartition struct {
+	PartitionKey string `json:"partition_key"`
+	// ID of the in-flight job, empty if none
+	Running string `json:"running,omitempty"`
+	// Total count of the jobs in the backlog
+	Total int64 `json:"total"`
+	// The jobs of the requested page in the submission order
+	Jobs []*PartitionedJob `json:"jobs"`
 }
 
 // JobActionRequest defines for triggering job action like stop/cancel.
@@ -71,10 +336,11 @@ type JobActionRequest struct {
 
 // JobStatusChange is designed for reporting the status change via hook.
 type JobStatusChange struct {
-	JobID    string       `json:"job_id"`
-	Status   string       `json:"status"`
-	CheckIn  string       `json:"check_in,omitempty"`
-	Metadata *JobStatData `json:"metadata,omitempty"`
+	JobID    string          `json:"job_id"`
+	Status   string          `json:"status"`
+	CheckIn  string          `json:"check_in,omitempty"`
+	Metadata *JobStatData    `json:"metadata,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
 }
 
 // Message is designed for sub/pub messages
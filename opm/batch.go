@@ -0,0 +1,228 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package opm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// batchJobEndedScript marks the job of the batch as ended and completes the batch
+// if all the jobs are ended.
+//
+// KEYS[1]: key of the batch
+// KEYS[2]: key of the ended job ID set of the batch
+// ARGV[1]: the job ID
+// ARGV[2]: the current time (unix seconds)
+//
+// Returns: 1 if the batch is completed by this call, otherwise 0
+var batchJobEndedScript = redis.NewScript(2, `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return 0
+end
+
+redis.call('SADD', KEYS[2], ARGV[1])
+local ttl = redis.call('TTL', KEYS[1])
+if ttl > 0 then
+  redis.call('EXPIRE', KEYS[2], ttl)
+end
+
+local total = tonumber(redis.call('HGET', KEYS[1], 'total'))
+if total and redis.call('SCARD', KEYS[2]) >= total then
+  return redis.call('HSETNX', KEYS[1], 'complete_time', ARGV[2])
+end
+
+return 0
+`)
+
+// batchJobReopenedScript marks the job of the batch as not ended and reopens the batch
+// if the job has been counted as ended, so the batch is completed and reported again later.
+//
+// KEYS[1]: key of the batch
+// KEYS[2]: key of the ended job ID set of the batch
+// ARGV[1]: the job ID
+//
+// Returns: 1 if the job is reopened, otherwise 0
+var batchJobReopenedScript = redis.NewScript(2, `
+if redis.call('SREM', KEYS[2], ARGV[1]) == 0 then
+  return 0
+end
+
+redis.call('HDEL', KEYS[1], 'complete_time')
+return 1
+`)
+
+// BatchJobEnded is implementation of same method in JobStatsManager interface.
+func (rjs *RedisJobStatsManager) BatchJobEnded(jobID string) {
+	if utils.IsEmptyStr(jobID) {
+		return
+	}
+
+	rjs.submit(&queueItem{
+		op:   opBatchJobEnded,
+		data: jobID,
+	})
+}
+
+// RetrieveBatch is implementation of same method in JobStatsManager interface.
+// The jobs whose stats are not found are not counted.
+func (rjs *RedisJobStatsManager) RetrieveBatch(batchID string) (models.JobBatch, error) {
+	if utils.IsEmptyStr(batchID) {
+		return models.JobBatch{}, errors.New("empty batch ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	vals, err := redis.StringMap(conn.Do("HGETALL", utils.KeyBatch(rjs.namespace, batchID)))
+	if err != nil {
+		return models.JobBatch{}, err
+	}
+	if len(vals) == 0 {
+		return models.JobBatch{}, errs.NoObjectFoundError(fmt.Sprintf("batch '%s'", batchID))
+	}
+
+	batch := models.JobBatch{
+		BatchID:    batchID,
+		StatusHook: vals["status_hook"],
+		RefLink:    fmt.Sprintf("/api/v1/batches/%s", batchID),
+		Counts:     make(map[string]int64),
+	}
+	batch.Total, _ = strconv.ParseInt(vals["total"], 10, 64)
+	batch.CreateTime, _ = strconv.ParseInt(vals["create_time"], 10, 64)
+	batch.CompleteTime, _ = strconv.ParseInt(vals["complete_time"], 10, 64)
+	batch.Completed = batch.CompleteTime > 0
+
+	jobIDs, err := redis.Strings(conn.Do("LRANGE", utils.KeyBatchJobs(rjs.namespace, batchID), 0, -1))
+	if err != nil {
+		return models.JobBatch{}, err
+	}
+	if len(jobIDs) == 0 {
+		return batch, nil
+	}
+
+	for _, jobID := range jobIDs {
+		if err := conn.Send("HGET", utils.KeyJobStats(rjs.namespace, jobID), "status"); err != nil {
+			return models.JobBatch{}, err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return models.JobBatch{}, err
+	}
+	for range jobIDs {
+		status, err := redis.String(conn.Receive())
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			return models.JobBatch{}, err
+		}
+		batch.Counts[status]++
+	}
+
+	return batch, nil
+}
+
+// batchOf returns the ID of the batch which the job is submitted in,
+// empty if the job is not in any batch.
+func (rjs *RedisJobStatsManager) batchOf(conn redis.Conn, jobID string) string {
+	batchID, err := redis.String(conn.Do("HGET", utils.KeyJobStats(rjs.namespace, jobID), "batch_id"))
+	if err != nil {
+		return ""
+	}
+
+	return batchID
+}
+
+// trackBatch reopens the batch with the accepted status if the job counted as ended runs again,
+// e.g: the dead job is retried or the cancelled job is resumed.
+// Whether the job is ended is decided by the pool via BatchJobEnded, as the failed job may be retried.
+// The errors are just logged as the status has been updated.
+func (rjs *RedisJobStatsManager) trackBatch(jobID string, status string) {
+	if isFinalStatus(status) {
+		return
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	batchID := rjs.batchOf(conn, jobID)
+	if utils.IsEmptyStr(batchID) {
+		// Not in any batch
+		return
+	}
+
+	reopened, err := redis.Int(batchJobReopenedScript.Do(
+		conn,
+		utils.KeyBatch(rjs.namespace, batchID),
+		utils.KeyBatchEnded(rjs.namespace, batchID),
+		jobID,
+	))
+	if err != nil {
+		logger.Errorf("Failed to track job %s of batch %s: %s\n", jobID, batchID, err)
+		return
+	}
+	if reopened == 1 {
+		logger.Infof("Batch %s is reopened as job %s is %s\n", batchID, jobID, status)
+	}
+}
+
+// batchJobEnded records the job of the batch as ended,
+// and reports the batch via the hook once all the jobs of the batch are ended.
+func (rjs *RedisJobStatsManager) batchJobEnded(jobID string) error {
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	batchID := rjs.batchOf(conn, jobID)
+	if utils.IsEmptyStr(batchID) {
+		// Not in any batch
+		return nil
+	}
+
+	completed, err := redis.Int(batchJobEndedScript.Do(
+		conn,
+		utils.KeyBatch(rjs.namespace, batchID),
+		utils.KeyBatchEnded(rjs.namespace, batchID),
+		jobID,
+		time.Now().Unix(),
+	))
+	if err != nil {
+		return err
+	}
+	if completed == 0 {
+		return nil
+	}
+
+	logger.Infof("Batch %s is completed\n", batchID)
+
+	hookURL, err := redis.String(conn.Do("HGET", utils.KeyBatch(rjs.namespace, batchID), "status_hook"))
+	if err != nil || !utils.IsValidURL(hookURL) {
+		return nil
+	}
+
+	rjs.submit(&queueItem{
+		op:   opReportBatch,
+		data: []string{batchID, hookURL},
+	})
+
+	return nil
+}
+
+// reportBatch reports the aggregate status of the completed batch via the hook.
+func (rjs *RedisJobStatsManager) reportBatch(batchID string, hookURL string) error {
+	batch, err := rjs.RetrieveBatch(batchID)
+	if err != nil {
+		return err
+	}
+
+	return DefaultHookClient.ReportBatch(hookURL, batch)
+}
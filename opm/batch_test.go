@@ -0,0 +1,136 @@
+// Copyright Project Harbor Authors. All rights reserved.
+package opm
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+func TestBatch(t *testing.T) {
+	reported := make(chan models.JobBatch, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err == nil {
+			batch := models.JobBatch{}
+			if err := json.Unmarshal(data, &batch); err == nil {
+				reported <- batch
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mgr := NewRedisJobStatsManager(context.Background(), testingNamespace, redisPool)
+	mgr.Start()
+	defer mgr.Shutdown()
+	<-time.After(200 * time.Millisecond)
+
+	batchID := "fake_batch_ID"
+	jobIDs := []string{"fake_batch_job_1", "fake_batch_job_2"}
+	keys := []string{
+		utils.KeyBatch(testingNamespace, batchID),
+		utils.KeyBatchJobs(testingNamespace, batchID),
+		utils.KeyBatchEnded(testingNamespace, batchID),
+	}
+	for _, jobID := range jobIDs {
+		keys = append(keys,
+			utils.KeyJobStats(testingNamespace, jobID),
+			utils.KeyJobStatusTimeline(testingNamespace, jobID),
+		)
+	}
+	defer func() {
+		for _, key := range keys {
+			if err := clear(key, redisPool.Get()); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	if _, err := mgr.RetrieveBatch(batchID); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect object not found error but got %v\n", err)
+	}
+
+	conn := redisPool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("HMSET", keys[0], "id", batchID, "total", len(jobIDs), "create_time", time.Now().Unix(), "status_hook", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("RPUSH", keys[1], jobIDs[0], jobIDs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, jobID := range jobIDs {
+		stats := createFakeStats()
+		stats.Stats.JobID = jobID
+		stats.Stats.JobKind = job.JobKindGeneric
+		stats.Stats.BatchID = batchID
+		mgr.Save(stats)
+	}
+	<-time.After(200 * time.Millisecond)
+
+	mgr.SetJobStatus(jobIDs[0], job.JobStatusRunning)
+	mgr.SetJobStatus(jobIDs[1], job.JobStatusRunning)
+	<-time.After(100 * time.Millisecond)
+	mgr.SetJobStatus(jobIDs[0], job.JobStatusSuccess)
+	mgr.BatchJobEnded(jobIDs[0])
+	<-time.After(100 * time.Millisecond)
+
+	// The failed job may be retried, it's not ended until the pool says so
+	mgr.SetJobStatus(jobIDs[1], job.JobStatusError)
+	<-time.After(100 * time.Millisecond)
+
+	batch, err := mgr.RetrieveBatch(batchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Completed || batch.Counts[job.JobStatusSuccess] != 1 || batch.Counts[job.JobStatusError] != 1 {
+		t.Fatalf("expect uncompleted batch with 1 succeeded and 1 failed job but got %+v\n", batch)
+	}
+
+	mgr.BatchJobEnded(jobIDs[1])
+
+	select {
+	case batch := <-reported:
+		if !batch.Completed || batch.Counts[job.JobStatusError] != 1 || batch.Total != 2 {
+			t.Fatalf("expect completed batch with 1 failed job reported but got %+v\n", batch)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expect the completed batch reported via hook")
+	}
+
+	// The dead job is retried, the batch is reopened and reported again once the job ends
+	mgr.SetJobStatus(jobIDs[1], job.JobStatusPending)
+	<-time.After(100 * time.Millisecond)
+
+	if batch, err = mgr.RetrieveBatch(batchID); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Completed || batch.CompleteTime != 0 {
+		t.Fatalf("expect the batch reopened but got %+v\n", batch)
+	}
+
+	mgr.SetJobStatus(jobIDs[1], job.JobStatusRunning)
+	<-time.After(100 * time.Millisecond)
+	mgr.SetJobStatus(jobIDs[1], job.JobStatusSuccess)
+	mgr.BatchJobEnded(jobIDs[1])
+
+	select {
+	case batch := <-reported:
+		if !batch.Completed || batch.Counts[job.JobStatusSuccess] != 2 {
+			t.Fatalf("expect completed batch with 2 succeeded jobs reported but got %+v\n", batch)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expect the completed batch reported via hook again")
+	}
+}
@@ -48,6 +48,16 @@ func NewHookClient() *HookClient {
 // ReportStatus reports the status change info to the subscribed party.
 // The status includes 'checkin' info with format 'check_in:<message>'
 func (hc *HookClient) ReportStatus(hookURL string, status models.JobStatusChange) error {
+	return hc.post(hookURL, &status)
+}
+
+// ReportBatch reports the completed batch of jobs to the subscribed party.
+func (hc *HookClient) ReportBatch(hookURL string, batch models.JobBatch) error {
+	return hc.post(hookURL, &batch)
+}
+
+// post the json data to the hook url and expect '200'
+func (hc *HookClient) post(hookURL string, v interface{}) error {
 	if utils.IsEmptyStr(hookURL) {
 		return errors.New("empty hook url") // do nothing
 	}
@@ -59,7 +69,7 @@ func (hc *HookClient) ReportStatus(hookURL string, status models.JobStatusChange
 	}
 
 	// Marshal data
-	data, err := json.Marshal(&status)
+	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
@@ -88,7 +98,7 @@ func (hc *HookClient) ReportStatus(hookURL string, status models.JobStatusChange
 			return errors.New(string(dt))
 		}
 
-		return fmt.Errorf("failed to report status change via hook, expect '200' but got '%d'", res.StatusCode)
+		return fmt.Errorf("failed to report via hook, expect '200' but got '%d'", res.StatusCode)
 	}
 
 	return nil
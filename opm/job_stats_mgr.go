@@ -14,6 +14,12 @@ type JobStatsManager interface {
 	// Shutdown the manager
 	Shutdown()
 
+	// Metrics of the async processing pipeline
+	//
+	// Returns:
+	//  models.StatsManagerMetrics : the queue depth, drops and process counts
+	Metrics() models.StatsManagerMetrics
+
 	// Save the job stats
 	// Async method to retry and improve performance
 	//
@@ -57,6 +63,142 @@ type JobStatsManager interface {
 	//
 	CheckIn(jobID string, message string)
 
+	// StatusTimeline returns the accepted status transitions of the specified job in order.
+	//
+	// jobID string : ID of the job
+	//
+	// Returns:
+	//  models.JobStatusTimeline : the status timeline
+	//  error                    : error if meet any problems
+	StatusTimeline(jobID string) (models.JobStatusTimeline, error)
+
+	// CheckIns returns the check in history of the specified job.
+	// The history is capped and the latest check in comes first.
+	//
+	// jobID string : ID of the job
+	//
+	// Returns:
+	//  models.JobCheckIns : the check in history
+	//  error              : error if meet any problems
+	CheckIns(jobID string) (models.JobCheckIns, error)
+
+	// ReportProgress updates the structured progress of the specified job.
+	// The progress is persisted and reported via hook asynchronously with throttling,
+	// the phase changing and completion are always persisted and reported.
+	//
+	// jobID string   : ID of the job
+	// current uint64 : the finished units
+	// total uint64   : the total units, 0 if unknown
+	// phase string   : name of the current phase
+	// message string : optional message
+	//
+	// Returns:
+	//  error if the progress is invalid
+	ReportProgress(jobID string, current, total uint64, phase, message string) error
+
+	// SetResult keeps the structured result object of the specified job.
+	// Sync method as the result should be ready before the job status turns to be final.
+	//
+	// jobID string       : ID of the job
+	// result interface{} : the result object which can be serialized to json
+	//
+	// Returns:
+	//  error if the result is too large or meet any other problems
+	SetResult(jobID string, result interface{}) error
+
+	// GetResult returns the result object set by the specified job.
+	//
+	// jobID string : ID of the job
+	//
+	// Returns:
+	//  models.JobResult : the result of job
+	//  error            : error if meet any problems
+	GetResult(jobID string) (models.JobResult, error)
+
+	// SaveCheckpoint persists the checkpoint of the specified job with the key.
+	// Sync method as the job may rely on it to resume.
+	//
+	// jobID string      : ID of the job
+	// key string        : key of the checkpoint
+	// value interface{} : the checkpoint value which can be serialized to json
+	//
+	// Returns:
+	//  error if meet any problems
+	SaveCheckpoint(jobID string, key string, value interface{}) error
+
+	// GetCheckpoint returns the raw json data of the checkpoint of the specified job with the key.
+	//
+	// jobID string : ID of the job
+	// key string   : key of the checkpoint
+	//
+	// Returns:
+	//  []byte : raw json data of the checkpoint
+	//  error  : error if meet any problems, errs.NoObjectFoundError if not existing
+	GetCheckpoint(jobID string, key string) ([]byte, error)
+
+	// ClearCheckpoints removes all the checkpoints of the specified job.
+	//
+	// jobID string : ID of the job
+	//
+	// Returns:
+	//  error if meet any problems
+	ClearCheckpoints(jobID string) error
+
+	// SetLastError records the error message of the last failed execution of the job.
+	// Sync method as the error should be kept before the job status turns to be final.
+	//
+	// jobID string   : ID of the job
+	// message string : the error message
+	//
+	// Returns:
+	//  error if meet any problems
+	SetLastError(jobID string, message string) error
+
+	// SetRunAt changes the time when the job is scheduled to run.
+	// Sync method as the job is rescheduled in the backend already.
+	//
+	// jobID string : ID of the job
+	// runAt int64  : the new time (unix seconds) to run the job
+	//
+	// Returns:
+	//  error if meet any problems
+	SetRunAt(jobID string, runAt int64) error
+
+	// SetNextRetryAt records the time when the failed job is going to be retried.
+	// Sync method as the job is put into the retry queue right after.
+	//
+	// jobID string       : ID of the job
+	// nextRetryAt int64  : the time (unix seconds) to retry the job, 0 to clear it
+	//
+	// Returns:
+	//  error if meet any problems
+	SetNextRetryAt(jobID string, nextRetryAt int64) error
+
+	// RetrieveBatch gets the aggregate status of the jobs submitted in the batch.
+	//
+	// batchID string : ID of the batch
+	//
+	// Returns:
+	//  models.JobBatch : the job counts by status and the completion of the batch
+	//  error           : error if meet any problems
+	RetrieveBatch(batchID string) (models.JobBatch, error)
+
+	// BatchJobEnded marks the job as ended in the batch it's submitted in,
+	// the batch is completed and reported via hook once all its jobs are ended.
+	// Async method as the job is not going to run again already.
+	//
+	// jobID string : ID of the job
+	BatchJobEnded(jobID string)
+
+	// List the archived stats of the ended jobs.
+	//
+	// query models.JobQuery : the query conditions
+	//
+	// Returns:
+	//  models.JobList : the matched jobs
+	//  error          : error if the archive is not enabled or meet any other problems
+	List(query models.JobQuery) (models.JobList, error)
+
 	// DieAt marks the failed jobs with the time they put into dead queue.
 	//
 	// jobID string   : ID of the job
@@ -0,0 +1,145 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package opm
+
+import (
+	"sync"
+
+	"github.com/Colstuwjx/job/config"
+)
+
+// processQueue is a bounded FIFO queue of the stats process items.
+// The queued items with the same coalescing key are merged into one, the latest data wins.
+// When the queue is full, the overflow policy decides to block the caller, drop the new item or
+// drop the oldest item.
+type processQueue struct {
+	lock     *sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	items    []*queueItem
+	capacity int
+	policy   string
+	closed   bool
+	// queued items which can be coalesced, indexed by the coalescing key
+	coalescing map[string]*queueItem
+
+	coalesced uint64
+	dropped   uint64
+}
+
+// newProcessQueue is constructor of processQueue
+func newProcessQueue(capacity int, policy string) *processQueue {
+	if capacity <= 0 {
+		capacity = processBufferSize
+	}
+
+	lock := new(sync.Mutex)
+	return &processQueue{
+		lock:       lock,
+		notEmpty:   sync.NewCond(lock),
+		notFull:    sync.NewCond(lock),
+		items:      make([]*queueItem, 0, capacity),
+		capacity:   capacity,
+		policy:     policy,
+		coalescing: make(map[string]*queueItem),
+	}
+}
+
+// push the item into the queue.
+// Returns false if the item is dropped or the queue is closed.
+func (q *processQueue) push(item *queueItem) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return false
+	}
+
+	if len(item.key) > 0 {
+		if queued, ok := q.coalescing[item.key]; ok {
+			queued.data = item.data
+			q.coalesced++
+			return true
+		}
+	}
+
+	for len(q.items) >= q.capacity {
+		switch q.policy {
+		case config.StatsOverflowPolicyDropNew:
+			q.dropped++
+			return false
+		case config.StatsOverflowPolicyDropOldest:
+			q.remove(q.items[0])
+			q.items = q.items[1:]
+			q.dropped++
+		default:
+			q.notFull.Wait()
+			if q.closed {
+				return false
+			}
+		}
+	}
+
+	q.items = append(q.items, item)
+	if len(item.key) > 0 {
+		q.coalescing[item.key] = item
+	}
+	q.notEmpty.Signal()
+
+	return true
+}
+
+// popBatch blocks until any items are available and then pops at most max items.
+// Returns nil if the queue is closed and drained.
+func (q *processQueue) popBatch(max int) []*queueItem {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	// Drain the queued items even if the queue is closed
+	for len(q.items) == 0 {
+		if q.closed {
+			return nil
+		}
+		q.notEmpty.Wait()
+	}
+
+	n := len(q.items)
+	if max > 0 && n > max {
+		n = max
+	}
+
+	batch := make([]*queueItem, n)
+	copy(batch, q.items[:n])
+	q.items = q.items[n:]
+	for _, item := range batch {
+		q.remove(item)
+	}
+	q.notFull.Broadcast()
+
+	return batch
+}
+
+// remove the item from the coalescing index
+func (q *processQueue) remove(item *queueItem) {
+	if len(item.key) > 0 && q.coalescing[item.key] == item {
+		delete(q.coalescing, item.key)
+	}
+}
+
+// close the queue and wake up all the waiting callers
+func (q *processQueue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// stats returns the depth of the queue and the count of coalesced and dropped items
+func (q *processQueue) stats() (int, uint64, uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.items), q.coalesced, q.dropped
+}
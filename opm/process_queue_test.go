@@ -0,0 +1,96 @@
+// Copyright Project Harbor Authors. All rights reserved.
+package opm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Colstuwjx/job/config"
+)
+
+func TestProcessQueueCoalescing(t *testing.T) {
+	q := newProcessQueue(4, config.StatsOverflowPolicyDropNew)
+
+	q.push(&queueItem{op: opUpdateProgress, data: 1, key: "p:id_1"})
+	q.push(&queueItem{op: opCheckIn, data: "a"})
+	q.push(&queueItem{op: opUpdateProgress, data: 2, key: "p:id_1"})
+
+	batch := q.popBatch(10)
+	if len(batch) != 2 {
+		t.Fatalf("expect 2 items after coalescing but got %d", len(batch))
+	}
+	if batch[0].data.(int) != 2 {
+		t.Errorf("expect the latest data kept but got %v", batch[0].data)
+	}
+
+	// Popped item should not be coalesced any more
+	q.push(&queueItem{op: opUpdateProgress, data: 3, key: "p:id_1"})
+	if depth, coalesced, _ := q.stats(); depth != 1 || coalesced != 1 {
+		t.Errorf("expect depth 1 and coalesced 1 but got %d and %d", depth, coalesced)
+	}
+}
+
+func TestProcessQueueOverflow(t *testing.T) {
+	q := newProcessQueue(2, config.StatsOverflowPolicyDropNew)
+	q.push(&queueItem{data: 1})
+	q.push(&queueItem{data: 2})
+	if q.push(&queueItem{data: 3}) {
+		t.Error("expect the new item dropped")
+	}
+	if batch := q.popBatch(1); batch[0].data.(int) != 1 {
+		t.Errorf("expect the first item kept but got %v", batch[0].data)
+	}
+
+	q = newProcessQueue(2, config.StatsOverflowPolicyDropOldest)
+	q.push(&queueItem{data: 1, key: "k"})
+	q.push(&queueItem{data: 2})
+	if !q.push(&queueItem{data: 3}) {
+		t.Error("expect the new item queued")
+	}
+	batch := q.popBatch(10)
+	if len(batch) != 2 || batch[0].data.(int) != 2 || batch[1].data.(int) != 3 {
+		t.Errorf("expect the oldest item dropped but got %d items", len(batch))
+	}
+	if _, _, dropped := q.stats(); dropped != 1 {
+		t.Errorf("expect 1 dropped item but got %d", dropped)
+	}
+
+	q = newProcessQueue(1, config.StatsOverflowPolicyBlock)
+	q.push(&queueItem{data: 1})
+	pushed := make(chan bool, 1)
+	go func() {
+		pushed <- q.push(&queueItem{data: 2})
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("expect the push blocked when the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	q.popBatch(1)
+	select {
+	case ok := <-pushed:
+		if !ok {
+			t.Error("expect the blocked item queued")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect the blocked push released")
+	}
+}
+
+func TestProcessQueueClose(t *testing.T) {
+	q := newProcessQueue(2, config.StatsOverflowPolicyBlock)
+	q.push(&queueItem{data: 1})
+	q.close()
+
+	if q.push(&queueItem{data: 2}) {
+		t.Error("expect push rejected after closed")
+	}
+	if batch := q.popBatch(10); len(batch) != 1 {
+		t.Errorf("expect queued items drained after closed but got %d", len(batch))
+	}
+	if batch := q.popBatch(10); batch != nil {
+		t.Error("expect nil batch when closed and drained")
+	}
+}
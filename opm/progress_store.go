@@ -0,0 +1,116 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package opm
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Colstuwjx/job/models"
+)
+
+const (
+	// Persist the progress at most once per interval unless the phase is changed or the job is completed
+	progressSaveInterval = 1 * time.Second
+	// Report the progress via hook at most once per interval unless the phase is changed or the job is completed
+	progressReportInterval = 5 * time.Second
+)
+
+// progressState keeps the in-memory tracking data of the job progress.
+type progressState struct {
+	progress models.JobProgress
+	// baseline of the rate calculation, reset when phase is changed or progress goes back
+	baseAt   time.Time
+	base     uint64
+	savedAt  time.Time
+	reportAt time.Time
+}
+
+// ProgressStore is used to track the progress of the running jobs in memory.
+// It calculates the ETA and throttles the persisting and reporting of the progress.
+// Use job ID as key to index
+type ProgressStore struct {
+	lock *sync.Mutex
+	data map[string]*progressState
+}
+
+// NewProgressStore is to create a ptr of new ProgressStore.
+func NewProgressStore() *ProgressStore {
+	return &ProgressStore{
+		lock: new(sync.Mutex),
+		data: make(map[string]*progressState),
+	}
+}
+
+// Update the progress of the specified job.
+// Returns the calculated progress and the flags to indicate if the progress should be saved or reported.
+func (ps *ProgressStore) Update(jobID string, current, total uint64, phase, message string, now time.Time) (models.JobProgress, bool, bool) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	st, ok := ps.data[jobID]
+	phaseChanged := !ok || st.progress.Phase != phase
+	if phaseChanged || current < st.base {
+		st = &progressState{
+			baseAt: now,
+			base:   current,
+		}
+		ps.data[jobID] = st
+	}
+
+	p := models.JobProgress{
+		Current:    current,
+		Total:      total,
+		Phase:      phase,
+		Message:    message,
+		UpdateTime: now.Unix(),
+	}
+
+	if total > 0 {
+		p.Percent = math.Floor(float64(current)/float64(total)*10000) / 100
+	}
+
+	// Estimate the completion time with the rate since the baseline
+	elapsed := now.Sub(st.baseAt)
+	if total > 0 && current < total && current > st.base && elapsed > 0 {
+		rate := float64(current-st.base) / elapsed.Seconds()
+		remaining := time.Duration(float64(total-current) / rate * float64(time.Second))
+		p.ETA = now.Add(remaining).Unix()
+	}
+
+	st.progress = p
+
+	completed := total > 0 && current >= total
+	shouldSave := phaseChanged || completed || now.Sub(st.savedAt) >= progressSaveInterval
+	shouldReport := phaseChanged || completed || now.Sub(st.reportAt) >= progressReportInterval
+	if shouldSave {
+		st.savedAt = now
+	}
+	if shouldReport {
+		st.reportAt = now
+	}
+
+	return p, shouldSave, shouldReport
+}
+
+// Get the latest progress of the specified job
+func (ps *ProgressStore) Get(jobID string) (models.JobProgress, bool) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	st, ok := ps.data[jobID]
+	if !ok {
+		return models.JobProgress{}, false
+	}
+
+	return st.progress, true
+}
+
+// Remove the tracking data of the specified job
+func (ps *ProgressStore) Remove(jobID string) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	delete(ps.data, jobID)
+}
@@ -0,0 +1,53 @@
+// Copyright Project Harbor Authors. All rights reserved.
+package opm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressStore(t *testing.T) {
+	store := NewProgressStore()
+	now := time.Now()
+
+	p, save, report := store.Update("id_1", 0, 100, "copy", "", now)
+	if !save || !report {
+		t.Fatal("expect the first progress saved and reported")
+	}
+	if p.Percent != 0 || p.ETA != 0 {
+		t.Errorf("expect zero percent and no ETA but got %f and %d", p.Percent, p.ETA)
+	}
+
+	p, save, report = store.Update("id_1", 25, 100, "copy", "", now.Add(500*time.Millisecond))
+	if save || report {
+		t.Error("expect the chatty progress throttled")
+	}
+	if p.Percent != 25 {
+		t.Errorf("expect percent 25 but got %f", p.Percent)
+	}
+
+	p, save, report = store.Update("id_1", 50, 100, "copy", "", now.Add(10*time.Second))
+	if !save || !report {
+		t.Error("expect the progress saved and reported after the interval")
+	}
+	if eta := now.Add(20 * time.Second).Unix(); p.ETA != eta {
+		t.Errorf("expect ETA %d but got %d", eta, p.ETA)
+	}
+
+	if _, save, report = store.Update("id_1", 0, 10, "verify", "", now.Add(11*time.Second)); !save || !report {
+		t.Error("expect the phase changing saved and reported")
+	}
+
+	if _, save, report = store.Update("id_1", 10, 10, "verify", "done", now.Add(11*time.Second)); !save || !report {
+		t.Error("expect the completion saved and reported")
+	}
+
+	if latest, ok := store.Get("id_1"); !ok || latest.Message != "done" {
+		t.Errorf("expect the latest progress tracked but got %#v", latest)
+	}
+
+	store.Remove("id_1")
+	if _, ok := store.Get("id_1"); ok {
+		t.Error("expect the progress removed")
+	}
+}
@@ -10,11 +10,14 @@ import (
 	"math"
 	"math/rand"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 
+	"github.com/Colstuwjx/job/archive"
+	"github.com/Colstuwjx/job/config"
 	"github.com/Colstuwjx/job/errs"
 	"github.com/Colstuwjx/job/impl/job"
 	"github.com/Colstuwjx/job/logger"
@@ -23,13 +26,20 @@ import (
 )
 
 const (
-	processBufferSize = 1024
-	opSaveStats       = "save_job_stats"
-	opUpdateStatus    = "update_job_status"
-	opCheckIn         = "check_in"
-	opDieAt           = "mark_die_at"
-	opReportStatus    = "report_status"
-	maxFails          = 3
+	processBufferSize  = 1024
+	retryCheckInterval = 500 * time.Millisecond
+	opSaveStats        = "save_job_stats"
+	opUpdateStatus     = "update_job_status"
+	opCheckIn          = "check_in"
+	opUpdateProgress   = "update_progress"
+	opDieAt            = "mark_die_at"
+	opReportStatus     = "report_status"
+	opReportBatch      = "report_batch"
+	opBatchJobEnded    = "batch_job_ended"
+	maxFails           = 3
+	maxResultSize      = 64 * 1024 // 64KB
+	maxCheckpointSize  = 64 * 1024 // 64KB
+	maxCheckInHistory  = 100
 
 	// CtlCommandStop : command stop
 	CtlCommandStop = "stop"
@@ -40,25 +50,114 @@ const (
 
 	// EventRegisterStatusHook is event name of registering hook
 	EventRegisterStatusHook = "register_hook"
+
+	hookDeliverySucceeded = "succeeded"
+	hookDeliveryFailed    = "failed"
 )
 
+// copyTTLScript copies the TTL of the key to another key.
+//
+// KEYS[1]: the source key
+// KEYS[2]: the target key
+var copyTTLScript = redis.NewScript(2, `
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return ttl
+`)
+
+// setWithTTLScript sets the value of the key attached to the job stats,
+// the key expires together with the job stats.
+//
+// KEYS[1]: key of the job stats
+// KEYS[2]: the attached key
+// ARGV[1]: the value
+// ARGV[2]: the field of the attached hash, empty for the string
+// ARGV[3]: the TTL (milliseconds) used if the job stats are not saved yet
+var setWithTTLScript = redis.NewScript(2, `
+local ttl = redis.call('PTTL', KEYS[1])
+if ARGV[2] == '' then
+  redis.call('SET', KEYS[2], ARGV[1])
+else
+  redis.call('HSET', KEYS[2], ARGV[2], ARGV[1])
+end
+
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[2], ttl)
+elseif ttl == -1 then
+  redis.call('PERSIST', KEYS[2])
+else
+  redis.call('PEXPIRE', KEYS[2], ARGV[3])
+end
+return ttl
+`)
+
+// updateStatsScript sets the fields of the job stats only if the job stats exist,
+// so the expired job stats are not recreated without TTL.
+//
+// KEYS[1]: key of the job stats
+// ARGV: the field and value pairs
+//
+// Returns: 1 if the job stats are updated, otherwise 0
+var updateStatsScript = redis.NewScript(1, `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return 0
+end
+
+redis.call('HMSET', KEYS[1], unpack(ARGV))
+return 1
+`)
+
+// statusSeqScript increases the sequence number of the status update request
+// only if the job stats exist.
+//
+// KEYS[1]: key of the job stats
+//
+// Returns: the sequence number, -1 if the job stats do not exist
+var statusSeqScript = redis.NewScript(1, `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return -1
+end
+
+return redis.call('HINCRBY', KEYS[1], 'status_request_seq', 1)
+`)
+
 type queueItem struct {
 	op    string
 	fails uint
 	data  interface{}
+	// the queued items with the same key can be coalesced, empty for no coalescing
+	key string
+}
+
+type retryItem struct {
+	item  *queueItem
+	dueAt time.Time
 }
 
 // RedisJobStatsManager implements JobStatsManager based on redis.
+// The async requests are queued and processed by a fixed number of workers,
+// the stats writes are batched and sent to redis through pipelining.
 type RedisJobStatsManager struct {
-	namespace   string
-	redisPool   *redis.Pool
-	context     context.Context
-	stopChan    chan struct{}
-	doneChan    chan struct{}
-	processChan chan *queueItem
-	isRunning   *atomic.Value
-	hookStore   *HookStore  // cache the hook here to avoid requesting backend
-	opCommands  *oPCommands // maintain the OP commands
+	namespace  string
+	redisPool  *redis.Pool
+	context    context.Context
+	stopChan   chan struct{}
+	doneChan   chan struct{}
+	queue      *processQueue
+	workers    int
+	batchSize  int
+	workerWG   *sync.WaitGroup
+	retryLock  *sync.Mutex
+	retrying   []*retryItem
+	processed  uint64
+	failed     uint64
+	isRunning  *atomic.Value
+	hookStore  *HookStore     // cache the hook here to avoid requesting backend
+	progress   *ProgressStore // track the progress of the running jobs
+	archive    archive.Store  // archive the stats of the ended jobs, nil if not enabled
+	opCommands *oPCommands    // maintain the OP commands
 }
 
 // NewRedisJobStatsManager is constructor of RedisJobStatsManager
@@ -67,15 +166,21 @@ func NewRedisJobStatsManager(ctx context.Context, namespace string, redisPool *r
 	isRunning.Store(false)
 
 	return &RedisJobStatsManager{
-		namespace:   namespace,
-		context:     ctx,
-		redisPool:   redisPool,
-		stopChan:    make(chan struct{}, 1),
-		doneChan:    make(chan struct{}, 1),
-		processChan: make(chan *queueItem, processBufferSize),
-		hookStore:   NewHookStore(),
-		isRunning:   isRunning,
-		opCommands:  newOPCommands(ctx, namespace, redisPool),
+		namespace:  namespace,
+		context:    ctx,
+		redisPool:  redisPool,
+		stopChan:   make(chan struct{}, 1),
+		doneChan:   make(chan struct{}, 1),
+		queue:      newProcessQueue(int(config.GetStatsManagerQueueSize()), config.GetStatsManagerOverflowPolicy()),
+		workers:    int(config.GetStatsManagerWorkers()),
+		batchSize:  int(config.GetStatsManagerBatchSize()),
+		workerWG:   new(sync.WaitGroup),
+		retryLock:  new(sync.Mutex),
+		retrying:   make([]*retryItem, 0),
+		hookStore:  NewHookStore(),
+		progress:   NewProgressStore(),
+		isRunning:  isRunning,
+		opCommands: newOPCommands(ctx, namespace, redisPool),
 	}
 }
 
@@ -85,6 +190,21 @@ func (rjs *RedisJobStatsManager) Start() {
 		return
 	}
 
+	if cfg := config.GetArchiveConfig(); cfg != nil {
+		store, err := archive.NewStore(cfg)
+		if err != nil {
+			// Just logged, the job stats can still be served from redis
+			logger.Errorf("Failed to open the job archive: %s\n", err)
+		} else {
+			rjs.archive = store
+		}
+	}
+
+	for i := 0; i < rjs.workers; i++ {
+		rjs.workerWG.Add(1)
+		go rjs.work()
+	}
+
 	go rjs.loop()
 
 	rjs.opCommands.Start()
@@ -108,6 +228,27 @@ func (rjs *RedisJobStatsManager) Shutdown() {
 	<-rjs.doneChan
 }
 
+// Metrics is implementation of same method in JobStatsManager interface.
+func (rjs *RedisJobStatsManager) Metrics() models.StatsManagerMetrics {
+	depth, coalesced, dropped := rjs.queue.stats()
+
+	rjs.retryLock.Lock()
+	retrying := len(rjs.retrying)
+	rjs.retryLock.Unlock()
+
+	return models.StatsManagerMetrics{
+		Workers:        uint(rjs.workers),
+		QueueCapacity:  uint(rjs.queue.capacity),
+		QueueDepth:     uint(depth),
+		Retrying:       uint(retrying),
+		OverflowPolicy: rjs.queue.policy,
+		Processed:      atomic.LoadUint64(&rjs.processed),
+		Failed:         atomic.LoadUint64(&rjs.failed),
+		Coalesced:      coalesced,
+		Dropped:        dropped,
+	}
+}
+
 // Save is implementation of same method in JobStatsManager interface.
 // Async method
 func (rjs *RedisJobStatsManager) Save(jobStats models.JobStats) {
@@ -116,7 +257,7 @@ func (rjs *RedisJobStatsManager) Save(jobStats models.JobStats) {
 		data: jobStats,
 	}
 
-	rjs.processChan <- item
+	rjs.submit(item)
 }
 
 // Retrieve is implementation of same method in JobStatsManager interface.
@@ -130,81 +271,74 @@ func (rjs *RedisJobStatsManager) Retrieve(jobID string) (models.JobStats, error)
 }
 
 // SetJobStatus is implementation of same method in JobStatsManager interface.
-// Async method
+// Async method, the status is reported once the status transition is accepted.
+// The sequence number of the request is taken in sync to keep the order of the updates.
 func (rjs *RedisJobStatsManager) SetJobStatus(jobID string, status string) {
 	if utils.IsEmptyStr(jobID) || utils.IsEmptyStr(status) {
 		return
 	}
 
+	// Keep the sequence number of the request to reject the outdated updates
+	seq, err := rjs.nextStatusSeq(jobID)
+	if err != nil {
+		logger.Errorf("Failed to get sequence number of the status update of job %s: %s\n", jobID, err)
+		seq = -1 // let the update skip the order check
+	}
+
 	item := &queueItem{
 		op:   opUpdateStatus,
-		data: []string{jobID, status},
+		data: []interface{}{jobID, status, seq},
 	}
 
-	rjs.processChan <- item
-
-	// Report status at the same time
-	rjs.submitStatusReportingItem(jobID, status, "")
+	rjs.submit(item)
+
+	// Flush the latest progress which may be throttled before
+	if isFinalStatus(status) {
+		if p, ok := rjs.progress.Get(jobID); ok {
+			rjs.submit(&queueItem{
+				op:   opUpdateProgress,
+				data: []interface{}{jobID, p},
+				key:  fmt.Sprintf("%s:%s", opUpdateProgress, jobID),
+			})
+			rjs.progress.Remove(jobID)
+		}
+	}
 }
 
+// loop schedules the retries and stops the workers when exiting
 func (rjs *RedisJobStatsManager) loop() {
-	controlChan := make(chan struct{})
+	ticker := time.NewTicker(retryCheckInterval)
+	stopped := false
 
 	defer func() {
+		ticker.Stop()
+
+		// Stop the workers after the queued items are drained
+		rjs.queue.close()
+		rjs.workerWG.Wait()
+		if rjs.archive != nil {
+			if err := rjs.archive.Close(); err != nil {
+				logger.Errorf("Failed to close the job archive: %s\n", err)
+			}
+		}
 		rjs.isRunning.Store(false)
 
-		// Notify other sub goroutines
-		close(controlChan)
-
 		logger.Info("Redis job stats manager is stopped")
+
+		// Let the shutdown return after all the queued items are processed
+		if stopped {
+			rjs.doneChan <- struct{}{}
+		}
 	}()
 
 	for {
 		select {
-		case item := <-rjs.processChan:
-			go func(item *queueItem) {
-				clearHookCache := false
-				if err := rjs.process(item); err != nil {
-					item.fails++
-					if item.fails < maxFails {
-						logger.Warningf("Failed to process '%s' request with error: %s\n", item.op, err)
-
-						// Retry after a random interval
-						go func() {
-							timer := time.NewTimer(time.Duration(backoff(item.fails)) * time.Second)
-							defer timer.Stop()
-
-							select {
-							case <-timer.C:
-								rjs.processChan <- item
-								return
-							case <-controlChan:
-							}
-						}()
-					} else {
-						logger.Errorf("Failed to process '%s' request with error: %s (%d times tried)\n", item.op, err, maxFails)
-						if item.op == opReportStatus {
-							clearHookCache = true
-						}
-					}
-				} else {
-					if item.op == opReportStatus {
-						clearHookCache = true
-					}
-				}
-
-				if clearHookCache {
-					// Clear cache to save memory if job status is success or stopped.
-					data := item.data.([]string)
-					status := data[2]
-					if status == job.JobStatusSuccess || status == job.JobStatusStopped {
-						rjs.hookStore.Remove(data[0])
-					}
-				}
-			}(item)
-			break
+		case <-ticker.C:
+			for _, item := range rjs.dueRetries(time.Now()) {
+				rjs.submit(item)
+			}
 		case <-rjs.stopChan:
-			rjs.doneChan <- struct{}{}
+			stopped = true
 			return
 		case <-rjs.context.Done():
 			return
@@ -212,6 +346,109 @@ func (rjs *RedisJobStatsManager) loop() {
 	}
 }
 
+// work processes the queued items in batch until the queue is closed
+func (rjs *RedisJobStatsManager) work() {
+	defer rjs.workerWG.Done()
+
+	for {
+		batch := rjs.queue.popBatch(rjs.batchSize)
+		if batch == nil {
+			return
+		}
+
+		writes := make([]*queueItem, 0, len(batch))
+		for _, item := range batch {
+			if isBatchWrite(item.op) {
+				writes = append(writes, item)
+				continue
+			}
+
+			rjs.done(item, rjs.process(item))
+		}
+
+		if len(writes) > 0 {
+			errs := rjs.writeBatch(writes)
+			for i, item := range writes {
+				rjs.done(item, errs[i])
+			}
+		}
+	}
+}
+
+// submit the item to the process queue
+func (rjs *RedisJobStatsManager) submit(item *queueItem) {
+	if !rjs.queue.push(item) {
+		logger.Warningf("'%s' request is dropped as the stats process queue is full or closed\n", item.op)
+	}
+}
+
+// done handles the processing result of the item
+func (rjs *RedisJobStatsManager) done(item *queueItem, err error) {
+	clearHookCache := false
+	if err != nil {
+		atomic.AddUint64(&rjs.failed, 1)
+
+		item.fails++
+		if item.fails < maxFails {
+			logger.Warningf("Failed to process '%s' request with error: %s\n", item.op, err)
+
+			// Retry after a random interval
+			rjs.retryLock.Lock()
+			rjs.retrying = append(rjs.retrying, &retryItem{
+				item:  item,
+				dueAt: time.Now().Add(time.Duration(backoff(item.fails)) * time.Second),
+			})
+			rjs.retryLock.Unlock()
+		} else {
+			logger.Errorf("Failed to process '%s' request with error: %s (%d times tried)\n", item.op, err, maxFails)
+			if item.op == opReportStatus {
+				clearHookCache = true
+			}
+		}
+	} else {
+		atomic.AddUint64(&rjs.processed, 1)
+
+		if item.op == opReportStatus {
+			clearHookCache = true
+		}
+	}
+
+	if clearHookCache {
+		data := item.data.([]string)
+		status := data[2]
+		if isFinalStatus(status) {
+			rjs.recordHookDelivery(data[0], err)
+		}
+
+		// Clear cache to save memory if job status is success, stopped or expired.
+		if status == job.JobStatusSuccess || status == job.JobStatusStopped || status == job.JobStatusExpired {
+			rjs.hookStore.Remove(data[0])
+		}
+	}
+}
+
+// dueRetries picks the retrying items which are due
+func (rjs *RedisJobStatsManager) dueRetries(now time.Time) []*queueItem {
+	rjs.retryLock.Lock()
+	defer rjs.retryLock.Unlock()
+
+	due := make([]*queueItem, 0)
+	pending := rjs.retrying[:0]
+	for _, r := range rjs.retrying {
+		if r.dueAt.After(now) {
+			pending = append(pending, r)
+			continue
+		}
+
+		// The retrying item should not override the newer one
+		r.item.key = ""
+		due = append(due, r.item)
+	}
+	rjs.retrying = pending
+
+	return due
+}
+
 // SendCommand for the specified job
 func (rjs *RedisJobStatsManager) SendCommand(jobID string, command string) error {
 	if utils.IsEmptyStr(jobID) {
@@ -242,12 +479,92 @@ func (rjs *RedisJobStatsManager) CheckIn(jobID string, message string) {
 		data: []string{jobID, message},
 	}
 
-	rjs.processChan <- item
+	rjs.submit(item)
 
 	// Report checkin message at the same time
 	rjs.submitStatusReportingItem(jobID, job.JobStatusRunning, message)
 }
 
+// StatusTimeline returns the accepted status transitions of the specified job in order.
+func (rjs *RedisJobStatsManager) StatusTimeline(jobID string) (models.JobStatusTimeline, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobStatusTimeline{}, errors.New("empty job ID")
+	}
+
+	items, err := rjs.listOfJob(jobID, utils.KeyJobStatusTimeline(rjs.namespace, jobID))
+	if err != nil {
+		return models.JobStatusTimeline{}, err
+	}
+
+	timeline := models.JobStatusTimeline{
+		JobID:    jobID,
+		Timeline: make([]*models.JobStatusTransition, 0, len(items)),
+	}
+	for _, item := range items {
+		t := &models.JobStatusTransition{}
+		if err := json.Unmarshal(item, t); err != nil {
+			logger.Warningf("Malformed status transition of job %s: %s\n", jobID, err)
+			continue
+		}
+		timeline.Timeline = append(timeline.Timeline, t)
+	}
+
+	return timeline, nil
+}
+
+// CheckIns returns the check in history of the specified job.
+func (rjs *RedisJobStatsManager) CheckIns(jobID string) (models.JobCheckIns, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobCheckIns{}, errors.New("empty job ID")
+	}
+
+	items, err := rjs.listOfJob(jobID, utils.KeyJobCheckIns(rjs.namespace, jobID))
+	if err != nil {
+		return models.JobCheckIns{}, err
+	}
+
+	history := models.JobCheckIns{
+		JobID:    jobID,
+		CheckIns: make([]*models.JobCheckIn, 0, len(items)),
+	}
+	for _, item := range items {
+		c := &models.JobCheckIn{}
+		if err := json.Unmarshal(item, c); err != nil {
+			logger.Warningf("Malformed check in of job %s: %s\n", jobID, err)
+			continue
+		}
+		history.CheckIns = append(history.CheckIns, c)
+	}
+
+	return history, nil
+}
+
+// ReportProgress updates the structured progress of the specified job.
+func (rjs *RedisJobStatsManager) ReportProgress(jobID string, current, total uint64, phase, message string) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	if total > 0 && current > total {
+		return fmt.Errorf("current progress %d exceeds the total %d", current, total)
+	}
+
+	p, shouldSave, shouldReport := rjs.progress.Update(jobID, current, total, phase, message, time.Now())
+	if shouldSave {
+		rjs.submit(&queueItem{
+			op:   opUpdateProgress,
+			data: []interface{}{jobID, p},
+			key:  fmt.Sprintf("%s:%s", opUpdateProgress, jobID),
+		})
+	}
+
+	if shouldReport {
+		rjs.submitStatusReportingItem(jobID, job.JobStatusRunning, "")
+	}
+
+	return nil
+}
+
 // CtlCommand checks if control command is fired for the specified job.
 func (rjs *RedisJobStatsManager) CtlCommand(jobID string) (string, error) {
 	if utils.IsEmptyStr(jobID) {
@@ -262,6 +579,200 @@ func (rjs *RedisJobStatsManager) CtlCommand(jobID string) (string, error) {
 	return c, nil
 }
 
+// SetResult keeps the structured result object of the specified job.
+// The result expires together with the job stats.
+func (rjs *RedisJobStatsManager) SetResult(jobID string, result interface{}) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	rawJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	if len(rawJSON) > maxResultSize {
+		return fmt.Errorf("size of result (%d bytes) exceeds the limit %d bytes", len(rawJSON), maxResultSize)
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	// Keep the result as long as the job stats
+	_, err = setWithTTLScript.Do(
+		conn,
+		utils.KeyJobStats(rjs.namespace, jobID),
+		utils.KeyJobResult(rjs.namespace, jobID),
+		rawJSON,
+		"",
+		config.GetStatsTTL(job.JobKindGeneric)*1000,
+	)
+
+	return err
+}
+
+// GetResult returns the result object set by the specified job.
+func (rjs *RedisJobStatsManager) GetResult(jobID string) (models.JobResult, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobResult{}, errors.New("empty job ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	key := utils.KeyJobResult(rjs.namespace, jobID)
+	rawJSON, err := redis.Bytes(conn.Do("GET", key))
+	if err != nil {
+		if err == redis.ErrNil {
+			return models.JobResult{}, errs.NoObjectFoundError(fmt.Sprintf("result of job '%s'", jobID))
+		}
+
+		return models.JobResult{}, err
+	}
+
+	return models.JobResult{
+		JobID:  jobID,
+		Result: rawJSON,
+	}, nil
+}
+
+// SaveCheckpoint persists the checkpoint of the specified job with the key.
+// The checkpoints key follows the expire time of the job stats key.
+func (rjs *RedisJobStatsManager) SaveCheckpoint(jobID string, key string, value interface{}) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	if utils.IsEmptyStr(key) {
+		return errors.New("empty checkpoint key")
+	}
+
+	rawJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if len(rawJSON) > maxCheckpointSize {
+		return fmt.Errorf("size of checkpoint '%s' (%d bytes) exceeds the limit %d bytes", key, len(rawJSON), maxCheckpointSize)
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	// Keep the checkpoints as long as the job stats
+	_, err = setWithTTLScript.Do(
+		conn,
+		utils.KeyJobStats(rjs.namespace, jobID),
+		utils.KeyJobCheckpoints(rjs.namespace, jobID),
+		rawJSON,
+		key,
+		config.GetStatsTTL(job.JobKindGeneric)*1000,
+	)
+
+	return err
+}
+
+// GetCheckpoint returns the raw json data of the checkpoint of the specified job with the key.
+func (rjs *RedisJobStatsManager) GetCheckpoint(jobID string, key string) ([]byte, error) {
+	if utils.IsEmptyStr(jobID) {
+		return nil, errors.New("empty job ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("HGET", utils.KeyJobCheckpoints(rjs.namespace, jobID), key))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, errs.NoObjectFoundError(fmt.Sprintf("checkpoint '%s' of job '%s'", key, jobID))
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ClearCheckpoints removes all the checkpoints of the specified job.
+func (rjs *RedisJobStatsManager) ClearCheckpoints(jobID string) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", utils.KeyJobCheckpoints(rjs.namespace, jobID))
+
+	return err
+}
+
+// SetLastError is implementation of same method in JobStatsManager interface.
+func (rjs *RedisJobStatsManager) SetLastError(jobID string, message string) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	return rjs.updateStats(conn, jobID, "last_error", message, "update_time", time.Now().Unix())
+}
+
+// SetRunAt is implementation of same method in JobStatsManager interface.
+func (rjs *RedisJobStatsManager) SetRunAt(jobID string, runAt int64) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	return rjs.updateStats(conn, jobID, "run_at", runAt, "update_time", time.Now().Unix())
+}
+
+// SetNextRetryAt is implementation of same method in JobStatsManager interface.
+func (rjs *RedisJobStatsManager) SetNextRetryAt(jobID string, nextRetryAt int64) error {
+	if utils.IsEmptyStr(jobID) {
+		return errors.New("empty job ID")
+	}
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	key := utils.KeyJobStats(rjs.namespace, jobID)
+	if nextRetryAt == 0 {
+		_, err := conn.Do("HDEL", key, "next_retry_at")
+		return err
+	}
+
+	return rjs.updateStats(conn, jobID, "next_retry_at", nextRetryAt)
+}
+
+// updateStats sets the fields of the existing job stats.
+// Returns errs.NoObjectFoundError if the job stats do not exist.
+func (rjs *RedisJobStatsManager) updateStats(conn redis.Conn, jobID string, fieldAndValues ...interface{}) error {
+	args := append([]interface{}{utils.KeyJobStats(rjs.namespace, jobID)}, fieldAndValues...)
+	updated, err := redis.Int(updateStatsScript.Do(conn, args...))
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return errs.NoObjectFoundError(fmt.Sprintf("job '%s'", jobID))
+	}
+
+	return nil
+}
+
+// List is implementation of same method in JobStatsManager interface.
+func (rjs *RedisJobStatsManager) List(query models.JobQuery) (models.JobList, error) {
+	if rjs.archive == nil {
+		return models.JobList{}, errs.ArchiveDisabledError()
+	}
+
+	return rjs.archive.List(query)
+}
+
 // DieAt marks the failed jobs with the time they put into dead queue.
 func (rjs *RedisJobStatsManager) DieAt(jobID string, dieAt int64) {
 	if utils.IsEmptyStr(jobID) || dieAt == 0 {
@@ -273,7 +784,7 @@ func (rjs *RedisJobStatsManager) DieAt(jobID string, dieAt int64) {
 		data: []interface{}{jobID, dieAt},
 	}
 
-	rjs.processChan <- item
+	rjs.submit(item)
 }
 
 // RegisterHook is used to save the hook url or cache the url in memory.
@@ -303,8 +814,12 @@ func (rjs *RedisJobStatsManager) ExpirePeriodicJobStats(jobID string) error {
 	// The periodic job (policy) is stopped/unscheduled and then
 	// the stats of periodic job now can be expired
 	key := utils.KeyJobStats(rjs.namespace, jobID)
-	expireTime := 24 * 60 * 60 // 1 day
-	_, err := conn.Do("EXPIRE", key, expireTime)
+	expireTime := config.GetStatsTTL(job.JobKindPeriodic)
+	conn.Send("MULTI")
+	conn.Send("EXPIRE", key, expireTime)
+	conn.Send("EXPIRE", utils.KeyJobCheckIns(rjs.namespace, jobID), expireTime)
+	conn.Send("EXPIRE", utils.KeyJobStatusTimeline(rjs.namespace, jobID), expireTime)
+	_, err := conn.Do("EXEC")
 
 	return err
 }
@@ -334,7 +849,7 @@ func (rjs *RedisJobStatsManager) submitStatusReportingItem(jobID string, status,
 			data: []string{jobID, hookURL, status, checkIn},
 		}
 
-		rjs.processChan <- item
+		rjs.submit(item)
 	}()
 }
 
@@ -357,41 +872,314 @@ func (rjs *RedisJobStatsManager) reportStatus(jobID string, hookURL, status, che
 		// Just double confirmation
 		jobStats.Stats.CheckIn = checkIn
 		jobStats.Stats.Status = status
+		if p, ok := rjs.progress.Get(jobID); ok {
+			jobStats.Stats.Progress = &p
+		}
 		reportingStatus.Metadata = jobStats.Stats
 	}
 
+	// Attach the result for the final status
+	if isFinalStatus(status) {
+		if res, err := rjs.GetResult(jobID); err == nil {
+			reportingStatus.Result = res.Result
+		}
+	}
+
 	return DefaultHookClient.ReportStatus(hookURL, reportingStatus)
 }
 
-func (rjs *RedisJobStatsManager) updateJobStatus(jobID string, status string) error {
+// nextStatusSeq issues the sequence number of the status update request of the job.
+// The numbers are increased in redis, so they're ordered among all the nodes.
+// Returns errs.NoObjectFoundError if the job stats do not exist.
+func (rjs *RedisJobStatsManager) nextStatusSeq(jobID string) (int64, error) {
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	seq, err := redis.Int64(statusSeqScript.Do(conn, utils.KeyJobStats(rjs.namespace, jobID)))
+	if err != nil {
+		return 0, err
+	}
+	if seq < 0 {
+		return 0, errs.NoObjectFoundError(fmt.Sprintf("job '%s'", jobID))
+	}
+
+	return seq, nil
+}
+
+// updateJobStatus updates the job status if the status transition is legal.
+// The illegal or outdated transitions are rejected and logged.
+func (rjs *RedisJobStatsManager) updateJobStatus(jobID string, status string, seq int64) (bool, error) {
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	return rjs.transitStatus(conn, jobID, status, seq)
+}
+
+func (rjs *RedisJobStatsManager) transitStatus(conn redis.Conn, jobID string, status string, seq int64) (bool, error) {
+	vals, err := redis.Values(statusTransitionScript.Do(
+		conn,
+		utils.KeyJobStats(rjs.namespace, jobID),
+		utils.KeyJobStatusTimeline(rjs.namespace, jobID),
+		status,
+		seq,
+		time.Now().Unix(),
+		maxStatusTimeline,
+	))
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		result  int
+		current string
+		detail  string
+	)
+	if _, err := redis.Scan(vals, &result, &current, &detail); err != nil {
+		return false, err
+	}
+
+	switch result {
+	case transitionAccepted:
+		logger.Debugf("Status of job %s is changed from '%s' to '%s' (revision %s)\n", jobID, current, status, detail)
+		return true, nil
+	case transitionUnchanged:
+		return false, nil
+	default:
+		logger.Warningf("Status transition of job %s from '%s' to '%s' is rejected: %s\n", jobID, current, status, detail)
+		return false, nil
+	}
+}
+
+// jobEnded applies the retention of the final status and archives the job stats.
+// The errors are just logged as the status has been updated.
+func (rjs *RedisJobStatsManager) jobEnded(jobID string, status string) {
+	jobStats, err := rjs.getJobStats(jobID)
+	if err != nil {
+		logger.Errorf("Failed to get stats of the ended job %s: %s\n", jobID, err)
+		return
+	}
+
+	// The stats of periodic job are kept until the policy is unscheduled
+	if ttl, ok := config.GetStatsTTLOfStatus(status); ok && jobStats.Stats.JobKind != job.JobKindPeriodic {
+		if err := rjs.expireJob(jobID, ttl); err != nil {
+			logger.Errorf("Failed to apply TTL of status %s to job %s: %s\n", status, jobID, err)
+		}
+	} else if err := rjs.alignJobTTL(jobID); err != nil {
+		logger.Errorf("Failed to align TTL of the data of job %s: %s\n", jobID, err)
+	}
+
+	rjs.archiveJob(jobStats)
+}
+
+// recordHookDelivery records the result of reporting the final status via hook
+// and refreshes the archived job stats.
+func (rjs *RedisJobStatsManager) recordHookDelivery(jobID string, err error) {
+	delivery, hookErr := hookDeliverySucceeded, ""
+	if err != nil {
+		delivery, hookErr = hookDeliveryFailed, err.Error()
+	}
+
 	conn := rjs.redisPool.Get()
 	defer conn.Close()
 
 	key := utils.KeyJobStats(rjs.namespace, jobID)
-	args := make([]interface{}, 0, 6)
-	args = append(args, key, "status", status, "update_time", time.Now().Unix())
-	if status == job.JobStatusSuccess {
-		// make sure the 'die_at' is reset in case it's a retrying job
-		args = append(args, "die_at", 0)
+	if _, e := conn.Do("HMSET", key, "hook_delivery", delivery, "hook_error", hookErr); e != nil {
+		logger.Errorf("Failed to record hook delivery of job %s: %s\n", jobID, e)
+		return
+	}
+
+	if rjs.archive == nil {
+		return
 	}
 
-	_, err := conn.Do("HMSET", args...)
+	jobStats, e := rjs.getJobStats(jobID)
+	if e != nil {
+		logger.Errorf("Failed to get stats of the ended job %s: %s\n", jobID, e)
+		return
+	}
+
+	rjs.archiveJob(jobStats)
+}
+
+func (rjs *RedisJobStatsManager) archiveJob(jobStats models.JobStats) {
+	if rjs.archive == nil {
+		return
+	}
+
+	if err := rjs.archive.Save(jobStats.Stats); err != nil {
+		logger.Errorf("Failed to archive stats of job %s: %s\n", jobStats.Stats.JobID, err)
+	}
+}
+
+// expireJob sets the TTL (seconds) of the job stats and the related data
+func (rjs *RedisJobStatsManager) expireJob(jobID string, ttl int64) error {
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("EXPIRE", utils.KeyJobStats(rjs.namespace, jobID), ttl)
+	conn.Send("EXPIRE", utils.KeyJobCheckIns(rjs.namespace, jobID), ttl)
+	conn.Send("EXPIRE", utils.KeyJobStatusTimeline(rjs.namespace, jobID), ttl)
+	conn.Send("EXPIRE", utils.KeyJobResult(rjs.namespace, jobID), ttl)
+	conn.Send("EXPIRE", utils.KeyJobCheckpoints(rjs.namespace, jobID), ttl)
+	_, err := conn.Do("EXEC")
+
 	return err
 }
 
-func (rjs *RedisJobStatsManager) checkIn(jobID string, message string) error {
+// alignJobTTL lets the result and the checkpoints of the job expire together with the job stats
+func (rjs *RedisJobStatsManager) alignJobTTL(jobID string) error {
 	conn := rjs.redisPool.Get()
 	defer conn.Close()
 
-	now := time.Now().Unix()
 	key := utils.KeyJobStats(rjs.namespace, jobID)
-	args := make([]interface{}, 0, 7)
-	args = append(args, key, "check_in", message, "check_in_at", now, "update_time", now)
-	_, err := conn.Do("HMSET", args...)
+	conn.Send("MULTI")
+	copyTTLScript.Send(conn, key, utils.KeyJobResult(rjs.namespace, jobID))
+	copyTTLScript.Send(conn, key, utils.KeyJobCheckpoints(rjs.namespace, jobID))
+	_, err := conn.Do("EXEC")
 
 	return err
 }
 
+// listOfJob returns all the items of the list attached to the existing job stats
+func (rjs *RedisJobStatsManager) listOfJob(jobID string, key string) ([][]byte, error) {
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	exists, err := redis.Bool(conn.Do("EXISTS", utils.KeyJobStats(rjs.namespace, jobID)))
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errs.NoObjectFoundError(fmt.Sprintf("job '%s'", jobID))
+	}
+
+	return redis.ByteSlices(conn.Do("LRANGE", key, 0, -1))
+}
+
+// writeBatch writes the check ins and progress updates of the batch through one pipelined connection.
+// The hash fields of the same job are merged into one HMSET command, the latest value wins.
+// Returns the errors of the items in the same order.
+func (rjs *RedisJobStatsManager) writeBatch(items []*queueItem) []error {
+	results := make([]error, len(items))
+
+	conn := rjs.redisPool.Get()
+	defer conn.Close()
+
+	now := time.Now().Unix()
+	jobs := make([]*batchedJob, 0)
+	index := make(map[string]*batchedJob)
+	for i, item := range items {
+		var jobID string
+		var fields []interface{}
+		var history []byte
+
+		switch item.op {
+		case opCheckIn:
+			data := item.data.([]string)
+			rawJSON, err := json.Marshal(&models.JobCheckIn{
+				Message:   data[1],
+				CheckInAt: now,
+			})
+			if err != nil {
+				results[i] = err
+				continue
+			}
+			jobID, history = data[0], rawJSON
+			fields = []interface{}{"check_in", data[1], "check_in_at", now}
+		case opUpdateProgress:
+			data := item.data.([]interface{})
+			p := data[1].(models.JobProgress)
+			jobID = data[0].(string)
+			fields = []interface{}{
+				"progress_current", p.Current,
+				"progress_total", p.Total,
+				"progress_percent", p.Percent,
+				"progress_phase", p.Phase,
+				"progress_message", p.Message,
+				"progress_eta", p.ETA,
+				"progress_update_time", p.UpdateTime,
+			}
+		default:
+			continue
+		}
+
+		bj, ok := index[jobID]
+		if !ok {
+			bj = &batchedJob{
+				jobID:  jobID,
+				fields: make(map[string]interface{}),
+			}
+			index[jobID] = bj
+			jobs = append(jobs, bj)
+		}
+		bj.items = append(bj.items, i)
+		for j := 0; j < len(fields); j += 2 {
+			name := fields[j].(string)
+			if _, ok := bj.fields[name]; !ok {
+				bj.names = append(bj.names, name)
+			}
+			bj.fields[name] = fields[j+1]
+		}
+		if history != nil {
+			bj.history = append(bj.history, history)
+		}
+	}
+
+	// owners of the pipelined commands
+	owners := make([][]int, 0)
+	for _, bj := range jobs {
+		key := utils.KeyJobStats(rjs.namespace, bj.jobID)
+		args := make([]interface{}, 0, 2*len(bj.names)+3)
+		args = append(args, key)
+		for _, name := range bj.names {
+			args = append(args, name, bj.fields[name])
+		}
+		args = append(args, "update_time", now)
+		conn.Send("HMSET", args...)
+		owners = append(owners, bj.items)
+
+		if len(bj.history) > 0 {
+			historyKey := utils.KeyJobCheckIns(rjs.namespace, bj.jobID)
+			for _, h := range bj.history {
+				conn.Send("LPUSH", historyKey, h)
+				owners = append(owners, bj.items)
+			}
+			conn.Send("LTRIM", historyKey, 0, maxCheckInHistory-1)
+			owners = append(owners, bj.items)
+			// The history expires together with the job stats
+			copyTTLScript.Send(conn, key, historyKey)
+			owners = append(owners, bj.items)
+		}
+	}
+
+	if len(owners) == 0 {
+		return results
+	}
+
+	if err := conn.Flush(); err != nil {
+		for _, bj := range jobs {
+			for _, i := range bj.items {
+				results[i] = err
+			}
+		}
+		return results
+	}
+
+	for _, itemIndexes := range owners {
+		if _, err := conn.Receive(); err != nil {
+			for _, i := range itemIndexes {
+				if results[i] == nil {
+					results[i] = err
+				}
+			}
+		}
+	}
+
+	return results
+}
+
 func (rjs *RedisJobStatsManager) dieAt(jobID string, baseTime int64) error {
 	conn := rjs.redisPool.Get()
 	defer conn.Close()
@@ -436,6 +1224,7 @@ func (rjs *RedisJobStatsManager) getJobStats(jobID string) (models.JobStats, err
 	res := models.JobStats{
 		Stats: &models.JobStatData{},
 	}
+	progress := &models.JobProgress{}
 	for i, l := 0, len(vals); i < l; i = i + 2 {
 		prop := vals[i]
 		value := vals[i+1]
@@ -471,6 +1260,12 @@ func (rjs *RedisJobStatsManager) getJobStats(jobID string) (models.JobStats, err
 		case "run_at":
 			v, _ := strconv.ParseInt(value, 10, 64)
 			res.Stats.RunAt = v
+		case "next_retry_at":
+			v, _ := strconv.ParseInt(value, 10, 64)
+			res.Stats.NextRetryAt = v
+		case "expire_at":
+			v, _ := strconv.ParseInt(value, 10, 64)
+			res.Stats.ExpireAt = v
 			break
 		case "check_in_at":
 			v, _ := strconv.ParseInt(value, 10, 64)
@@ -485,11 +1280,49 @@ func (rjs *RedisJobStatsManager) getJobStats(jobID string) (models.JobStats, err
 		case "die_at":
 			v, _ := strconv.ParseInt(value, 10, 64)
 			res.Stats.DieAt = v
+		case "revision":
+			v, _ := strconv.ParseInt(value, 10, 64)
+			res.Stats.Revision = v
+		case "last_error":
+			res.Stats.LastError = value
+		case "hook_delivery":
+			res.Stats.HookDelivery = value
+		case "hook_error":
+			res.Stats.HookError = value
+		case "batch_id":
+			res.Stats.BatchID = value
+		case "partition_key":
+			res.Stats.PartitionKey = value
+		case "parent_id":
+			res.Stats.ParentID = value
+		case "progress_current":
+			v, _ := strconv.ParseUint(value, 10, 64)
+			progress.Current = v
+		case "progress_total":
+			v, _ := strconv.ParseUint(value, 10, 64)
+			progress.Total = v
+		case "progress_percent":
+			v, _ := strconv.ParseFloat(value, 64)
+			progress.Percent = v
+		case "progress_phase":
+			progress.Phase = value
+		case "progress_message":
+			progress.Message = value
+		case "progress_eta":
+			v, _ := strconv.ParseInt(value, 10, 64)
+			progress.ETA = v
+		case "progress_update_time":
+			v, _ := strconv.ParseInt(value, 10, 64)
+			progress.UpdateTime = v
 		default:
 			break
 		}
 	}
 
+	if progress.UpdateTime > 0 {
+		res.Stats.Progress = progress
+	}
+
 	return res, nil
 }
 
@@ -509,7 +1342,6 @@ func (rjs *RedisJobStatsManager) saveJobStats(jobStats models.JobStats) error {
 		"name", jobStats.Stats.JobName,
 		"kind", jobStats.Stats.JobKind,
 		"unique", jobStats.Stats.IsUnique,
-		"status", jobStats.Stats.Status,
 		"ref_link", jobStats.Stats.RefLink,
 		"enqueue_time", jobStats.Stats.EnqueueTime,
 		"update_time", jobStats.Stats.UpdateTime,
@@ -528,12 +1360,28 @@ func (rjs *RedisJobStatsManager) saveJobStats(jobStats models.JobStats) error {
 		args = append(args, "die_at", jobStats.Stats.DieAt)
 	}
 
+	if jobStats.Stats.ExpireAt > 0 {
+		args = append(args, "expire_at", jobStats.Stats.ExpireAt)
+	}
+
+	if !utils.IsEmptyStr(jobStats.Stats.BatchID) {
+		args = append(args, "batch_id", jobStats.Stats.BatchID)
+	}
+
+	if !utils.IsEmptyStr(jobStats.Stats.PartitionKey) {
+		args = append(args, "partition_key", jobStats.Stats.PartitionKey)
+	}
+
+	if !utils.IsEmptyStr(jobStats.Stats.ParentID) {
+		args = append(args, "parent_id", jobStats.Stats.ParentID)
+	}
+
 	conn.Send("HMSET", args...)
 
 	// If job kind is periodic job, expire time should not be set
 	// If job kind is scheduled job, expire time should be runAt+1day
 	if jobStats.Stats.JobKind != job.JobKindPeriodic {
-		var expireTime int64 = 60 * 60 * 24
+		expireTime := config.GetStatsTTL(jobStats.Stats.JobKind)
 		if jobStats.Stats.JobKind == job.JobKindScheduled {
 			nowTime := time.Now().Unix()
 			future := jobStats.Stats.RunAt - nowTime
@@ -543,9 +1391,24 @@ func (rjs *RedisJobStatsManager) saveJobStats(jobStats models.JobStats) error {
 		}
 		expireTime += rand.Int63n(30) // Avoid lots of keys being expired at the same time
 		conn.Send("EXPIRE", key, expireTime)
+		conn.Send("EXPIRE", utils.KeyJobCheckIns(rjs.namespace, jobStats.Stats.JobID), expireTime)
+		conn.Send("EXPIRE", utils.KeyJobStatusTimeline(rjs.namespace, jobStats.Stats.JobID), expireTime)
+	}
+
+	if err := conn.Flush(); err != nil {
+		return err
 	}
 
-	return conn.Flush()
+	// The job may be already running before the stats are saved,
+	// so the initial status also goes through the status state machine.
+	if !utils.IsEmptyStr(jobStats.Stats.Status) {
+		// The initial status precedes all the requested updates
+		if _, err := rjs.transitStatus(conn, jobStats.Stats.JobID, jobStats.Stats.Status, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (rjs *RedisJobStatsManager) process(item *queueItem) error {
@@ -554,17 +1417,34 @@ func (rjs *RedisJobStatsManager) process(item *queueItem) error {
 		jobStats := item.data.(models.JobStats)
 		return rjs.saveJobStats(jobStats)
 	case opUpdateStatus:
-		data := item.data.([]string)
-		return rjs.updateJobStatus(data[0], data[1])
-	case opCheckIn:
-		data := item.data.([]string)
-		return rjs.checkIn(data[0], data[1])
+		data := item.data.([]interface{})
+		jobID, status := data[0].(string), data[1].(string)
+		accepted, err := rjs.updateJobStatus(jobID, status, data[2].(int64))
+		if err != nil {
+			return err
+		}
+		// Only report the accepted status
+		if accepted {
+			if isFinalStatus(status) {
+				rjs.jobEnded(jobID, status)
+			}
+			rjs.trackBatch(jobID, status)
+			rjs.submitStatusReportingItem(jobID, status, "")
+		}
+		return nil
+	case opCheckIn, opUpdateProgress:
+		return rjs.writeBatch([]*queueItem{item})[0]
 	case opDieAt:
 		data := item.data.([]interface{})
 		return rjs.dieAt(data[0].(string), data[1].(int64))
 	case opReportStatus:
 		data := item.data.([]string)
 		return rjs.reportStatus(data[0], data[1], data[2], data[3])
+	case opReportBatch:
+		data := item.data.([]string)
+		return rjs.reportBatch(data[0], data[1])
+	case opBatchJobEnded:
+		return rjs.batchJobEnded(item.data.(string))
 	default:
 		break
 	}
@@ -637,6 +1517,30 @@ func (rjs *RedisJobStatsManager) getHook(jobID string) (string, error) {
 	return "", fmt.Errorf("no hook found for job '%s'", jobID)
 }
 
+// batchedJob keeps the merged writes of the same job in one batch
+type batchedJob struct {
+	jobID   string
+	names   []string
+	fields  map[string]interface{}
+	history [][]byte
+	// indexes of the items in the batch
+	items []int
+}
+
+// isBatchWrite checks if the writes of the op can be batched
+func isBatchWrite(op string) bool {
+	return op == opCheckIn || op == opUpdateProgress
+}
+
+// isFinalStatus checks if the job stops running with the status
+func isFinalStatus(status string) bool {
+	return status == job.JobStatusSuccess ||
+		status == job.JobStatusError ||
+		status == job.JobStatusStopped ||
+		status == job.JobStatusCancelled ||
+		status == job.JobStatusExpired
+}
+
 func backoff(seed uint) int {
 	if seed < 1 {
 		seed = 1
@@ -10,11 +10,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/errs"
 	"github.com/Colstuwjx/job/impl/job"
 	"github.com/Colstuwjx/job/models"
 	"github.com/Colstuwjx/job/utils"
@@ -72,6 +75,151 @@ func TestSetJobStatus(t *testing.T) {
 	}
 }
 
+func TestStatusTransitions(t *testing.T) {
+	mgr := NewRedisJobStatsManager(context.Background(), testingNamespace, redisPool)
+	mgr.Start()
+	defer mgr.Shutdown()
+	<-time.After(200 * time.Millisecond)
+
+	keys := []string{
+		utils.KeyJobStats(testingNamespace, "fake_job_ID"),
+		utils.KeyJobStatusTimeline(testingNamespace, "fake_job_ID"),
+	}
+	for _, key := range keys {
+		if err := clear(key, redisPool.Get()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testingStats := createFakeStats()
+	testingStats.Stats.JobKind = job.JobKindGeneric
+	mgr.Save(testingStats)
+	<-time.After(200 * time.Millisecond)
+
+	mgr.SetJobStatus("fake_job_ID", job.JobStatusRunning)
+	<-time.After(100 * time.Millisecond)
+	mgr.SetJobStatus("fake_job_ID", job.JobStatusSuccess)
+	<-time.After(100 * time.Millisecond)
+
+	// Delayed updates
+	if accepted, err := mgr.updateJobStatus("fake_job_ID", "running", 3); err != nil || accepted {
+		t.Fatalf("expect regression from 'Success' to 'running' rejected but got %v: %v\n", accepted, err)
+	}
+	if accepted, err := mgr.updateJobStatus("fake_job_ID", job.JobStatusError, 1); err != nil || accepted {
+		t.Fatalf("expect outdated update rejected but got %v: %v\n", accepted, err)
+	}
+
+	stats, err := mgr.Retrieve("fake_job_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Stats.Status != job.JobStatusSuccess || stats.Stats.Revision != 3 {
+		t.Fatalf("expect status 'Success' with revision 3 but got '%s' with revision %d\n", stats.Stats.Status, stats.Stats.Revision)
+	}
+
+	timeline, err := mgr.StatusTimeline("fake_job_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{job.JobStatusPending, job.JobStatusRunning, job.JobStatusSuccess}
+	if len(timeline.Timeline) != len(expected) {
+		t.Fatalf("expect %d transitions but got %d\n", len(expected), len(timeline.Timeline))
+	}
+	for i, tr := range timeline.Timeline {
+		if tr.Status != expected[i] || tr.Revision != int64(i+1) {
+			t.Errorf("expect transition %d to '%s' but got '%s' (revision %d)\n", i, expected[i], tr.Status, tr.Revision)
+		}
+	}
+
+	for _, key := range keys {
+		if err := clear(key, redisPool.Get()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	poolCfg := config.DefaultConfig.PoolConfig
+	defer func() {
+		config.DefaultConfig.ArchiveConfig = nil
+		config.DefaultConfig.PoolConfig = poolCfg
+	}()
+	config.DefaultConfig.ArchiveConfig = &config.ArchiveConfig{
+		Backend: config.ArchiveBackendSQLite,
+		Path:    filepath.Join(dir, "history.db"),
+	}
+	config.DefaultConfig.PoolConfig = &config.PoolConfig{
+		RetentionCfg: &config.RetentionConfig{
+			Statuses: map[string]int64{"error": 60},
+		},
+	}
+
+	mgr := NewRedisJobStatsManager(context.Background(), testingNamespace, redisPool)
+	mgr.Start()
+	defer mgr.Shutdown()
+	<-time.After(200 * time.Millisecond)
+
+	jobID := "fake_archived_job_ID"
+	key := utils.KeyJobStats(testingNamespace, jobID)
+	if err := clear(key, redisPool.Get()); err != nil {
+		t.Fatal(err)
+	}
+	defer clear(key, redisPool.Get())
+
+	testingStats := createFakeStats()
+	testingStats.Stats.JobID = jobID
+	testingStats.Stats.JobKind = job.JobKindGeneric
+	mgr.Save(testingStats)
+	<-time.After(200 * time.Millisecond)
+
+	if err := mgr.SetLastError(jobID, "boom"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SaveCheckpoint(jobID, "phase", 1); err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetJobStatus(jobID, job.JobStatusError)
+	<-time.After(200 * time.Millisecond)
+
+	list, err := mgr.List(models.JobQuery{Status: job.JobStatusError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Jobs[0].JobID != jobID || list.Jobs[0].LastError != "boom" {
+		t.Fatalf("expect the failed job archived with error details but got %d jobs\n", list.Total)
+	}
+
+	conn := redisPool.Get()
+	defer conn.Close()
+	ttl, err := redis.Int64(conn.Do("TTL", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > 60 {
+		t.Errorf("expect TTL of status 'Error' applied but got %d\n", ttl)
+	}
+
+	ttl, err = redis.Int64(conn.Do("TTL", utils.KeyJobCheckpoints(testingNamespace, jobID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > 60 {
+		t.Errorf("expect TTL of status 'Error' applied to the checkpoints but got %d\n", ttl)
+	}
+
+	if _, err := conn.Do("DEL", utils.KeyJobCheckpoints(testingNamespace, jobID)); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestCommand(t *testing.T) {
 	mgr := createStatsManager(redisPool)
 	mgr.Start()
@@ -164,6 +312,37 @@ func TestExpireJobStats(t *testing.T) {
 	}
 }
 
+func TestUpdateMissingJobStats(t *testing.T) {
+	rjs := NewRedisJobStatsManager(context.Background(), testingNamespace, redisPool)
+
+	jobID := "fake_expired_job_ID"
+	key := utils.KeyJobStats(testingNamespace, jobID)
+
+	if _, err := rjs.nextStatusSeq(jobID); !errs.IsObjectNotFoundError(err) {
+		t.Errorf("expect object not found error when issuing status sequence but got %v\n", err)
+	}
+	if err := rjs.SetLastError(jobID, "boom"); !errs.IsObjectNotFoundError(err) {
+		t.Errorf("expect object not found error when setting last error but got %v\n", err)
+	}
+	if err := rjs.SetRunAt(jobID, time.Now().Unix()); !errs.IsObjectNotFoundError(err) {
+		t.Errorf("expect object not found error when setting run_at but got %v\n", err)
+	}
+	if err := rjs.SetNextRetryAt(jobID, time.Now().Unix()); !errs.IsObjectNotFoundError(err) {
+		t.Errorf("expect object not found error when setting next_retry_at but got %v\n", err)
+	}
+
+	conn := redisPool.Get()
+	defer conn.Close()
+	existing, err := redis.Bool(conn.Do("EXISTS", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing {
+		t.Error("expect the missing job stats not recreated")
+		conn.Do("DEL", key)
+	}
+}
+
 func TestCheckIn(t *testing.T) {
 	mgr := createStatsManager(redisPool)
 	mgr.Start()
@@ -215,10 +394,154 @@ func TestCheckIn(t *testing.T) {
 		t.Fatalf("expect check in info 'checkin' but got '%s'\n", stats.Stats.CheckIn)
 	}
 
+	mgr.CheckIn("fake_job_ID", "checkin again")
+	<-time.After(200 * time.Millisecond)
+
+	history, err := mgr.CheckIns("fake_job_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.CheckIns) != 2 || history.CheckIns[0].Message != "checkin again" {
+		t.Fatalf("expect 2 check ins with the latest 'checkin again' but got %d\n", len(history.CheckIns))
+	}
+
+	if _, err := mgr.CheckIns("none_job_ID"); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect object not found error but got '%v'\n", err)
+	}
+
 	key := utils.KeyJobStats(testingNamespace, "fake_job_ID")
 	if err := clear(key, redisPool.Get()); err != nil {
 		t.Fatal(err)
 	}
+
+	key = utils.KeyJobCheckIns(testingNamespace, "fake_job_ID")
+	if err := clear(key, redisPool.Get()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReportProgress(t *testing.T) {
+	mgr := createStatsManager(redisPool)
+	mgr.Start()
+	defer mgr.Shutdown()
+	<-time.After(200 * time.Millisecond)
+
+	// make sure data existing
+	testingStats := createFakeStats()
+	mgr.Save(testingStats)
+	<-time.After(200 * time.Millisecond)
+
+	if err := mgr.ReportProgress("fake_job_ID", 11, 10, "copy", ""); err == nil {
+		t.Fatal("expect error for the progress exceeding total but got nil")
+	}
+
+	if err := mgr.ReportProgress("fake_job_ID", 3, 10, "copy", "copying"); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(200 * time.Millisecond)
+
+	stats, err := mgr.Retrieve("fake_job_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := stats.Stats.Progress
+	if p == nil || p.Current != 3 || p.Total != 10 || p.Percent != 30 || p.Phase != "copy" || p.Message != "copying" {
+		t.Fatalf("expect progress 3/10 of phase 'copy' but got %#v\n", p)
+	}
+
+	key := utils.KeyJobStats(testingNamespace, "fake_job_ID")
+	if err := clear(key, redisPool.Get()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetResult(t *testing.T) {
+	mgr := createStatsManager(redisPool)
+	mgr.Start()
+	defer mgr.Shutdown()
+	<-time.After(200 * time.Millisecond)
+
+	if err := mgr.SetResult("fake_job_ID", map[string]int{"count": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := mgr.GetResult("fake_job_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(res.Result) != `{"count":1}` {
+		t.Fatalf("expect result '{\"count\":1}' but got '%s'\n", res.Result)
+	}
+
+	if err := mgr.SetResult("fake_job_ID", make([]byte, maxResultSize)); err == nil {
+		t.Fatal("expect error for the oversized result but got nil")
+	}
+
+	// The result follows the TTL of the job stats
+	conn := redisPool.Get()
+	defer conn.Close()
+
+	statsKey := utils.KeyJobStats(testingNamespace, "fake_job_ID")
+	if _, err := conn.Do("HSET", statsKey, "id", "fake_job_ID"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("EXPIRE", statsKey, 3600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SetResult("fake_job_ID", map[string]int{"count": 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	key := utils.KeyJobResult(testingNamespace, "fake_job_ID")
+	if ttl, err := redis.Int64(conn.Do("TTL", key)); err != nil || ttl <= 3500 || ttl > 3600 {
+		t.Fatalf("expect TTL of the result aligned with the job stats but got %d (%v)\n", ttl, err)
+	}
+
+	if err := clear(statsKey, redisPool.Get()); err != nil {
+		t.Fatal(err)
+	}
+	if err := clear(key, redisPool.Get()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mgr.GetResult("fake_job_ID"); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect object not found error but got '%v'\n", err)
+	}
+}
+
+func TestCheckpoints(t *testing.T) {
+	mgr := createStatsManager(redisPool)
+	mgr.Start()
+	defer mgr.Shutdown()
+	<-time.After(200 * time.Millisecond)
+
+	if err := mgr.SaveCheckpoint("fake_job_ID", "phase", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := mgr.GetCheckpoint("fake_job_ID", "phase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "2" {
+		t.Fatalf("expect checkpoint '2' but got '%s'\n", data)
+	}
+
+	if _, err := mgr.GetCheckpoint("fake_job_ID", "none"); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect object not found error but got '%v'\n", err)
+	}
+
+	if err := mgr.ClearCheckpoints("fake_job_ID"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mgr.GetCheckpoint("fake_job_ID", "phase"); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect checkpoint cleared but got '%v'\n", err)
+	}
 }
 
 func getRedisHost() string {
@@ -0,0 +1,94 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package opm
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	maxStatusTimeline = 100
+
+	// Results of the status transition script
+	transitionRejected  = 0
+	transitionAccepted  = 1
+	transitionUnchanged = 2
+)
+
+// statusTransitionScript enforces the status state machine of the job.
+//
+// The status is updated only if the transition is legal and the update is not requested before
+// the current status, then the revision is increased and the transition is appended to the timeline.
+// The order of the requests follows the sequence numbers issued by the job stats rather than
+// the clocks of the nodes.
+// Statuses are compared case-insensitively. The terminal statuses 'Success', 'Stopped' and 'Expired' reject
+// any further transitions except the periodic job which restarts the execution with the same ID.
+//
+// KEYS[1]: key of the job stats
+// KEYS[2]: key of the status timeline
+// ARGV[1]: the target status
+// ARGV[2]: the sequence number of the request, 0 for the initial status, negative to skip the order check
+// ARGV[3]: now (seconds)
+// ARGV[4]: the max size of the timeline
+//
+// Returns: {result, current status, revision or rejection reason}
+var statusTransitionScript = redis.NewScript(2, `
+local transitions = {
+  pending = {scheduled = true, running = true, stopped = true, cancelled = true, error = true, success = true, expired = true},
+  scheduled = {pending = true, running = true, stopped = true, cancelled = true, error = true, success = true, expired = true},
+  running = {scheduled = true, stopped = true, cancelled = true, error = true, success = true},
+  error = {pending = true, scheduled = true, running = true, stopped = true, cancelled = true, success = true, expired = true},
+  cancelled = {pending = true, scheduled = true, running = true, stopped = true, error = true, success = true, expired = true},
+  stopped = {},
+  success = {},
+  expired = {}
+}
+local restarts = {pending = true, scheduled = true, running = true}
+
+local to = ARGV[1]
+local seq = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local vals = redis.call('HMGET', KEYS[1], 'status', 'status_seq', 'kind')
+local from = vals[1] or ''
+local lfrom = string.lower(from)
+local lto = string.lower(to)
+
+if lfrom == lto then
+  return {2, from, 'unchanged'}
+end
+
+if seq >= 0 and vals[2] and seq <= tonumber(vals[2]) then
+  return {0, from, 'outdated update'}
+end
+
+local allowed = transitions[lfrom] == nil or transitions[lfrom][lto] == true
+if not allowed and string.lower(vals[3] or '') == 'periodic' and restarts[lto] then
+  allowed = true
+end
+
+if not allowed then
+  return {0, from, 'illegal transition'}
+end
+
+local rev = redis.call('HINCRBY', KEYS[1], 'revision', 1)
+redis.call('HMSET', KEYS[1], 'status', to, 'update_time', now)
+if seq >= 0 then
+  redis.call('HSET', KEYS[1], 'status_seq', seq)
+end
+if lto == 'success' then
+  -- make sure the 'die_at' is reset in case it's a retrying job
+  redis.call('HSET', KEYS[1], 'die_at', 0)
+end
+
+redis.call('RPUSH', KEYS[2], cjson.encode({status = to, from = from, revision = rev, time = now}))
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[4]), -1)
+
+-- The timeline expires together with the job stats
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[2], ttl)
+end
+
+return {1, from, tostring(rev)}
+`)
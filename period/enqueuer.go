@@ -20,6 +20,24 @@ const (
 	periodicEnqueuerHorizon = 4 * time.Minute
 )
 
+// enqueueExecutionScript adds the execution of the periodic job policy to the scheduled set
+// unless the execution has been withdrawn.
+//
+// KEYS[1]: key of the scheduled set
+// KEYS[2]: key of the withdrawn flag of the execution
+// ARGV[1]: the time the execution is scheduled to run at
+// ARGV[2]: the raw json of the execution
+//
+// Returns: 1 if added or 0 if withdrawn
+var enqueueExecutionScript = redis.NewScript(2, `
+if redis.call('EXISTS', KEYS[2]) == 1 then
+  return 0
+end
+
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
 type periodicEnqueuer struct {
 	namespace             string
 	pool                  *redis.Pool
@@ -131,11 +149,22 @@ func (pe *periodicEnqueuer) enqueue() error {
 				return err
 			}
 
-			_, err = conn.Do("ZADD", utils.RedisKeyScheduled(pe.namespace), epoch, rawJSON)
+			added, err := redis.Bool(enqueueExecutionScript.Do(
+				conn,
+				utils.RedisKeyScheduled(pe.namespace),
+				utils.KeyPeriodicExecutionWithdrawn(pe.namespace, pl.PolicyID, epoch),
+				epoch,
+				rawJSON,
+			))
 			if err != nil {
 				return err
 			}
 
+			if !added {
+				logger.Infof("Skip the withdrawn execution of job %s for policy %s at %d\n", pj.jobName, pl.PolicyID, epoch)
+				continue
+			}
+
 			logger.Infof("Schedule job %s for policy %s at %d\n", pj.jobName, pl.PolicyID, epoch)
 		}
 
@@ -163,3 +192,23 @@ func (pe *periodicEnqueuer) shouldEnqueue() bool {
 
 	return lastEnqueue < (utils.NowEpochSeconds() - int64(periodicEnqueuerSleep/time.Minute))
 }
+
+// WithdrawExecution marks the execution of the periodic job policy at the epoch withdrawn,
+// so it's not scheduled again by the enqueuer and not run if it's already scheduled.
+// The mark expires once the execution is out of the horizon of the enqueuer.
+func WithdrawExecution(conn redis.Conn, namespace string, policyID string, epoch int64) error {
+	ttl := epoch - time.Now().Unix()
+	if ttl < 0 {
+		ttl = 0
+	}
+	ttl += int64(periodicEnqueuerHorizon / time.Second)
+
+	_, err := conn.Do("SET", utils.KeyPeriodicExecutionWithdrawn(namespace, policyID, epoch), 1, "EX", ttl)
+
+	return err
+}
+
+// IsExecutionWithdrawn checks if the execution of the periodic job policy at the epoch is withdrawn.
+func IsExecutionWithdrawn(conn redis.Conn, namespace string, policyID string, epoch int64) (bool, error) {
+	return redis.Bool(conn.Do("EXISTS", utils.KeyPeriodicExecutionWithdrawn(namespace, policyID, epoch)))
+}
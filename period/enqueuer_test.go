@@ -6,6 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gomodule/redigo/redis"
+	"github.com/robfig/cron"
+
 	"github.com/Colstuwjx/job/tests"
 	"github.com/Colstuwjx/job/utils"
 )
@@ -52,3 +55,61 @@ func TestEnqueue(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestEnqueueSkipsWithdrawnExecution(t *testing.T) {
+	ns := tests.GiveMeTestNamespace()
+
+	pl := &PeriodicJobPolicy{
+		PolicyID: "fake_ID",
+		JobName:  "fake_name",
+		CronSpec: "0 * * * * *",
+	}
+
+	ps := &periodicJobPolicyStore{
+		lock:     new(sync.RWMutex),
+		policies: make(map[string]*PeriodicJobPolicy),
+	}
+	ps.add(pl)
+
+	schedule, err := cron.Parse(pl.CronSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := schedule.Next(time.Now())
+	withdrawn := []int64{next.Unix(), schedule.Next(next).Unix()}
+
+	conn := redisPool.Get()
+	defer conn.Close()
+	for _, epoch := range withdrawn {
+		if err := WithdrawExecution(conn, ns, pl.PolicyID, epoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enqueuer := newPeriodicEnqueuer(ns, redisPool, ps)
+	if err := enqueuer.enqueue(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, epoch := range withdrawn {
+		if n, err := redis.Int(conn.Do("ZCOUNT", utils.RedisKeyScheduled(ns), epoch, epoch)); err != nil || n != 0 {
+			t.Errorf("expect withdrawn execution at %d not enqueued but got %d (%v)\n", epoch, n, err)
+		}
+		if yes, err := IsExecutionWithdrawn(conn, ns, pl.PolicyID, epoch); err != nil || !yes {
+			t.Errorf("expect execution at %d withdrawn but got %v (%v)\n", epoch, yes, err)
+		}
+	}
+	if n, err := redis.Int(conn.Do("ZCARD", utils.RedisKeyScheduled(ns))); err != nil || n == 0 {
+		t.Errorf("expect the other executions enqueued but got %d (%v)\n", n, err)
+	}
+
+	keys := []string{utils.RedisKeyScheduled(ns), utils.KeyJobStats(ns, "fake_ID"), utils.RedisKeyLastPeriodicEnqueue(ns)}
+	for _, epoch := range withdrawn {
+		keys = append(keys, utils.KeyPeriodicExecutionWithdrawn(ns, pl.PolicyID, epoch))
+	}
+	for _, key := range keys {
+		if err := tests.Clear(key, redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -58,4 +58,10 @@ type Interface interface {
 	// Return:
 	//  the ptr of the being deletd policy
 	RemovePeriodicPolicy(policyID string) *PeriodicJobPolicy
+
+	// List all the cached periodic job policies
+	//
+	// Return:
+	//  the copies of the cached policies
+	Policies() []*PeriodicJobPolicy
 }
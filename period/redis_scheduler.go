@@ -310,6 +310,17 @@ func (rps *RedisPeriodicScheduler) RemovePeriodicPolicy(policyID string) *Period
 	return rps.pstore.remove(policyID)
 }
 
+// Policies is implementation of the same method in period.Interface
+func (rps *RedisPeriodicScheduler) Policies() []*PeriodicJobPolicy {
+	policies := make([]*PeriodicJobPolicy, 0)
+	for _, p := range rps.pstore.list() {
+		copied := *p
+		policies = append(policies, &copied)
+	}
+
+	return policies
+}
+
 func (rps *RedisPeriodicScheduler) exists(rawPolicy string) (int64, bool) {
 	if utils.IsEmptyStr(rawPolicy) {
 		return 0, false
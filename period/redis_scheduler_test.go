@@ -67,6 +67,10 @@ func TestPubFunc(t *testing.T) {
 		t.Fatalf("expect 1 item in pstore but got '%d' after accepting \n", scheduler.pstore.size())
 	}
 
+	if policies := scheduler.Policies(); len(policies) != 1 || policies[0].CronSpec != p.CronSpec {
+		t.Fatalf("expect the accepted policy listed but got %d policies \n", len(policies))
+	}
+
 	if rmp := scheduler.RemovePeriodicPolicy("fake_ID"); rmp == nil {
 		t.Fatal("expect none nil object returned after removing but got nil")
 	}
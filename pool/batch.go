@@ -0,0 +1,132 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocraft/work"
+
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// EnqueueBatch enqueues the jobs in one redis transaction and tracks them as a batch.
+// Only the generic and scheduled jobs are supported, the jobs are validated by the caller.
+func (gcwp *GoCraftWorkPool) EnqueueBatch(jobs []*models.JobData, hookURL string) (models.JobBatch, error) {
+	if len(jobs) == 0 {
+		return models.JobBatch{}, errors.New("empty batch")
+	}
+
+	batchID := utils.MakeIdentifier()
+	now := time.Now().Unix()
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return models.JobBatch{}, err
+	}
+
+	var (
+		results  = make([]models.JobStats, 0, len(jobs))
+		jobIDs   = make([]interface{}, 0, len(jobs))
+		jobNames = make(map[string]bool)
+		maxDelay int64
+	)
+	for _, jd := range jobs {
+		j := &work.Job{
+			Name:       jd.Name,
+			ID:         utils.MakeIdentifier(),
+			EnqueuedAt: now,
+			Args:       jd.Parameters,
+		}
+
+		rawJSON, err := utils.SerializeJob(j)
+		if err != nil {
+			conn.Do("DISCARD")
+			return models.JobBatch{}, err
+		}
+
+		kind := job.JobKindGeneric
+		runAt := int64(0)
+		if jd.Metadata != nil && jd.Metadata.JobKind == job.JobKindScheduled {
+			kind = job.JobKindScheduled
+			delay := int64(jd.Metadata.ScheduleDelay)
+			if delay > maxDelay {
+				maxDelay = delay
+			}
+			runAt = now + delay
+		}
+
+		res := generateResult(j, kind, false)
+		res.Stats.RunAt = runAt
+		res.Stats.BatchID = batchID
+
+		// Keep the settings of the job before it's possible to be run
+		if err := gcwp.sendJobSettings(conn, res.Stats, jd); err != nil {
+			conn.Do("DISCARD")
+			return models.JobBatch{}, err
+		}
+
+		if runAt > 0 {
+			conn.Send("ZADD", utils.KeyScheduled(gcwp.namespace), runAt, rawJSON)
+		} else {
+			conn.Send("LPUSH", utils.KeyJobs(gcwp.namespace, jd.Name), rawJSON)
+		}
+
+		if !jobNames[jd.Name] {
+			jobNames[jd.Name] = true
+			conn.Send("SADD", utils.KeyKnownJobs(gcwp.namespace), jd.Name)
+		}
+
+		results = append(results, res)
+		jobIDs = append(jobIDs, j.ID)
+	}
+
+	// The batch is kept as long as the stats of the jobs
+	ttl := config.GetStatsTTL(job.JobKindGeneric) + maxDelay
+	batchKey := utils.KeyBatch(gcwp.namespace, batchID)
+	jobsKey := utils.KeyBatchJobs(gcwp.namespace, batchID)
+	conn.Send("HMSET", batchKey,
+		"id", batchID,
+		"total", len(jobs),
+		"create_time", now,
+		"status_hook", hookURL,
+	)
+	conn.Send("RPUSH", append([]interface{}{jobsKey}, jobIDs...)...)
+	conn.Send("EXPIRE", batchKey, ttl)
+	conn.Send("EXPIRE", jobsKey, ttl)
+
+	if _, err := conn.Do("EXEC"); err != nil {
+		return models.JobBatch{}, err
+	}
+
+	batch := models.JobBatch{
+		BatchID:    batchID,
+		Total:      int64(len(jobs)),
+		StatusHook: hookURL,
+		RefLink:    fmt.Sprintf("/api/v1/batches/%s", batchID),
+		CreateTime: now,
+		Counts:     make(map[string]int64),
+		Jobs:       make([]*models.JobStatData, 0, len(results)),
+	}
+	for _, res := range results {
+		// Save data with async way like the single enqueued job
+		gcwp.statsManager.Save(res)
+
+		batch.Counts[res.Stats.Status]++
+		batch.Jobs = append(batch.Jobs, res.Stats)
+	}
+
+	return batch, nil
+}
+
+// GetBatch returns the aggregate status of the jobs submitted in the batch.
+func (gcwp *GoCraftWorkPool) GetBatch(batchID string) (models.JobBatch, error) {
+	return gcwp.statsManager.RetrieveBatch(batchID)
+}
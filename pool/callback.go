@@ -0,0 +1,142 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	callbackOnSuccess  = "on_success"
+	callbackOnFailure  = "on_failure"
+	callbackOnComplete = "on_complete"
+
+	// The parameters injected into the follow-up jobs
+	paramParentID     = "parent_id"
+	paramParentStatus = "parent_status"
+	paramParentResult = "parent_result"
+)
+
+// sendCallbacks sends the commands keeping the follow-up jobs which are enqueued when the job ends.
+func sendCallbacks(conn redis.Conn, namespace string, jobID string, runAt int64, callbacks *models.JobCallbacks) error {
+	if callbacks == nil {
+		return nil
+	}
+
+	args := []interface{}{utils.KeyJobCallbacks(namespace, jobID)}
+	for event, cb := range map[string]*models.JobCallback{
+		callbackOnSuccess:  callbacks.OnSuccess,
+		callbackOnFailure:  callbacks.OnFailure,
+		callbackOnComplete: callbacks.OnComplete,
+	} {
+		if cb == nil {
+			continue
+		}
+
+		rawJSON, err := json.Marshal(cb)
+		if err != nil {
+			return err
+		}
+		args = append(args, event, rawJSON)
+	}
+
+	if len(args) == 1 {
+		return nil
+	}
+
+	// Keep the callbacks as long as the stats of the job
+	ttl := config.GetStatsTTL(job.JobKindGeneric)
+	if delay := runAt - time.Now().Unix(); delay > 0 {
+		ttl += delay
+	}
+
+	if err := conn.Send("HMSET", args...); err != nil {
+		return err
+	}
+
+	return conn.Send("EXPIRE", args[0], ttl)
+}
+
+// fireCallbacks enqueues the follow-up jobs of the ended job regarding of its terminal status.
+// The callbacks are removed when they're fetched to make sure they're fired only once.
+func (jt *jobTracker) fireCallbacks(conn redis.Conn, jobID string, status string) {
+	key := utils.KeyJobCallbacks(jt.namespace, jobID)
+
+	conn.Send("MULTI")
+	conn.Send("HGETALL", key)
+	conn.Send("DEL", key)
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		logger.Errorf("Failed to get callbacks of job %s: %s\n", jobID, err)
+		return
+	}
+
+	callbacks, err := redis.StringMap(replies[0], nil)
+	if err != nil || len(callbacks) == 0 {
+		return
+	}
+
+	events := []string{callbackOnComplete}
+	switch status {
+	case job.JobStatusSuccess:
+		events = append([]string{callbackOnSuccess}, events...)
+	case job.JobStatusError:
+		events = append([]string{callbackOnFailure}, events...)
+	}
+
+	for _, event := range events {
+		rawJSON, ok := callbacks[event]
+		if !ok {
+			continue
+		}
+
+		cb := &models.JobCallback{}
+		if err := json.Unmarshal([]byte(rawJSON), cb); err != nil {
+			logger.Errorf("Failed to decode %s callback of job %s: %s\n", event, jobID, err)
+			continue
+		}
+
+		if err := jt.enqueueCallback(jobID, status, cb); err != nil {
+			logger.Errorf("Failed to enqueue %s callback '%s' of job %s: %s\n", event, cb.Name, jobID, err)
+			continue
+		}
+		logger.Infof("The %s callback '%s' of job %s is enqueued\n", event, cb.Name, jobID)
+	}
+}
+
+// enqueueCallback enqueues the follow-up job with the status and result of the ended job injected.
+func (jt *jobTracker) enqueueCallback(jobID string, status string, cb *models.JobCallback) error {
+	params := make(models.Parameters, len(cb.Parameters)+3)
+	for k, v := range cb.Parameters {
+		params[k] = v
+	}
+	params[paramParentID] = jobID
+	params[paramParentStatus] = status
+	if res, err := jt.statsManager.GetResult(jobID); err == nil {
+		var result interface{}
+		if err := json.Unmarshal(res.Result, &result); err == nil {
+			params[paramParentResult] = result
+		}
+	}
+
+	theJob, ok := jt.backendPool.IsKnownJob(cb.Name)
+	if !ok {
+		return errors.New("job is unknown")
+	}
+	if err := jt.backendPool.ValidateJobParameters(theJob, params); err != nil {
+		return err
+	}
+
+	_, err := jt.backendPool.EnqueueChild(jobID, cb.Name, params, 0, false)
+	return err
+}
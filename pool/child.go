@@ -0,0 +1,185 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// awaitChildrenScript is called when the parent job succeeds. The parent job is marked
+// as awaiting if any awaited child job is unfinished.
+//
+// KEYS[1]: key of the set of the unfinished awaited child jobs
+// KEYS[2]: key of the parent job stats
+//
+// Returns: nil if the parent job is awaiting, otherwise the ID of the failed child job or empty
+var awaitChildrenScript = redis.NewScript(2, `
+if redis.call('SCARD', KEYS[1]) > 0 then
+  redis.call('HSET', KEYS[2], 'awaiting_children', 1)
+  return false
+end
+
+return redis.call('HGET', KEYS[2], 'child_failed') or ''
+`)
+
+// childEndedScript removes the ended child job from the set of the parent job.
+// The failed child job is recorded with the parent job stats.
+//
+// KEYS[1]: key of the set of the unfinished awaited child jobs
+// KEYS[2]: key of the parent job stats
+// ARGV[1]: ID of the child job
+// ARGV[2]: 1 if the child job succeeded, otherwise 0
+//
+// Returns: nil if the parent job is not completed by this call, otherwise the ID of the failed child job or empty
+var childEndedScript = redis.NewScript(2, `
+if redis.call('SREM', KEYS[1], ARGV[1]) == 0 or redis.call('EXISTS', KEYS[2]) == 0 then
+  return false
+end
+
+if ARGV[2] ~= '1' then
+  redis.call('HSETNX', KEYS[2], 'child_failed', ARGV[1])
+end
+
+if redis.call('SCARD', KEYS[1]) > 0 or redis.call('HDEL', KEYS[2], 'awaiting_children') == 0 then
+  return false
+end
+
+return redis.call('HGET', KEYS[2], 'child_failed') or ''
+`)
+
+// EnqueueChild enqueues the child job of the running job with the parent job recorded.
+func (gcwp *GoCraftWorkPool) EnqueueChild(parentID string, jobName string, params models.Parameters, runAfterSeconds uint64, await bool) (models.JobStats, error) {
+	if utils.IsEmptyStr(parentID) {
+		return models.JobStats{}, errors.New("empty parent job ID")
+	}
+
+	now := time.Now().Unix()
+	j := &work.Job{
+		Name:       jobName,
+		ID:         utils.MakeIdentifier(),
+		EnqueuedAt: now,
+		Args:       params,
+	}
+
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return models.JobStats{}, err
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	// The parent job is recorded with the enqueuing to make sure it's known when the child job ends
+	conn.Send("MULTI")
+	conn.Send("HSET", utils.KeyJobStats(gcwp.namespace, j.ID), "parent_id", parentID)
+	if await {
+		childrenKey := utils.KeyJobChildren(gcwp.namespace, parentID)
+		conn.Send("SADD", childrenKey, j.ID)
+		conn.Send("EXPIRE", childrenKey, config.GetStatsTTL(job.JobKindGeneric)+int64(runAfterSeconds))
+	}
+	conn.Send("SADD", utils.KeyKnownJobs(gcwp.namespace), jobName)
+
+	kind := job.JobKindGeneric
+	runAt := int64(0)
+	if runAfterSeconds > 0 {
+		kind = job.JobKindScheduled
+		runAt = now + int64(runAfterSeconds)
+		conn.Send("ZADD", utils.KeyScheduled(gcwp.namespace), runAt, rawJSON)
+	} else {
+		conn.Send("LPUSH", utils.KeyJobs(gcwp.namespace, jobName), rawJSON)
+	}
+
+	if _, err := conn.Do("EXEC"); err != nil {
+		return models.JobStats{}, err
+	}
+
+	res := generateResult(j, kind, false)
+	res.Stats.RunAt = runAt
+	res.Stats.ParentID = parentID
+	// Save data with async way like the other enqueued jobs
+	gcwp.statsManager.Save(res)
+
+	return res, nil
+}
+
+// awaitChildren checks the awaited child jobs when the parent job succeeds.
+// Returns true if the parent job is awaiting the unfinished child jobs,
+// otherwise the ID of the failed child job if have.
+func awaitChildren(conn redis.Conn, namespace string, jobID string) (bool, string, error) {
+	failed, err := redis.String(awaitChildrenScript.Do(
+		conn,
+		utils.KeyJobChildren(namespace, jobID),
+		utils.KeyJobStats(namespace, jobID),
+	))
+	if err == redis.ErrNil {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return false, failed, nil
+}
+
+// childEnded tracks the ended job which may be awaited by its parent job,
+// and completes the parent job if all the awaited child jobs are finished.
+// The errors are just logged as the child job has ended.
+func (jt *jobTracker) childEnded(conn redis.Conn, jobID string, succeeded bool) {
+	parentID, err := redis.String(conn.Do("HGET", utils.KeyJobStats(jt.namespace, jobID), "parent_id"))
+	if err != nil || utils.IsEmptyStr(parentID) {
+		// Not a child job
+		return
+	}
+
+	result := 0
+	if succeeded {
+		result = 1
+	}
+
+	failed, err := redis.String(childEndedScript.Do(
+		conn,
+		utils.KeyJobChildren(jt.namespace, parentID),
+		utils.KeyJobStats(jt.namespace, parentID),
+		jobID,
+		result,
+	))
+	if err == redis.ErrNil {
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to track child job %s of job %s: %s\n", jobID, parentID, err)
+		return
+	}
+
+	jt.completeParent(conn, parentID, failed)
+}
+
+// completeParent completes the parent job which has been awaiting the child jobs.
+// The parent job fails if any awaited child job fails.
+func (jt *jobTracker) completeParent(conn redis.Conn, jobID string, failedChild string) {
+	status := job.JobStatusSuccess
+	if utils.IsEmptyStr(failedChild) {
+		logger.Infof("All child jobs of job %s are finished\n", jobID)
+	} else {
+		status = job.JobStatusError
+		msg := fmt.Sprintf("child job %s is not succeeded", failedChild)
+		if err := jt.statsManager.SetLastError(jobID, msg); err != nil {
+			logger.Errorf("Failed to record the error of job %s: %s\n", jobID, err)
+		}
+	}
+	jt.statsManager.SetJobStatus(jobID, status)
+
+	// The completed parent job may be awaited by its parent job or have follow-up jobs too
+	jt.jobEnded(conn, jobID, status)
+}
@@ -0,0 +1,219 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"time"
+
+	"github.com/gocraft/work"
+
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	// The fixed page size of listing the dead jobs via work.Client
+	deadJobsClientPageSize = 20
+
+	defaultDeadJobsPageSize = 20
+	maxDeadJobsPageSize     = 100
+
+	deadJobsActionRetry  = "retry"
+	deadJobsActionDelete = "delete"
+)
+
+// ListDeadJobs lists the jobs in the dead queue.
+func (gcwp *GoCraftWorkPool) ListDeadJobs(filter *models.DeadJobFilter, page, pageSize uint) (models.DeadJobList, error) {
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = defaultDeadJobsPageSize
+	}
+	if pageSize > maxDeadJobsPageSize {
+		pageSize = maxDeadJobsPageSize
+	}
+
+	offset := int64((page - 1) * pageSize)
+	list := models.DeadJobList{
+		Jobs: make([]*models.DeadJob, 0),
+	}
+
+	// No need to scan the whole queue if no filter
+	if filter == nil || *filter == (models.DeadJobFilter{}) {
+		clientPage := uint(offset/deadJobsClientPageSize) + 1
+		skip := offset % deadJobsClientPageSize
+		for ; int64(len(list.Jobs)) < int64(pageSize); clientPage++ {
+			jobs, count, err := gcwp.client.DeadJobs(clientPage)
+			if err != nil {
+				return list, err
+			}
+
+			list.Total = count
+			for _, dj := range jobs {
+				if skip > 0 {
+					skip--
+					continue
+				}
+				if int64(len(list.Jobs)) < int64(pageSize) {
+					list.Jobs = append(list.Jobs, toDeadJob(dj))
+				}
+			}
+
+			if len(jobs) < deadJobsClientPageSize {
+				break
+			}
+		}
+
+		return list, nil
+	}
+
+	matched, total, err := gcwp.scanDeadJobs(matchDeadJob(filter, time.Now().Unix()), offset+int64(pageSize))
+	if err != nil {
+		return list, err
+	}
+
+	list.Total = total
+	for i := offset; i < int64(len(matched)); i++ {
+		list.Jobs = append(list.Jobs, toDeadJob(matched[i]))
+	}
+
+	return list, nil
+}
+
+// RetryDeadJobs retries the selected dead jobs.
+func (gcwp *GoCraftWorkPool) RetryDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	return gcwp.handleDeadJobs(deadJobsActionRetry, jobIDs, filter, gcwp.client.RetryDeadJob)
+}
+
+// DeleteDeadJobs deletes the selected dead jobs.
+func (gcwp *GoCraftWorkPool) DeleteDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error) {
+	return gcwp.handleDeadJobs(deadJobsActionDelete, jobIDs, filter, gcwp.client.DeleteDeadJob)
+}
+
+// handleDeadJobs applies the action to the dead jobs selected by the IDs and/or the filter.
+func (gcwp *GoCraftWorkPool) handleDeadJobs(
+	action string,
+	jobIDs []string,
+	filter *models.DeadJobFilter,
+	apply func(diedAt int64, jobID string) error,
+) (models.DeadJobsActionResult, error) {
+	result := models.DeadJobsActionResult{
+		Action: action,
+		Failed: make(map[string]string),
+	}
+
+	match := matchDeadJob(filter, time.Now().Unix())
+	if len(jobIDs) > 0 {
+		ids := make(map[string]bool, len(jobIDs))
+		for _, id := range jobIDs {
+			ids[id] = true
+		}
+
+		matchFilter := match
+		match = func(dj *work.DeadJob) bool {
+			return ids[dj.ID] && matchFilter(dj)
+		}
+	}
+
+	// Collect the whole matched set first as the paging is shifted by the action
+	matched, _, err := gcwp.scanDeadJobs(match, -1)
+	if err != nil {
+		return result, err
+	}
+
+	result.Matched = len(matched)
+	handled := make(map[string]bool, len(matched))
+	for _, dj := range matched {
+		handled[dj.ID] = true
+		if err := apply(dj.DiedAt, dj.ID); err != nil {
+			result.Failed[dj.ID] = err.Error()
+			continue
+		}
+		result.Succeeded++
+	}
+
+	for _, id := range jobIDs {
+		if !handled[id] {
+			result.Failed[id] = "not found in the dead queue"
+		}
+	}
+
+	return result, nil
+}
+
+// findDeadJob finds the dead job with the ID.
+// Returns nil if the job is not in the dead queue.
+func (gcwp *GoCraftWorkPool) findDeadJob(jobID string) (*work.DeadJob, error) {
+	matched, _, err := gcwp.scanDeadJobs(func(dj *work.DeadJob) bool {
+		return dj.ID == jobID
+	}, 1)
+	if err != nil || len(matched) == 0 {
+		return nil, err
+	}
+
+	return matched[0], nil
+}
+
+// scanDeadJobs walks through the dead queue page by page and collects the dead jobs matching the condition.
+// At most limit jobs are collected, -1 for no limit.
+// Returns the collected dead jobs and the total count of the matched ones.
+func (gcwp *GoCraftWorkPool) scanDeadJobs(match func(dj *work.DeadJob) bool, limit int64) ([]*work.DeadJob, int64, error) {
+	matched := make([]*work.DeadJob, 0)
+	var total int64
+
+	for page := uint(1); ; page++ {
+		jobs, count, err := gcwp.client.DeadJobs(page)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, dj := range jobs {
+			if !match(dj) {
+				continue
+			}
+
+			total++
+			if limit < 0 || int64(len(matched)) < limit {
+				matched = append(matched, dj)
+			}
+		}
+
+		if len(jobs) == 0 || int64(page)*deadJobsClientPageSize >= count {
+			break
+		}
+	}
+
+	return matched, total, nil
+}
+
+// matchDeadJob returns the matching func of the filter
+func matchDeadJob(filter *models.DeadJobFilter, now int64) func(dj *work.DeadJob) bool {
+	return func(dj *work.DeadJob) bool {
+		if filter == nil {
+			return true
+		}
+
+		if !utils.IsEmptyStr(filter.Name) && dj.Name != filter.Name {
+			return false
+		}
+
+		if filter.OlderThan > 0 && dj.DiedAt > now-filter.OlderThan {
+			return false
+		}
+
+		return true
+	}
+}
+
+func toDeadJob(dj *work.DeadJob) *models.DeadJob {
+	return &models.DeadJob{
+		JobID:     dj.ID,
+		Name:      dj.Name,
+		Args:      dj.Args,
+		Fails:     dj.Fails,
+		LastError: dj.LastErr,
+		FailedAt:  dj.FailedAt,
+		DiedAt:    dj.DiedAt,
+	}
+}
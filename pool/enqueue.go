@@ -0,0 +1,174 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// enqueueUniqueScript pushes the unique job to the pending job list if it's not duplicated.
+// Same with the one of the backend pool, so the unique jobs enqueued by both are deduplicated.
+//
+// KEYS[1]: key of the pending job list
+// KEYS[2]: key of the unique job
+// ARGV[1]: the raw json of the job
+//
+// Returns: 'ok' if pushed, 'dup' if duplicated
+var enqueueUniqueScript = redis.NewScript(2, `
+if redis.call('SET', KEYS[2], '1', 'NX', 'EX', '86400') then
+  redis.call('LPUSH', KEYS[1], ARGV[1])
+  return 'ok'
+end
+
+redis.call('SET', KEYS[2], '1', 'EX', '86400')
+return 'dup'
+`)
+
+// enqueueUniqueInScript adds the unique job to the scheduled set if it's not duplicated.
+//
+// KEYS[1]: key of the scheduled set
+// KEYS[2]: key of the unique job
+// ARGV[1]: the raw json of the job
+// ARGV[2]: the time the job is scheduled to run at
+//
+// Returns: 'ok' if added, 'dup' if duplicated
+var enqueueUniqueInScript = redis.NewScript(2, `
+if redis.call('SET', KEYS[2], '1', 'NX', 'EX', '86400') then
+  redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+  return 'ok'
+end
+
+redis.call('SET', KEYS[2], '1', 'EX', '86400')
+return 'dup'
+`)
+
+// EnqueueJob enqueues the generic or scheduled job in the same redis transaction which keeps
+// the settings of the job, so the job is never run without them.
+func (gcwp *GoCraftWorkPool) EnqueueJob(jd *models.JobData) (models.JobStats, error) {
+	if jd == nil || jd.Metadata == nil {
+		return models.JobStats{}, errors.New("malformed job data")
+	}
+
+	now := time.Now().Unix()
+	j := &work.Job{
+		Name:       jd.Name,
+		ID:         utils.MakeIdentifier(),
+		EnqueuedAt: now,
+		Args:       jd.Parameters,
+	}
+
+	kind := job.JobKindGeneric
+	runAt := int64(0)
+	switch jd.Metadata.JobKind {
+	case job.JobKindGeneric:
+	case job.JobKindScheduled:
+		kind = job.JobKindScheduled
+		runAt = now + int64(jd.Metadata.ScheduleDelay)
+	default:
+		return models.JobStats{}, fmt.Errorf("job kind '%s' can not be enqueued", jd.Metadata.JobKind)
+	}
+
+	partitionKey := jd.Metadata.PartitionKey
+	if !utils.IsEmptyStr(partitionKey) && (kind != job.JobKindGeneric || jd.Metadata.IsUnique) {
+		return models.JobStats{}, errors.New("partition key is only supported by the generic job without uniqueness")
+	}
+
+	if jd.Metadata.IsUnique {
+		uniqueKey, err := utils.RedisKeyUniqueJob(gcwp.namespace, jd.Name, jd.Parameters)
+		if err != nil {
+			return models.JobStats{}, err
+		}
+		j.Unique = true
+		j.UniqueKey = uniqueKey
+	}
+
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return models.JobStats{}, err
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	res := generateResult(j, kind, j.Unique)
+	res.Stats.RunAt = runAt
+	res.Stats.PartitionKey = partitionKey
+
+	if err := conn.Send("MULTI"); err != nil {
+		return models.JobStats{}, err
+	}
+
+	if err := gcwp.sendJobSettings(conn, res.Stats, jd); err != nil {
+		conn.Do("DISCARD")
+		return models.JobStats{}, err
+	}
+
+	switch {
+	case !utils.IsEmptyStr(partitionKey):
+		// The job is appended to the backlog of the partition together with the partition key,
+		// and dispatched if the partition is free
+		conn.Send("HSET", utils.KeyJobStats(gcwp.namespace, j.ID), "partition_key", partitionKey)
+		sendDispatchPartition(conn, gcwp.namespace, partitionKey, rawJSON)
+	case j.Unique && runAt > 0:
+		enqueueUniqueInScript.Send(conn, utils.KeyScheduled(gcwp.namespace), j.UniqueKey, rawJSON, runAt)
+	case j.Unique:
+		enqueueUniqueScript.Send(conn, utils.KeyJobs(gcwp.namespace, jd.Name), j.UniqueKey, rawJSON)
+	case runAt > 0:
+		conn.Send("ZADD", utils.KeyScheduled(gcwp.namespace), runAt, rawJSON)
+	default:
+		conn.Send("LPUSH", utils.KeyJobs(gcwp.namespace, jd.Name), rawJSON)
+	}
+	conn.Send("SADD", utils.KeyKnownJobs(gcwp.namespace), jd.Name)
+
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return models.JobStats{}, err
+	}
+
+	if j.Unique {
+		if res, _ := redis.String(replies[len(replies)-2], nil); res != "ok" {
+			// Drop the settings of the discarded duplicated job
+			conn.Do("DEL", utils.KeyJobStats(gcwp.namespace, j.ID), utils.KeyJobCallbacks(gcwp.namespace, j.ID))
+			return models.JobStats{}, fmt.Errorf("job '%s' can not be enqueued, please check the job metatdata", jd.Name)
+		}
+	}
+
+	if !utils.IsEmptyStr(partitionKey) {
+		if err := partitionDispatched(partitionKey, replies[len(replies)-2]); err != nil {
+			return models.JobStats{}, err
+		}
+	}
+
+	// Save data with async way like the other enqueued jobs
+	gcwp.statsManager.Save(res)
+
+	return res, nil
+}
+
+// sendJobSettings sends the commands keeping the settings of the job with the job stats
+// to the transaction which enqueues the job. The expiry time is filled in the stats.
+func (gcwp *GoCraftWorkPool) sendJobSettings(conn redis.Conn, stats *models.JobStatData, jd *models.JobData) error {
+	if err := sendCallbacks(conn, gcwp.namespace, stats.JobID, stats.RunAt, &jd.JobCallbacks); err != nil {
+		return err
+	}
+
+	if jd.Metadata == nil {
+		return nil
+	}
+
+	if err := sendRetryPolicy(conn, gcwp.namespace, stats.JobID, jd.Metadata.RetryPolicy); err != nil {
+		return err
+	}
+
+	stats.ExpireAt = expireAt(jd.Metadata, stats)
+	return sendExpireAt(conn, gcwp.namespace, stats.JobID, stats.ExpireAt)
+}
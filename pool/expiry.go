@@ -0,0 +1,66 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// expireAt returns the time the job expires at if it has not started, 0 if it never expires.
+// The max queue time counts from the time the job is due to run.
+func expireAt(metadata *models.JobMetadata, stats *models.JobStatData) int64 {
+	t := metadata.ExpireAt
+	if metadata.MaxQueueTime > 0 {
+		runAt := stats.RunAt
+		if runAt == 0 {
+			runAt = stats.EnqueueTime
+		}
+
+		if deadline := runAt + metadata.MaxQueueTime; t == 0 || deadline < t {
+			t = deadline
+		}
+	}
+
+	return t
+}
+
+// sendExpireAt sends the command keeping the time the job expires at if it has not started.
+func sendExpireAt(conn redis.Conn, namespace string, jobID string, expireAt int64) error {
+	if expireAt <= 0 {
+		return nil
+	}
+
+	return conn.Send("HSET", utils.KeyJobStats(namespace, jobID), "expire_at", expireAt)
+}
+
+// expired checks if the job starts too late and marks it expired.
+// The job is run if the expiry time can not be checked.
+func (rj *RedisJob) expired(j *work.Job) bool {
+	conn := rj.redisPool.Get()
+	defer conn.Close()
+
+	expireAt, err := redis.Int64(conn.Do("HGET", utils.KeyJobStats(rj.namespace, j.ID), "expire_at"))
+	if err != nil {
+		if err != redis.ErrNil {
+			logger.Errorf("Failed to check expiry time of job '%s:%s': %s\n", j.Name, j.ID, err)
+		}
+		return false
+	}
+
+	if expireAt <= 0 || time.Now().Unix() < expireAt {
+		return false
+	}
+
+	rj.statsManager.SetJobStatus(j.ID, job.JobStatusExpired)
+	logger.Warningf("Job '%s:%s' is expired at %d without running\n", j.Name, j.ID, expireAt)
+
+	return true
+}
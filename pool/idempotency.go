@@ -0,0 +1,99 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// idempotencyRecord is kept with the idempotency key.
+// The stats are empty until the original submission is done.
+type idempotencyRecord struct {
+	Digest string           `json:"digest"`
+	Stats  *models.JobStats `json:"stats,omitempty"`
+}
+
+// ClaimIdempotencyKey claims the idempotency key for the job submission with the request digest.
+// Returns the stats of the original submission if the key has been used by the same request,
+// or nil if the key is claimed by this submission.
+func (gcwp *GoCraftWorkPool) ClaimIdempotencyKey(key string, digest string) (*models.JobStats, error) {
+	if utils.IsEmptyStr(key) {
+		return nil, errors.New("empty idempotency key")
+	}
+
+	data, err := json.Marshal(&idempotencyRecord{Digest: digest})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	redisKey := utils.KeyIdempotency(gcwp.namespace, key)
+	// Try twice in case the existing key expires between SET and GET
+	for i := 0; i < 2; i++ {
+		reply, err := redis.String(conn.Do("SET", redisKey, data, "EX", config.GetIdempotencyKeyTTL(), "NX"))
+		if err == nil && reply == "OK" {
+			return nil, nil
+		}
+		if err != nil && err != redis.ErrNil {
+			return nil, err
+		}
+
+		raw, err := redis.Bytes(conn.Do("GET", redisKey))
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := &idempotencyRecord{}
+		if err := json.Unmarshal(raw, record); err != nil {
+			return nil, err
+		}
+		if record.Digest != digest {
+			return nil, errs.IdempotencyConflictError(key, "already used by a different request")
+		}
+		if record.Stats == nil {
+			return nil, errs.IdempotencyConflictError(key, "the original request is still in progress")
+		}
+
+		return record.Stats, nil
+	}
+
+	return nil, errs.IdempotencyConflictError(key, "failed to claim the key")
+}
+
+// SaveIdempotencyKey keeps the stats of the submission with the claimed idempotency key
+// to be returned to the replayed submissions.
+func (gcwp *GoCraftWorkPool) SaveIdempotencyKey(key string, digest string, stats models.JobStats) error {
+	data, err := json.Marshal(&idempotencyRecord{Digest: digest, Stats: &stats})
+	if err != nil {
+		return err
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("SET", utils.KeyIdempotency(gcwp.namespace, key), data, "EX", config.GetIdempotencyKeyTTL())
+	return err
+}
+
+// ReleaseIdempotencyKey releases the claimed idempotency key if the submission is failed,
+// then the key can be used again.
+func (gcwp *GoCraftWorkPool) ReleaseIdempotencyKey(key string) error {
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", utils.KeyIdempotency(gcwp.namespace, key))
+	return err
+}
@@ -101,6 +101,42 @@ type Interface interface {
 	//  error           : error returned if meet any problems
 	GetJobStats(jobID string) (models.JobStats, error)
 
+	// Get the result set by the specified job
+	//
+	// jobID string : ID of the enqueued job
+	//
+	// Returns:
+	//  models.JobResult : job result data
+	//  error            : error returned if meet any problems
+	GetJobResult(jobID string) (models.JobResult, error)
+
+	// Get the check in history of the specified job
+	//
+	// jobID string : ID of the enqueued job
+	//
+	// Returns:
+	//  models.JobCheckIns : check in history, the latest one comes first
+	//  error              : error returned if meet any problems
+	GetJobCheckIns(jobID string) (models.JobCheckIns, error)
+
+	// Get the status timeline of the specified job
+	//
+	// jobID string : ID of the enqueued job
+	//
+	// Returns:
+	//  models.JobStatusTimeline : the accepted status transitions in order
+	//  error                    : error returned if meet any problems
+	GetJobTimeline(jobID string) (models.JobStatusTimeline, error)
+
+	// List the archived jobs which are already ended
+	//
+	// query models.JobQuery : the query conditions
+	//
+	// Returns:
+	//  models.JobList : the matched jobs
+	//  error          : error returned if meet any problems
+	ListJobs(query models.JobQuery) (models.JobList, error)
+
 	// Stop the job
 	//
 	// jobID string : ID of the enqueued job
@@ -117,6 +153,176 @@ type Interface interface {
 	//  error           : error returned if meet any problems
 	CancelJob(jobID string) error
 
+	// Update the job which is still pending or scheduled, the job ID is kept
+	//
+	// jobID string             : ID of the enqueued job
+	// params models.Parameters : the new parameters, nil for unchanged
+	// runAt int64              : the new time (unix seconds) to run the job, 0 for unchanged
+	//
+	// Return:
+	//  models.JobStats : the updated job stats
+	//  error           : error returned if meet any problems
+	UpdateJob(jobID string, params models.Parameters, runAt int64) (models.JobStats, error)
+
+	// List the upcoming runs of the scheduled jobs and the periodic job policies in the time window
+	//
+	// from int64 : the start of the time window (unix seconds)
+	// to int64   : the end of the time window (unix seconds)
+	//
+	// Returns:
+	//  models.JobSchedule : the upcoming runs, the earliest one comes first
+	//  error              : error returned if meet any problems
+	ListSchedule(from, to int64) (models.JobSchedule, error)
+
+	// List the jobs in the dead queue, the earliest died one comes first
+	//
+	// filter *models.DeadJobFilter : the optional filter of the dead jobs
+	// page uint                    : the page number starting from 1
+	// pageSize uint                : the page size
+	//
+	// Returns:
+	//  models.DeadJobList : the matched dead jobs of the requested page
+	//  error              : error returned if meet any problems
+	ListDeadJobs(filter *models.DeadJobFilter, page, pageSize uint) (models.DeadJobList, error)
+
+	// Retry the dead jobs selected by the IDs and/or the filter
+	//
+	// jobIDs []string              : the IDs of the dead jobs, empty for all the ones matching the filter
+	// filter *models.DeadJobFilter : the optional filter of the dead jobs
+	//
+	// Returns:
+	//  models.DeadJobsActionResult : the result of each selected job
+	//  error                       : error returned if meet any problems
+	RetryDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error)
+
+	// Delete the dead jobs selected by the IDs and/or the filter
+	//
+	// jobIDs []string              : the IDs of the dead jobs, empty for all the ones matching the filter
+	// filter *models.DeadJobFilter : the optional filter of the dead jobs
+	//
+	// Returns:
+	//  models.DeadJobsActionResult : the result of each selected job
+	//  error                       : error returned if meet any problems
+	DeleteDeadJobs(jobIDs []string, filter *models.DeadJobFilter) (models.DeadJobsActionResult, error)
+
+	// Get the status of the queues of all the registered job names
+	//
+	// Returns:
+	//  models.JobQueues : the pending, scheduled, retry and dead counts and the paused state of each job name
+	//  error            : error returned if meet any problems
+	Queues() (models.JobQueues, error)
+
+	// Pause the processing of the jobs with the name on all the nodes.
+	// The jobs can still be enqueued but are not picked up until resumed.
+	//
+	// jobName string : the name of the registered job
+	//
+	// Returns:
+	//  error : error returned if meet any problems
+	PauseQueue(jobName string) error
+
+	// Resume the processing of the jobs with the name
+	//
+	// jobName string : the name of the registered job
+	//
+	// Returns:
+	//  error : error returned if meet any problems
+	ResumeQueue(jobName string) error
+
+	// Enqueue the generic or scheduled job with the settings in the job data, like the follow-up jobs,
+	// the retry policy and the expiry time, the settings are kept in the same transaction which enqueues the job
+	//
+	// jd *models.JobData : the validated generic or scheduled job
+	//
+	// Returns:
+	//  models.JobStats: the stats of enqueuing job if succeed
+	//  error          : if failed to enqueue
+	EnqueueJob(jd *models.JobData) (models.JobStats, error)
+
+	// Enqueue the jobs in one transaction and track them as a batch
+	//
+	// jobs []*models.JobData : the validated generic or scheduled jobs
+	// hookURL string         : the optional hook fired when all the jobs are ended
+	//
+	// Returns:
+	//  models.JobBatch : the batch with the stats of the enqueued jobs
+	//  error           : error returned if meet any problems
+	EnqueueBatch(jobs []*models.JobData, hookURL string) (models.JobBatch, error)
+
+	// Get the aggregate status of the jobs submitted in the batch
+	//
+	// batchID string : ID of the batch
+	//
+	// Returns:
+	//  models.JobBatch : the job counts by status and the completion of the batch
+	//  error           : error returned if meet any problems
+	GetBatch(batchID string) (models.JobBatch, error)
+
+	// Enqueue the job to the backlog of the partition, the jobs with the same partition key
+	// are run one by one in the submission order
+	//
+	// jobName string           : the name of enqueuing job
+	// params models.Parameters : parameters of enqueuing job
+	// partitionKey string      : the partition key
+	//
+	// Returns:
+	//  models.JobStats: the stats of enqueuing job if succeed
+	//  error          : if failed to enqueue
+	EnqueuePartitioned(jobName string, params models.Parameters, partitionKey string) (models.JobStats, error)
+
+	// Get the in-flight job and the backlog of the partition
+	//
+	// partitionKey string : the partition key
+	// page uint           : the page number starting from 1
+	// pageSize uint       : the page size
+	//
+	// Returns:
+	//  models.JobPartition : the in-flight job and the jobs of the requested page in the backlog
+	//  error               : error returned if meet any problems
+	GetPartition(partitionKey string, page, pageSize uint) (models.JobPartition, error)
+
+	// Enqueue the child job of the running job
+	//
+	// parentID string          : ID of the parent job
+	// jobName string           : the name of enqueuing job
+	// params models.Parameters : parameters of enqueuing job
+	// runAfterSeconds uint64   : the delay (seconds), 0 for running immediately
+	// await bool               : whether the parent job waits for the child job
+	//
+	// Returns:
+	//  models.JobStats: the stats of enqueuing job if succeed
+	//  error          : if failed to enqueue
+	EnqueueChild(parentID string, jobName string, params models.Parameters, runAfterSeconds uint64, await bool) (models.JobStats, error)
+
+	// Claim the idempotency key for the job submission
+	//
+	// key string    : the idempotency key
+	// digest string : the digest of the submitted request
+	//
+	// Returns:
+	//  *models.JobStats : the stats of the original submission if the key has been used by the same request,
+	//                     nil if the key is claimed by this submission
+	//  error            : error returned if meet any problems, including the key conflict
+	ClaimIdempotencyKey(key string, digest string) (*models.JobStats, error)
+
+	// Save the stats of the submission with the claimed idempotency key
+	//
+	// key string            : the idempotency key
+	// digest string         : the digest of the submitted request
+	// stats models.JobStats : the stats of the launched job
+	//
+	// Returns:
+	//  error : error returned if meet any problems
+	SaveIdempotencyKey(key string, digest string, stats models.JobStats) error
+
+	// Release the claimed idempotency key if the submission is failed
+	//
+	// key string : the idempotency key
+	//
+	// Returns:
+	//  error : error returned if meet any problems
+	ReleaseIdempotencyKey(key string) error
+
 	// Retry the job
 	//
 	// jobID string : ID of the enqueued job
@@ -0,0 +1,268 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	// Default TTL of the locks
+	defaultLockTTL = 30 * time.Second
+	// Min TTL of the locks to make sure they can be renewed in time
+	minLockTTL = 3 * time.Second
+	// Interval of checking if the held locks need to be renewed
+	lockRenewInterval = 1 * time.Second
+)
+
+// acquireLockScript acquires the semaphore for the holder if the number of
+// the live holders is under the limit. The holders are kept in a sorted set
+// scored by their expiry time.
+//
+// KEYS[1]: key of the semaphore
+// ARGV[1]: the holder
+// ARGV[2]: the limit
+// ARGV[3]: the ttl (milliseconds)
+// ARGV[4]: the current time (unix milliseconds)
+//
+// Returns: 1 if acquired (or renewed if already held), otherwise 0
+var acquireLockScript = redis.NewScript(1, `
+local ttl = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) and redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+  return 0
+end
+
+redis.call('ZADD', KEYS[1], now + ttl, ARGV[1])
+if redis.call('PTTL', KEYS[1]) < ttl then
+  redis.call('PEXPIRE', KEYS[1], ttl)
+end
+
+return 1
+`)
+
+// renewLockScript extends the expiry time of the semaphore held by the holder.
+//
+// KEYS[1]: key of the semaphore
+// ARGV[1]: the holder
+// ARGV[2]: the ttl (milliseconds)
+// ARGV[3]: the current time (unix milliseconds)
+//
+// Returns: 1 if renewed, 0 if the semaphore is not held by the holder any more
+var renewLockScript = redis.NewScript(1, `
+local ttl = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local expiry = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not expiry or tonumber(expiry) <= now then
+  redis.call('ZREM', KEYS[1], ARGV[1])
+  return 0
+end
+
+redis.call('ZADD', KEYS[1], now + ttl, ARGV[1])
+if redis.call('PTTL', KEYS[1]) < ttl then
+  redis.call('PEXPIRE', KEYS[1], ttl)
+end
+
+return 1
+`)
+
+// heldLock keeps the info of the lock held by the job.
+type heldLock struct {
+	ttl       time.Duration
+	renewedAt time.Time
+}
+
+// jobLocks manages the named locks and semaphores held by one run of the job.
+// The held ones are renewed in background until released.
+type jobLocks struct {
+	namespace string
+	redisPool *redis.Pool
+	holder    string
+
+	lock    *sync.Mutex
+	held    map[string]*heldLock
+	stopped bool
+	stop    chan struct{} // stop renewing, nil if nothing held
+}
+
+// lockHolder returns the holder token of the locks acquired by the run of the job.
+// The executions of the periodic job share the same job ID, so the run is identified
+// by the enqueued time and a random nonce as well.
+func lockHolder(j *work.Job) string {
+	return fmt.Sprintf("%s@%d:%s", j.ID, j.EnqueuedAt, utils.MakeIdentifier())
+}
+
+// newJobLocks is constructor of jobLocks
+func newJobLocks(namespace string, redisPool *redis.Pool, holder string) *jobLocks {
+	return &jobLocks{
+		namespace: namespace,
+		redisPool: redisPool,
+		holder:    holder,
+		lock:      new(sync.Mutex),
+		held:      make(map[string]*heldLock),
+	}
+}
+
+// acquire tries to acquire the named semaphore allowing at most limit holders.
+func (jl *jobLocks) acquire(name string, limit uint, ttl time.Duration) (bool, error) {
+	if utils.IsEmptyStr(name) {
+		return false, errors.New("empty lock name")
+	}
+	if limit == 0 {
+		return false, errors.New("limit of the semaphore should be greater than 0")
+	}
+	if ttl == 0 {
+		ttl = defaultLockTTL
+	}
+	if ttl < minLockTTL {
+		return false, errors.New("ttl of the lock should not be less than 3s")
+	}
+
+	jl.lock.Lock()
+	defer jl.lock.Unlock()
+
+	if jl.stopped {
+		return false, errors.New("job is exited")
+	}
+
+	conn := jl.redisPool.Get()
+	defer conn.Close()
+
+	now := time.Now()
+	acquired, err := redis.Bool(acquireLockScript.Do(
+		conn,
+		utils.KeyLock(jl.namespace, name),
+		jl.holder,
+		limit,
+		int64(ttl/time.Millisecond),
+		now.UnixNano()/int64(time.Millisecond),
+	))
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	if jl.stop == nil {
+		jl.stop = make(chan struct{})
+		go jl.keepAlive(jl.stop)
+	}
+	jl.held[name] = &heldLock{
+		ttl:       ttl,
+		renewedAt: now,
+	}
+
+	return true, nil
+}
+
+// release releases the named semaphore held by the job.
+func (jl *jobLocks) release(name string) error {
+	jl.lock.Lock()
+	defer jl.lock.Unlock()
+
+	if _, ok := jl.held[name]; !ok {
+		return nil
+	}
+	delete(jl.held, name)
+	if len(jl.held) == 0 {
+		jl.stopKeepAlive()
+	}
+
+	conn := jl.redisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("ZREM", utils.KeyLock(jl.namespace, name), jl.holder)
+	return err
+}
+
+// releaseAll releases all the held locks when the job exits.
+func (jl *jobLocks) releaseAll() {
+	jl.lock.Lock()
+	defer jl.lock.Unlock()
+
+	jl.stopped = true
+	jl.stopKeepAlive()
+	if len(jl.held) == 0 {
+		return
+	}
+
+	conn := jl.redisPool.Get()
+	defer conn.Close()
+
+	for name := range jl.held {
+		if _, err := conn.Do("ZREM", utils.KeyLock(jl.namespace, name), jl.holder); err != nil {
+			logger.Errorf("Failed to release lock %s held by job %s: %s\n", name, jl.holder, err)
+		}
+	}
+	jl.held = make(map[string]*heldLock)
+}
+
+// keepAlive renews the held locks every third of their ttl until all of them are released.
+func (jl *jobLocks) keepAlive(stop chan struct{}) {
+	ticker := time.NewTicker(lockRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			jl.renew()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// stopKeepAlive stops renewing, the caller should hold the mutex.
+func (jl *jobLocks) stopKeepAlive() {
+	if jl.stop != nil {
+		close(jl.stop)
+		jl.stop = nil
+	}
+}
+
+func (jl *jobLocks) renew() {
+	jl.lock.Lock()
+	defer jl.lock.Unlock()
+
+	conn := jl.redisPool.Get()
+	defer conn.Close()
+
+	now := time.Now()
+	for name, l := range jl.held {
+		if now.Sub(l.renewedAt) < l.ttl/3 {
+			continue
+		}
+
+		renewed, err := redis.Bool(renewLockScript.Do(
+			conn,
+			utils.KeyLock(jl.namespace, name),
+			jl.holder,
+			int64(l.ttl/time.Millisecond),
+			now.UnixNano()/int64(time.Millisecond),
+		))
+		if err != nil {
+			// Try again next time
+			logger.Errorf("Failed to renew lock %s held by job %s: %s\n", name, jl.holder, err)
+			continue
+		}
+		if !renewed {
+			logger.Warningf("Lock %s held by job %s is expired\n", name, jl.holder)
+			delete(jl.held, name)
+			continue
+		}
+		l.renewedAt = now
+	}
+
+	if len(jl.held) == 0 {
+		jl.stopKeepAlive()
+	}
+}
@@ -60,7 +60,8 @@ func TestPublishPolicy(t *testing.T) {
 
 		rawJSON, err := json.Marshal(notification)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		conn := redisPool.Get()
@@ -68,25 +69,29 @@ func TestPublishPolicy(t *testing.T) {
 
 		err = conn.Send("PUBLISH", utils.KeyPeriodicNotification(tests.GiveMeTestNamespace()), rawJSON)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		notification.Event = period.EventUnSchedulePeriodicPolicy
 		rawJSON, err = json.Marshal(notification)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		err = conn.Send("PUBLISH", utils.KeyPeriodicNotification(tests.GiveMeTestNamespace()), rawJSON)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		// send quit signal
 		<-time.After(200 * time.Millisecond)
 		err = tests.Clear(utils.KeyPeriodicNotification(tests.GiveMeTestNamespace()), conn)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 	}()
 
@@ -121,7 +126,8 @@ func TestPublishHook(t *testing.T) {
 
 		rawJSON, err := json.Marshal(notification)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		conn := redisPool.Get()
@@ -129,7 +135,8 @@ func TestPublishHook(t *testing.T) {
 
 		err = conn.Send("PUBLISH", utils.KeyPeriodicNotification(tests.GiveMeTestNamespace()), rawJSON)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		// send quit signal
@@ -137,7 +144,8 @@ func TestPublishHook(t *testing.T) {
 
 		err = tests.Clear(utils.KeyPeriodicNotification(tests.GiveMeTestNamespace()), conn)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 	}()
 
@@ -181,7 +189,8 @@ func TestPublishCommands(t *testing.T) {
 
 		rawJSON, err := json.Marshal(notification)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		conn := redisPool.Get()
@@ -189,7 +198,8 @@ func TestPublishCommands(t *testing.T) {
 
 		err = conn.Send("PUBLISH", utils.KeyPeriodicNotification(tests.GiveMeTestNamespace()), rawJSON)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 
 		// hold for a while
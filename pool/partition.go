@@ -0,0 +1,203 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	defaultPartitionPageSize = 20
+	maxPartitionPageSize     = 100
+)
+
+// dispatchPartitionScript moves the first job of the partition backlog to the pending job list
+// if no job of the partition is in flight.
+// The jobs of the partition may have different names, so the pending job list is decided
+// by the name of the dispatched job.
+//
+// KEYS[1]: key of the partition backlog
+// KEYS[2]: key of the in-flight job ID of the partition
+// ARGV[1]: prefix of the keys of the pending job lists
+// ARGV[2]: the job ID releasing the partition, empty if not releasing
+// ARGV[3]: the raw json of the job appended to the backlog, empty if not appending
+//
+// Returns: the ID of the dispatched job or nil if nothing dispatched
+var dispatchPartitionScript = redis.NewScript(2, `
+if ARGV[3] ~= '' then
+  redis.call('RPUSH', KEYS[1], ARGV[3])
+end
+
+if ARGV[2] ~= '' then
+  if redis.call('GET', KEYS[2]) ~= ARGV[2] then
+    return false
+  end
+  redis.call('DEL', KEYS[2])
+end
+
+if redis.call('EXISTS', KEYS[2]) == 1 then
+  return false
+end
+
+local raw = redis.call('LPOP', KEYS[1])
+if not raw then
+  return false
+end
+
+local j = cjson.decode(raw)
+redis.call('LPUSH', ARGV[1] .. j['name'], raw)
+redis.call('SET', KEYS[2], j['id'])
+
+return j['id']
+`)
+
+// EnqueuePartitioned enqueues the job to the backlog of the partition key.
+// The jobs with the same partition key are run one by one in the submission order.
+func (gcwp *GoCraftWorkPool) EnqueuePartitioned(jobName string, params models.Parameters, partitionKey string) (models.JobStats, error) {
+	if utils.IsEmptyStr(partitionKey) {
+		return models.JobStats{}, errors.New("empty partition key")
+	}
+
+	return gcwp.EnqueueJob(&models.JobData{
+		Name:       jobName,
+		Parameters: params,
+		Metadata: &models.JobMetadata{
+			JobKind:      job.JobKindGeneric,
+			PartitionKey: partitionKey,
+		},
+	})
+}
+
+// GetPartition returns the in-flight job and the backlog of the partition key.
+func (gcwp *GoCraftWorkPool) GetPartition(partitionKey string, page, pageSize uint) (models.JobPartition, error) {
+	if utils.IsEmptyStr(partitionKey) {
+		return models.JobPartition{}, errors.New("empty partition key")
+	}
+
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = defaultPartitionPageSize
+	}
+	if pageSize > maxPartitionPageSize {
+		pageSize = maxPartitionPageSize
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	backlogKey := utils.KeyPartition(gcwp.namespace, partitionKey)
+	offset := int64((page - 1) * pageSize)
+
+	conn.Send("GET", utils.KeyPartitionRunning(gcwp.namespace, partitionKey))
+	conn.Send("LLEN", backlogKey)
+	conn.Send("LRANGE", backlogKey, offset, offset+int64(pageSize)-1)
+	if err := conn.Flush(); err != nil {
+		return models.JobPartition{}, err
+	}
+
+	running, err := redis.String(conn.Receive())
+	if err != nil && err != redis.ErrNil {
+		return models.JobPartition{}, err
+	}
+	total, err := redis.Int64(conn.Receive())
+	if err != nil {
+		return models.JobPartition{}, err
+	}
+	items, err := redis.ByteSlices(conn.Receive())
+	if err != nil {
+		return models.JobPartition{}, err
+	}
+
+	if utils.IsEmptyStr(running) && total == 0 {
+		return models.JobPartition{}, errs.NoObjectFoundError(fmt.Sprintf("partition '%s'", partitionKey))
+	}
+
+	partition := models.JobPartition{
+		PartitionKey: partitionKey,
+		Running:      running,
+		Total:        total,
+		Jobs:         make([]*models.PartitionedJob, 0, len(items)),
+	}
+	for _, raw := range items {
+		j := &work.Job{}
+		if err := json.Unmarshal(raw, j); err != nil {
+			logger.Errorf("Failed to decode job in backlog of partition %s: %s\n", partitionKey, err)
+			continue
+		}
+
+		partition.Jobs = append(partition.Jobs, &models.PartitionedJob{
+			JobID:       j.ID,
+			JobName:     j.Name,
+			EnqueueTime: j.EnqueuedAt,
+		})
+	}
+
+	return partition, nil
+}
+
+// releasePartition releases the partition held by the ended job and dispatches the next job of the partition.
+func (gcwp *GoCraftWorkPool) releasePartition(partitionKey string, jobID string) error {
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	return dispatchPartition(conn, gcwp.namespace, partitionKey, jobID)
+}
+
+// dispatchPartition releases the partition if it's held by the releasing job
+// and dispatches the next job if the partition is free.
+func dispatchPartition(conn redis.Conn, namespace string, partitionKey string, releasedBy string) error {
+	reply, err := dispatchPartitionScript.Do(
+		conn,
+		utils.KeyPartition(namespace, partitionKey),
+		utils.KeyPartitionRunning(namespace, partitionKey),
+		utils.KeyJobs(namespace, ""),
+		releasedBy,
+		"",
+	)
+	if err != nil {
+		return err
+	}
+
+	return partitionDispatched(partitionKey, reply)
+}
+
+// sendDispatchPartition sends the command appending the job to the partition backlog
+// and dispatching the first job if the partition is free to the transaction which enqueues the job.
+// The reply is checked with partitionDispatched.
+func sendDispatchPartition(conn redis.Conn, namespace string, partitionKey string, rawJSON []byte) error {
+	return dispatchPartitionScript.Send(
+		conn,
+		utils.KeyPartition(namespace, partitionKey),
+		utils.KeyPartitionRunning(namespace, partitionKey),
+		utils.KeyJobs(namespace, ""),
+		"",
+		rawJSON,
+	)
+}
+
+// partitionDispatched checks the reply of dispatching the partition.
+func partitionDispatched(partitionKey string, reply interface{}) error {
+	jobID, err := redis.String(reply, nil)
+	if err == redis.ErrNil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("Job %s of partition %s is dispatched\n", jobID, partitionKey)
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// The batch size of scanning the job sets
+const queueScanCount = 1000
+
+// Queues returns the status of the queues of all the registered job names.
+func (gcwp *GoCraftWorkPool) Queues() (models.JobQueues, error) {
+	result := models.JobQueues{
+		Queues: make([]*models.JobQueue, 0),
+	}
+
+	pendingQueues, err := gcwp.client.Queues()
+	if err != nil {
+		return result, err
+	}
+
+	queues := make(map[string]*models.JobQueue)
+	for _, q := range pendingQueues {
+		queues[q.JobName] = &models.JobQueue{
+			JobName: q.JobName,
+			Pending: q.Count,
+			Latency: q.Latency,
+		}
+	}
+
+	// The jobs registered to this pool but not yet known by the backend
+	for name := range gcwp.knownJobs {
+		if _, ok := queues[name]; !ok {
+			queues[name] = &models.JobQueue{
+				JobName: name,
+			}
+		}
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	sets := []struct {
+		key   string
+		count func(q *models.JobQueue, n int64)
+	}{
+		{utils.KeyScheduled(gcwp.namespace), func(q *models.JobQueue, n int64) { q.Scheduled = n }},
+		{utils.KeyRetry(gcwp.namespace), func(q *models.JobQueue, n int64) { q.Retry = n }},
+		{utils.KeyDead(gcwp.namespace), func(q *models.JobQueue, n int64) { q.Dead = n }},
+	}
+	for _, set := range sets {
+		counts, err := countJobsByName(conn, set.key)
+		if err != nil {
+			return result, err
+		}
+
+		for name, n := range counts {
+			q, ok := queues[name]
+			if !ok {
+				// The jobs left by the job names which are not registered any more
+				q = &models.JobQueue{
+					JobName: name,
+				}
+				queues[name] = q
+			}
+			set.count(q, n)
+		}
+	}
+
+	for _, q := range queues {
+		result.Queues = append(result.Queues, q)
+	}
+	sort.Slice(result.Queues, func(i, j int) bool {
+		return result.Queues[i].JobName < result.Queues[j].JobName
+	})
+
+	names := make([]string, 0, len(result.Queues))
+	for _, q := range result.Queues {
+		names = append(names, q.JobName)
+	}
+	paused, err := gcwp.pausedJobNames(conn, names)
+	if err != nil {
+		return result, err
+	}
+	for _, q := range result.Queues {
+		q.Paused = paused[q.JobName]
+	}
+
+	if err := gcwp.fillConcurrency(conn, result.Queues); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// PauseQueue pauses the processing of the jobs with the name.
+// The paused flag is persisted in the backend and respected by the job fetching of all the nodes.
+func (gcwp *GoCraftWorkPool) PauseQueue(jobName string) error {
+	if _, ok := gcwp.IsKnownJob(jobName); !ok {
+		return errs.NoObjectFoundError(jobName)
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", utils.KeyJobsPaused(gcwp.namespace, jobName), "1")
+	return err
+}
+
+// ResumeQueue resumes the processing of the jobs with the name.
+func (gcwp *GoCraftWorkPool) ResumeQueue(jobName string) error {
+	if _, ok := gcwp.IsKnownJob(jobName); !ok {
+		return errs.NoObjectFoundError(jobName)
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", utils.KeyJobsPaused(gcwp.namespace, jobName))
+	return err
+}
+
+// pausedJobNames checks the paused flags of the job names in one round trip.
+func (gcwp *GoCraftWorkPool) pausedJobNames(conn redis.Conn, names []string) (map[string]bool, error) {
+	paused := make(map[string]bool, len(names))
+	if len(names) == 0 {
+		return paused, nil
+	}
+
+	for _, name := range names {
+		if err := conn.Send("EXISTS", utils.KeyJobsPaused(gcwp.namespace, name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		exists, err := redis.Bool(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		paused[name] = exists
+	}
+
+	return paused, nil
+}
+
+// fillConcurrency fills the running count and the max concurrency of the queues in one round trip.
+func (gcwp *GoCraftWorkPool) fillConcurrency(conn redis.Conn, queues []*models.JobQueue) error {
+	if len(queues) == 0 {
+		return nil
+	}
+
+	for _, q := range queues {
+		if err := conn.Send("GET", utils.KeyJobsLock(gcwp.namespace, q.JobName)); err != nil {
+			return err
+		}
+		if err := conn.Send("GET", utils.KeyJobsMaxConcurrency(gcwp.namespace, q.JobName)); err != nil {
+			return err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	for _, q := range queues {
+		running, err := redis.Int64(conn.Receive())
+		if err != nil && err != redis.ErrNil {
+			return err
+		}
+		max, err := redis.Int64(conn.Receive())
+		if err != nil && err != redis.ErrNil {
+			return err
+		}
+
+		// The count may be negative shortly after the stale locks are reaped
+		if running > 0 {
+			q.Running = running
+		}
+		q.MaxConcurrency = max
+	}
+
+	return nil
+}
+
+// countJobsByName scans the job set and counts the jobs by the job name.
+func countJobsByName(conn redis.Conn, key string) (map[string]int64, error) {
+	counts := make(map[string]int64)
+
+	cursor := int64(0)
+	for {
+		values, err := redis.Values(conn.Do("ZSCAN", key, cursor, "COUNT", queueScanCount))
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, redis.Error("unexpected reply of ZSCAN")
+		}
+
+		if cursor, err = redis.Int64(values[0], nil); err != nil {
+			return nil, err
+		}
+		members, err := redis.Values(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// The members come with the scores
+		for i := 0; i < len(members); i += 2 {
+			data, err := redis.Bytes(members[i], nil)
+			if err != nil {
+				return nil, err
+			}
+
+			j := struct {
+				Name string `json:"name"`
+			}{}
+			if err := json.Unmarshal(data, &j); err != nil {
+				// Skip the malformed ones
+				continue
+			}
+			counts[j.Name]++
+		}
+
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,96 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/config"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// tokenBucketScript takes one token from the bucket refilled at the rate.
+//
+// KEYS[1]: key of the bucket
+// ARGV[1]: number of the tokens refilled in the period
+// ARGV[2]: the period (milliseconds)
+// ARGV[3]: capacity of the bucket
+// ARGV[4]: the current time (unix milliseconds)
+//
+// Returns: 0 if the token is taken, otherwise the milliseconds to wait for the next token
+var tokenBucketScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+
+local tokens = burst
+local ts = now
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+if bucket[1] and bucket[2] then
+  tokens = tonumber(bucket[1])
+  ts = tonumber(bucket[2])
+  if now > ts then
+    tokens = math.min(burst, tokens + (now - ts) * rate / period)
+    ts = now
+  end
+end
+
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+else
+  wait = math.ceil((1 - tokens) * period / rate)
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * period / rate) + period)
+
+return wait
+`)
+
+// rateLimiter limits the starts of the jobs of the same name across all the pools
+// with the token bucket kept in redis.
+type rateLimiter struct {
+	namespace string
+	redisPool *redis.Pool
+	jobName   string
+	rate      uint
+	period    time.Duration
+	burst     uint
+}
+
+// newRateLimiter is constructor of rateLimiter
+func newRateLimiter(namespace string, redisPool *redis.Pool, jobName string, limit *config.RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		namespace: namespace,
+		redisPool: redisPool,
+		jobName:   jobName,
+		rate:      limit.Rate,
+		period:    time.Duration(limit.Period) * time.Second,
+		burst:     limit.Burst,
+	}
+}
+
+// take tries to take a token for starting the job and returns the duration to wait
+// for the next token if no token is available.
+func (rl *rateLimiter) take() (time.Duration, error) {
+	conn := rl.redisPool.Get()
+	defer conn.Close()
+
+	wait, err := redis.Int64(tokenBucketScript.Do(
+		conn,
+		utils.KeyJobsRateLimit(rl.namespace, rl.jobName),
+		rl.rate,
+		int64(rl.period/time.Millisecond),
+		rl.burst,
+		time.Now().UnixNano()/int64(time.Millisecond),
+	))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
@@ -3,45 +3,83 @@
 package pool
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
 
 	"github.com/Colstuwjx/job/env"
 	"github.com/Colstuwjx/job/errs"
 	"github.com/Colstuwjx/job/impl/job"
 	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
 	"github.com/Colstuwjx/job/opm"
+	"github.com/Colstuwjx/job/utils"
 )
 
+// Default delay of the job throttled by the services it depends on
+const defaultThrottledDelay = 1 * time.Minute
+
 // RedisJob is a job wrapper to wrap the job.Interface to the style which can be recognized by the redis pool.
 type RedisJob struct {
 	job          interface{}         // the real job implementation
 	context      *env.Context        // context
 	statsManager opm.JobStatsManager // job stats manager
+	namespace    string              // namespace of the locks
+	redisPool    *redis.Pool         // redis pool of the locks
+	limiter      *rateLimiter        // limit the starts of the job if configured
+	backendPool  Interface           // enqueue the child jobs
+	tracker      *jobTracker         // track the ended job
+	policy       *models.RetryPolicy // retry policy of the job type
+	retryDelays  sync.Map            // the seconds to wait before retrying the failed jobs, keyed by job ID
 }
 
 // NewRedisJob is constructor of RedisJob
-func NewRedisJob(j interface{}, ctx *env.Context, statsManager opm.JobStatsManager) *RedisJob {
+func NewRedisJob(j interface{}, ctx *env.Context, statsManager opm.JobStatsManager, namespace string, redisPool *redis.Pool) *RedisJob {
 	return &RedisJob{
 		job:          j,
 		context:      ctx,
 		statsManager: statsManager,
+		namespace:    namespace,
+		redisPool:    redisPool,
 	}
 }
 
 // Run the job
-func (rj *RedisJob) Run(j *work.Job) error {
+func (rj *RedisJob) Run(j *work.Job) (runErr error) {
 	var (
 		cancelled          = false
 		buildContextFailed = false
+		delayed            = false
+		withdrawn          = false
+		waiting            = false
+		dead               = false
+		status             = ""
 		runningJob         job.Interface
 		err                error
 		execContext        env.JobContext
 	)
 
 	defer func() {
+		// The job is not going to run again, dispatch the next job of the partition,
+		// let the parent job know if it's awaited and enqueue the follow-up jobs
+		if delayed || withdrawn || (runErr != nil && !dead) {
+			return
+		}
+		rj.releasePartition(j)
+		if !waiting && rj.tracker != nil {
+			rj.tracker.ended(j.ID, status)
+		}
+	}()
+
+	defer func() {
+		if delayed || withdrawn || status == job.JobStatusExpired {
+			return // the job is not run
+		}
+
 		if err == nil {
 			logger.Infof("Job '%s:%s' exit with success", j.Name, j.ID)
 			return // nothing need to do
@@ -50,7 +88,11 @@ func (rj *RedisJob) Run(j *work.Job) error {
 		// log error
 		logger.Errorf("Job '%s:%s' exit with error: %s\n", j.Name, j.ID, err)
 
-		if buildContextFailed || rj.shouldDisableRetry(runningJob, j, cancelled) {
+		policy, requested := rj.retryPolicy(j.ID)
+		if buildContextFailed ||
+			rj.shouldDisableRetry(runningJob, j, cancelled, err, policy, requested) ||
+			(runErr != nil && rj.willDie(runningJob, j, policy)) {
+			dead = true
 			j.Fails = 10000000000 // Make it big enough to avoid retrying
 			now := time.Now().Unix()
 			go func() {
@@ -61,21 +103,39 @@ func (rj *RedisJob) Run(j *work.Job) error {
 
 				rj.statsManager.DieAt(j.ID, now)
 			}()
+
+			return
+		}
+
+		if runErr != nil {
+			rj.scheduleRetry(j, err, policy)
 		}
 	}()
 
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("Runtime error: %s", r)
+			runErr = err // let the backend pool know the job is failed
+			status = job.JobStatusError
 			// record runtime error status
-			rj.jobFailed(j.ID)
+			rj.jobFailed(j.ID, err)
 		}
 	}()
 
+	// Drop the withdrawn execution of the periodic job which may be put back by the periodic enqueuer
+	if withdrawn = rj.withdrawn(j); withdrawn {
+		logger.Infof("Job '%s:%s' scheduled at %d is withdrawn\n", j.Name, j.ID, j.EnqueuedAt)
+		return nil
+	}
+
 	// Wrap job
 	runningJob = Wrap(rj.job)
 
-	execContext, err = rj.buildContext(j)
+	// The locks acquired by the job are released when it exits even if panic
+	locks := newJobLocks(rj.namespace, rj.redisPool, lockHolder(j))
+	defer locks.releaseAll()
+
+	execContext, err = rj.buildContext(j, locks)
 	if err != nil {
 		buildContextFailed = true
 		goto FAILED // no need to retry
@@ -88,39 +148,217 @@ func (rj *RedisJob) Run(j *work.Job) error {
 		}
 	}()
 
+	// The job may be stopped or cancelled after it's fetched from the queue
+	if cmd, e := rj.statsManager.CtlCommand(j.ID); e == nil {
+		switch cmd {
+		case opm.CtlCommandStop:
+			rj.jobStopped(j.ID)
+			status = job.JobStatusStopped
+			return nil
+		case opm.CtlCommandCancel:
+			err = errs.JobCancelledError()
+			rj.jobCancelled(j.ID)
+			cancelled = true
+			status = job.JobStatusCancelled
+			return err
+		}
+	}
+
+	// Skip the stale job which has not started before the expiry time, the retries are not expired
+	if j.Fails == 0 && rj.expired(j) {
+		status = job.JobStatusExpired
+		return nil
+	}
+
+	// Delay the job if the rate limit is exceeded, it's not counted as a failure
+	if delayed = rj.rateLimited(j); delayed {
+		return nil
+	}
+
 	// Start to run
 	rj.jobRunning(j.ID)
+	if j.Fails > 0 {
+		// The retry is started
+		if e := rj.statsManager.SetNextRetryAt(j.ID, 0); e != nil {
+			logger.Errorf("Failed to clear the next retry time of job '%s:%s': %s\n", j.Name, j.ID, e)
+		}
+	}
 
 	// Inject data
 	err = runningJob.Run(execContext, j.Args)
 
 	// update the proper status
 	if err == nil {
-		rj.jobSucceed(j.ID)
+		// The checkpoints are useless after the job succeeds
+		if e := rj.statsManager.ClearCheckpoints(j.ID); e != nil {
+			logger.Errorf("Failed to clear checkpoints of job '%s:%s': %s\n", j.Name, j.ID, e)
+		}
+
+		waiting, status = rj.jobDone(j.ID)
 		return nil
 	}
 
 	if errs.IsJobStoppedError(err) {
 		rj.jobStopped(j.ID)
+		status = job.JobStatusStopped
 		return nil // no need to put it into the dead queue for resume
 	}
 
 	if errs.IsJobCancelledError(err) {
 		rj.jobCancelled(j.ID)
 		cancelled = true
+		status = job.JobStatusCancelled
 		return err // need to resume
 	}
 
+	// Run the throttled job later, it's not counted as a failure
+	if errs.IsRateLimitedError(err) {
+		if delayed = rj.throttled(j, err); delayed {
+			return nil
+		}
+	}
+
 FAILED:
-	rj.jobFailed(j.ID)
+	rj.jobFailed(j.ID, err)
+	status = job.JobStatusError
 	return err
 }
 
+// rateLimited checks the rate limit of the job and puts the job back to the scheduled set
+// if the limit is exceeded. The job is run if the limit can not be checked.
+func (rj *RedisJob) rateLimited(j *work.Job) bool {
+	if rj.limiter == nil {
+		return false
+	}
+
+	wait, err := rj.limiter.take()
+	if err != nil {
+		logger.Errorf("Failed to check rate limit of job '%s:%s': %s\n", j.Name, j.ID, err)
+		return false
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	runAt, err := rj.delay(j, wait)
+	if err != nil {
+		logger.Errorf("Failed to delay rate limited job '%s:%s': %s\n", j.Name, j.ID, err)
+		return false
+	}
+	logger.Infof("Job '%s:%s' is rate limited and delayed to run at %d\n", j.Name, j.ID, runAt)
+
+	return true
+}
+
+// throttled puts the job throttled by the services it depends on back to the scheduled set.
+// The job fails as usual if it can not be delayed.
+func (rj *RedisJob) throttled(j *work.Job, jobErr error) bool {
+	wait, _ := errs.RetryDelay(jobErr)
+	if wait <= 0 {
+		wait = defaultThrottledDelay
+	}
+
+	// Keep the reason of the delay
+	if err := rj.statsManager.SetLastError(j.ID, jobErr.Error()); err != nil {
+		logger.Errorf("Failed to record the error of job %s: %s\n", j.ID, err)
+	}
+
+	runAt, err := rj.delay(j, wait)
+	if err != nil {
+		logger.Errorf("Failed to delay throttled job '%s:%s': %s\n", j.Name, j.ID, err)
+		return false
+	}
+	logger.Infof("Job '%s:%s' is throttled and delayed to run at %d\n", j.Name, j.ID, runAt)
+
+	return true
+}
+
+// delay puts the job back to the scheduled set to run after the wait duration
+// and returns the time it's scheduled to run at.
+// The fails of the job are kept, and the unique job holds the unique key again
+// as it's released when the job is fetched.
+func (rj *RedisJob) delay(j *work.Job, wait time.Duration) (int64, error) {
+	rawJSON, err := utils.SerializeJob(j)
+	if err != nil {
+		return 0, err
+	}
+
+	// The scheduled set is checked with seconds
+	runAt := time.Now().Add(wait + time.Second - 1).Unix()
+
+	conn := rj.redisPool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("ZADD", utils.KeyScheduled(rj.namespace), runAt, rawJSON)
+	if j.Unique && !utils.IsEmptyStr(j.UniqueKey) {
+		// Consistent with the unique job enqueued in
+		conn.Send("SET", j.UniqueKey, "1", "EX", 86400)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return 0, err
+	}
+
+	if err := rj.statsManager.SetRunAt(j.ID, runAt); err != nil {
+		logger.Errorf("Failed to update run time of delayed job '%s:%s': %s\n", j.Name, j.ID, err)
+	}
+	rj.statsManager.SetJobStatus(j.ID, job.JobStatusScheduled)
+
+	return runAt, nil
+}
+
+// releasePartition releases the partition held by the job if it has the partition key.
+func (rj *RedisJob) releasePartition(j *work.Job) {
+	conn := rj.redisPool.Get()
+	defer conn.Close()
+
+	partitionKey, err := redis.String(conn.Do("HGET", utils.KeyJobStats(rj.namespace, j.ID), "partition_key"))
+	if err != nil || utils.IsEmptyStr(partitionKey) {
+		return
+	}
+
+	if err := dispatchPartition(conn, rj.namespace, partitionKey, j.ID); err != nil {
+		logger.Errorf("Failed to release partition %s held by job '%s:%s': %s\n", partitionKey, j.Name, j.ID, err)
+	}
+}
+
+// jobDone succeeds the job which returns without error unless it's awaiting the child jobs.
+// Returns if the job is awaiting the child jobs and the terminal status of the job.
+func (rj *RedisJob) jobDone(jobID string) (bool, string) {
+	conn := rj.redisPool.Get()
+	defer conn.Close()
+
+	waiting, failedChild, err := awaitChildren(conn, rj.namespace, jobID)
+	if err != nil {
+		logger.Errorf("Failed to check child jobs of job %s: %s\n", jobID, err)
+		rj.jobSucceed(jobID)
+		return false, job.JobStatusSuccess
+	}
+
+	if waiting {
+		logger.Infof("Job %s is waiting for the child jobs\n", jobID)
+		return true, ""
+	}
+
+	if !utils.IsEmptyStr(failedChild) {
+		rj.jobFailed(jobID, fmt.Errorf("child job %s is not succeeded", failedChild))
+		return false, job.JobStatusError
+	}
+
+	rj.jobSucceed(jobID)
+	return false, job.JobStatusSuccess
+}
+
 func (rj *RedisJob) jobRunning(jobID string) {
 	rj.statsManager.SetJobStatus(jobID, job.JobStatusRunning)
 }
 
-func (rj *RedisJob) jobFailed(jobID string) {
+func (rj *RedisJob) jobFailed(jobID string, err error) {
+	// Keep the failure details before the status is changed
+	if e := rj.statsManager.SetLastError(jobID, err.Error()); e != nil {
+		logger.Errorf("Failed to record the error of job %s: %s\n", jobID, e)
+	}
+
 	rj.statsManager.SetJobStatus(jobID, job.JobStatusError)
 }
 
@@ -136,7 +374,7 @@ func (rj *RedisJob) jobSucceed(jobID string) {
 	rj.statsManager.SetJobStatus(jobID, job.JobStatusSuccess)
 }
 
-func (rj *RedisJob) buildContext(j *work.Job) (env.JobContext, error) {
+func (rj *RedisJob) buildContext(j *work.Job, locks *jobLocks) (env.JobContext, error) {
 	// Build job execution context
 	jData := env.JobData{
 		ID:        j.ID,
@@ -165,23 +403,102 @@ func (rj *RedisJob) buildContext(j *work.Job) (env.JobContext, error) {
 
 	jData.ExtraData["checkInFunc"] = checkInFuncFactory(j.ID)
 
+	resultFuncFactory := func(jobID string) job.ResultFunc {
+		return func(result interface{}) error {
+			return rj.statsManager.SetResult(jobID, result)
+		}
+	}
+
+	jData.ExtraData["resultFunc"] = resultFuncFactory(j.ID)
+
+	progressFuncFactory := func(jobID string) job.ProgressFunc {
+		return func(current, total uint64, phase, message string) error {
+			return rj.statsManager.ReportProgress(jobID, current, total, phase, message)
+		}
+	}
+
+	jData.ExtraData["progressFunc"] = progressFuncFactory(j.ID)
+
+	saveCheckpointFuncFactory := func(jobID string) job.SaveCheckpointFunc {
+		return func(key string, value interface{}) error {
+			return rj.statsManager.SaveCheckpoint(jobID, key, value)
+		}
+	}
+
+	jData.ExtraData["saveCheckpointFunc"] = saveCheckpointFuncFactory(j.ID)
+
+	loadCheckpointFuncFactory := func(jobID string) job.LoadCheckpointFunc {
+		return func(key string) ([]byte, error) {
+			data, err := rj.statsManager.GetCheckpoint(jobID, key)
+			if err != nil {
+				if errs.IsObjectNotFoundError(err) {
+					return nil, nil
+				}
+				return nil, err
+			}
+			return data, nil
+		}
+	}
+
+	jData.ExtraData["loadCheckpointFunc"] = loadCheckpointFuncFactory(j.ID)
+
+	enqueueFuncFactory := func(jobID string) job.EnqueueFunc {
+		return func(jobName string, params map[string]interface{}, runAfterSeconds uint64, await bool) (string, error) {
+			if rj.backendPool == nil {
+				return "", errors.New("no backend pool to enqueue the child job")
+			}
+
+			theJob, ok := rj.backendPool.IsKnownJob(jobName)
+			if !ok {
+				return "", fmt.Errorf("job with name '%s' is unknown", jobName)
+			}
+			if err := rj.backendPool.ValidateJobParameters(theJob, params); err != nil {
+				return "", err
+			}
+
+			res, err := rj.backendPool.EnqueueChild(jobID, jobName, params, runAfterSeconds, await)
+			if err != nil {
+				return "", err
+			}
+
+			return res.Stats.JobID, nil
+		}
+	}
+
+	jData.ExtraData["enqueueFunc"] = enqueueFuncFactory(j.ID)
+
+	jData.ExtraData["acquireLockFunc"] = job.AcquireLockFunc(locks.acquire)
+	jData.ExtraData["releaseLockFunc"] = job.ReleaseLockFunc(locks.release)
+
 	return rj.context.JobContext.Build(jData)
 }
 
-func (rj *RedisJob) shouldDisableRetry(j job.Interface, wj *work.Job, cancelled bool) bool {
-	maxFails := j.MaxFails()
-	if maxFails == 0 {
-		maxFails = 4 // Consistent with backend worker pool
+// willDie tells if the failed job is put into the dead queue instead of being retried.
+func (rj *RedisJob) willDie(j job.Interface, wj *work.Job, policy *models.RetryPolicy) bool {
+	if j == nil {
+		return true
+	}
+
+	return wj.Fails+1 >= maxFails(j, policy)
+}
+
+func (rj *RedisJob) shouldDisableRetry(j job.Interface, wj *work.Job, cancelled bool, err error, policy *models.RetryPolicy, requested bool) bool {
+	// The permanent failure can not be fixed by retrying
+	if errs.IsPermanentError(err) {
+		return true
 	}
 
+	limit := maxFails(j, policy)
 	fails := wj.Fails
 	fails++ // as the fail is not returned to backend pool yet
 
-	if cancelled && fails < int64(maxFails) {
+	if cancelled && fails < limit {
 		return true
 	}
 
-	if !cancelled && fails < int64(maxFails) && !j.ShouldRetry() {
+	// The job asking to retry later or with the retry policy of the request is retried
+	// even if it does not retry in general
+	if !cancelled && fails < limit && !requested && !j.ShouldRetry() && !errs.IsRetryAfterError(err) {
 		return true
 	}
 
@@ -3,6 +3,7 @@
 package pool
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -12,6 +13,7 @@ import (
 	"github.com/gomodule/redigo/redis"
 	"github.com/robfig/cron"
 
+	"github.com/Colstuwjx/job/config"
 	"github.com/Colstuwjx/job/env"
 	"github.com/Colstuwjx/job/impl/job"
 	"github.com/Colstuwjx/job/logger"
@@ -47,6 +49,7 @@ type GoCraftWorkPool struct {
 	scheduler     period.Interface
 	statsManager  opm.JobStatsManager
 	messageServer *MessageServer
+	tracker       *jobTracker
 
 	// no need to sync as write once and then only read
 	// key is name of known job
@@ -68,7 +71,7 @@ func NewGoCraftWorkPool(ctx *env.Context, namespace string, workerCount uint, re
 	statsMgr := opm.NewRedisJobStatsManager(ctx.SystemContext, namespace, redisPool)
 	msgServer := NewMessageServer(ctx.SystemContext, namespace, redisPool)
 
-	return &GoCraftWorkPool{
+	gcwp := &GoCraftWorkPool{
 		namespace:     namespace,
 		redisPool:     redisPool,
 		pool:          pool,
@@ -81,6 +84,9 @@ func NewGoCraftWorkPool(ctx *env.Context, namespace string, workerCount uint, re
 		knownJobs:     make(map[string]interface{}),
 		messageServer: msgServer,
 	}
+	gcwp.tracker = newJobTracker(namespace, redisPool, statsMgr, gcwp)
+
+	return gcwp
 }
 
 // Start to serve
@@ -216,13 +222,25 @@ func (gcwp *GoCraftWorkPool) RegisterJob(name string, j interface{}) error {
 		return errors.New("job must implement the job.Interface")
 	}
 
-	redisJob := NewRedisJob(j, gcwp.context, gcwp.statsManager)
+	redisJob := NewRedisJob(j, gcwp.context, gcwp.statsManager, gcwp.namespace, gcwp.redisPool)
+	redisJob.backendPool = gcwp
+	redisJob.tracker = gcwp.tracker
+	if limit, ok := config.GetRateLimit(name); ok {
+		redisJob.limiter = newRateLimiter(gcwp.namespace, gcwp.redisPool, name, limit)
+	}
 
 	// Get more info from j
 	theJ := Wrap(j)
+	if retrier, ok := theJ.(job.Retrier); ok {
+		redisJob.policy = retrier.RetryPolicy()
+	}
 
 	gcwp.pool.JobWithOptions(name,
-		work.JobOptions{MaxFails: theJ.MaxFails()},
+		work.JobOptions{
+			MaxFails:       unlimitedFails,
+			MaxConcurrency: maxConcurrency(name, theJ),
+			Backoff:        redisJob.backoff,
+		},
 		func(job *work.Job) error {
 			return redisJob.Run(job)
 		}, // Use generic handler to handle as we do not accept context with this way.
@@ -346,6 +364,38 @@ func (gcwp *GoCraftWorkPool) GetJobStats(jobID string) (models.JobStats, error)
 	return gcwp.statsManager.Retrieve(jobID)
 }
 
+// GetJobResult return the result set by the specified job.
+func (gcwp *GoCraftWorkPool) GetJobResult(jobID string) (models.JobResult, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobResult{}, errors.New("empty job ID")
+	}
+
+	return gcwp.statsManager.GetResult(jobID)
+}
+
+// GetJobCheckIns return the check in history of the specified job.
+func (gcwp *GoCraftWorkPool) GetJobCheckIns(jobID string) (models.JobCheckIns, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobCheckIns{}, errors.New("empty job ID")
+	}
+
+	return gcwp.statsManager.CheckIns(jobID)
+}
+
+// GetJobTimeline return the status timeline of the specified job.
+func (gcwp *GoCraftWorkPool) GetJobTimeline(jobID string) (models.JobStatusTimeline, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobStatusTimeline{}, errors.New("empty job ID")
+	}
+
+	return gcwp.statsManager.StatusTimeline(jobID)
+}
+
+// ListJobs lists the archived jobs matching the query.
+func (gcwp *GoCraftWorkPool) ListJobs(query models.JobQuery) (models.JobList, error) {
+	return gcwp.statsManager.List(query)
+}
+
 // Stats of pool
 func (gcwp *GoCraftWorkPool) Stats() (models.JobPoolStats, error) {
 	// Get the status of workerpool via client
@@ -380,12 +430,44 @@ func (gcwp *GoCraftWorkPool) Stats() (models.JobPoolStats, error) {
 		return models.JobPoolStats{}, errors.New("Failed to get stats of worker pools")
 	}
 
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, stat := range stats {
+		for _, name := range stat.JobNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	paused, err := gcwp.pausedJobNames(conn, names)
+	if err != nil {
+		return models.JobPoolStats{}, err
+	}
+	for _, stat := range stats {
+		for _, name := range stat.JobNames {
+			if paused[name] {
+				stat.PausedJobNames = append(stat.PausedJobNames, name)
+			}
+		}
+	}
+
+	metrics := gcwp.statsManager.Metrics()
+
 	return models.JobPoolStats{
-		Pools: stats,
+		Pools:        stats,
+		StatsManager: &metrics,
 	}, nil
 }
 
-// StopJob will stop the job
+// StopJob will stop the job.
+// The running job is stopped via the 'stop' command and the job waiting in the pending queue,
+// the scheduled set, the retry set or the dead queue (cancelled) is removed from there.
+// For the periodic job, the policy is unscheduled as well.
 func (gcwp *GoCraftWorkPool) StopJob(jobID string) error {
 	if utils.IsEmptyStr(jobID) {
 		return errors.New("empty job ID")
@@ -396,60 +478,25 @@ func (gcwp *GoCraftWorkPool) StopJob(jobID string) error {
 		return err
 	}
 
-	needSetStopStatus := false
-
-	switch theJob.Stats.JobKind {
-	case job.JobKindGeneric:
-		// Only running job can be stopped
-		if theJob.Stats.Status != job.JobStatusRunning {
-			return fmt.Errorf("job '%s' is not a running job", jobID)
-		}
-	case job.JobKindScheduled:
-		// we need to delete the scheduled job in the queue if it is not running yet
-		// otherwise, nothing need to do
-		if theJob.Stats.Status == job.JobStatusScheduled {
-			if err := gcwp.client.DeleteScheduledJob(theJob.Stats.RunAt, jobID); err != nil {
-				return err
-			}
-			needSetStopStatus = true
-		}
-	case job.JobKindPeriodic:
-		// firstly delete the periodic job policy
-		if err := gcwp.scheduler.UnSchedule(jobID); err != nil {
-			return err
-		}
-		// secondly we need try to delete the job instances scheduled for this periodic job, a try best action
-		gcwp.deleteScheduledJobsOfPeriodicPolicy(theJob.Stats.JobID, theJob.Stats.CronSpec) // ignore error as we have logged
-		// thirdly expire the job stats of this periodic job if exists
-		if err := gcwp.statsManager.ExpirePeriodicJobStats(theJob.Stats.JobID); err != nil {
-			// only logged
-			logger.Errorf("Expire the stats of job %s failed with error: %s\n", theJob.Stats.JobID, err)
-		}
-
-		needSetStopStatus = true
-	default:
-		break
+	if theJob.Stats.JobKind == job.JobKindPeriodic {
+		return gcwp.stopPeriodicJob(theJob.Stats)
 	}
 
-	// Check if the job has 'running' instance
-	if theJob.Stats.Status == job.JobStatusRunning {
-		// Send 'stop' ctl command to the running instance
-		if err := gcwp.statsManager.SendCommand(jobID, opm.CtlCommandStop); err != nil {
-			return err
-		}
+	switch theJob.Stats.Status {
+	case job.JobStatusRunning:
 		// The job running instance will set the status to 'stopped'
-		needSetStopStatus = false
-	}
-
-	// If needed, update the job status to 'stopped'
-	if needSetStopStatus {
-		gcwp.statsManager.SetJobStatus(jobID, job.JobStatusStopped)
+		return gcwp.statsManager.SendCommand(jobID, opm.CtlCommandStop)
+	case job.JobStatusSuccess, job.JobStatusStopped, job.JobStatusExpired:
+		return fmt.Errorf("job '%s' has already ended with status '%s'", jobID, theJob.Stats.Status)
+	default:
+		return gcwp.withdrawJob(theJob.Stats, opm.CtlCommandStop)
 	}
-
-	return nil
 }
 
-// CancelJob will cancel the job
+// CancelJob will cancel the job.
+// The running job is cancelled via the 'cancel' command and the job waiting in the pending queue,
+// the scheduled set or the retry set is moved to the dead queue to be resumed later.
+// For the periodic job, only the current execution is cancelled and the policy stays in place.
 func (gcwp *GoCraftWorkPool) CancelJob(jobID string) error {
 	if utils.IsEmptyStr(jobID) {
 		return errors.New("empty job ID")
@@ -460,21 +507,69 @@ func (gcwp *GoCraftWorkPool) CancelJob(jobID string) error {
 		return err
 	}
 
-	switch theJob.Stats.JobKind {
-	case job.JobKindGeneric:
-		if theJob.Stats.Status != job.JobStatusRunning {
-			return fmt.Errorf("only running job can be cancelled, job '%s' seems not running now", theJob.Stats.JobID)
-		}
+	if theJob.Stats.Status == job.JobStatusRunning {
+		// The job running instance will set the status to 'cancelled'
+		return gcwp.statsManager.SendCommand(jobID, opm.CtlCommandCancel)
+	}
 
-		// Send 'cancel' ctl command to the running instance
-		if err := gcwp.statsManager.SendCommand(jobID, opm.CtlCommandCancel); err != nil {
+	if theJob.Stats.JobKind == job.JobKindPeriodic {
+		withdrawn, err := gcwp.withdrawPeriodicExecution(theJob.Stats)
+		if err != nil {
 			return err
 		}
-		break
+
+		if !withdrawn {
+			return fmt.Errorf("periodic job '%s' has no pending execution to cancel", jobID)
+		}
+
+		gcwp.statsManager.SetJobStatus(jobID, job.JobStatusCancelled)
+		return nil
+	}
+
+	switch theJob.Stats.Status {
+	case job.JobStatusSuccess, job.JobStatusStopped, job.JobStatusCancelled, job.JobStatusExpired:
+		return fmt.Errorf("job '%s' has already ended with status '%s'", jobID, theJob.Stats.Status)
 	default:
-		return fmt.Errorf("job kind '%s' does not support 'cancel' operation", theJob.Stats.JobKind)
+		return gcwp.withdrawJob(theJob.Stats, opm.CtlCommandCancel)
+	}
+}
+
+// stopPeriodicJob unschedules the periodic job policy and stops the current execution.
+func (gcwp *GoCraftWorkPool) stopPeriodicJob(stats *models.JobStatData) error {
+	// firstly delete the periodic job policy
+	if err := gcwp.scheduler.UnSchedule(stats.JobID); err != nil {
+		return err
+	}
+	// secondly we need try to delete the job instances scheduled for this periodic job, a try best action
+	gcwp.deleteScheduledJobsOfPeriodicPolicy(stats.JobID, stats.CronSpec) // ignore error as we have logged
+	// thirdly expire the job stats of this periodic job if exists
+	if err := gcwp.statsManager.ExpirePeriodicJobStats(stats.JobID); err != nil {
+		// only logged
+		logger.Errorf("Expire the stats of job %s failed with error: %s\n", stats.JobID, err)
+	}
+
+	if stats.Status == job.JobStatusRunning {
+		// The job running instance will set the status to 'stopped'
+		return gcwp.statsManager.SendCommand(stats.JobID, opm.CtlCommandStop)
 	}
 
+	// The execution may be waiting in the pending queue
+	_, rawJSON, _, err := gcwp.lookupWaitingJob(stats.JobID, true, pendingList(utils.KeyJobs(gcwp.namespace, stats.JobName)))
+	if err != nil {
+		logger.Warningf("Remove the pending execution of periodic job %s failed with error: %s\n", stats.JobID, err)
+	}
+	if rawJSON != nil {
+		// Drop the copy put back by the periodic enqueuer before the policy is unscheduled
+		j := &work.Job{}
+		if err := json.Unmarshal(rawJSON, j); err == nil {
+			if err := gcwp.withdrawExecution(stats.JobID, j.EnqueuedAt); err != nil {
+				logger.Warningf("Withdraw the pending execution of periodic job %s failed with error: %s\n", stats.JobID, err)
+			}
+		}
+	}
+
+	gcwp.statsManager.SetJobStatus(stats.JobID, job.JobStatusStopped)
+
 	return nil
 }
 
@@ -489,11 +584,22 @@ func (gcwp *GoCraftWorkPool) RetryJob(jobID string) error {
 		return err
 	}
 
-	if theJob.Stats.DieAt == 0 {
-		return fmt.Errorf("job '%s' is not a retryable job", jobID)
+	dieAt := theJob.Stats.DieAt
+	if dieAt == 0 {
+		// The dying time may be not recorded yet, look up the dead queue
+		dj, err := gcwp.findDeadJob(jobID)
+		if err != nil {
+			return err
+		}
+
+		if dj == nil {
+			return fmt.Errorf("job '%s' is not a retryable job", jobID)
+		}
+
+		dieAt = dj.DiedAt
 	}
 
-	return gcwp.client.RetryDeadJob(theJob.Stats.DieAt, jobID)
+	return gcwp.client.RetryDeadJob(dieAt, jobID)
 }
 
 // IsKnownJob ...
@@ -541,6 +647,10 @@ func (gcwp *GoCraftWorkPool) deleteScheduledJobsOfPeriodicPolicy(policyID string
 	// return the last error if occurred
 	for t := schedule.Next(nowTime); t.Before(horizon); t = schedule.Next(t) {
 		epoch := t.Unix()
+		// The periodic enqueuer may not know the policy is unscheduled yet
+		if err = gcwp.withdrawExecution(policyID, epoch); err != nil {
+			logger.Warningf("withdraw scheduled instance for periodic job %s failed with error: %s\n", policyID, err)
+		}
 		if err = gcwp.client.DeleteScheduledJob(epoch, policyID); err != nil {
 			// only logged
 			logger.Warningf("delete scheduled instance for periodic job %s failed with error: %s\n", policyID, err)
@@ -636,6 +746,20 @@ func (gcwp *GoCraftWorkPool) ping() error {
 	return fmt.Errorf("connect to redis server timeout: %s", err.Error())
 }
 
+// maxConcurrency returns the max number of the running jobs of the name across all the pools,
+// the configured one takes precedence over the one declared by the job.
+func maxConcurrency(name string, j job.Interface) uint {
+	if n, ok := config.GetMaxConcurrency(name); ok {
+		return n
+	}
+
+	if limiter, ok := j.(job.ConcurrencyLimiter); ok {
+		return limiter.MaxConcurrency()
+	}
+
+	return 0
+}
+
 // generate the job stats data
 func generateResult(j *work.Job, jobKind string, isUnique bool) models.JobStats {
 	if j == nil {
@@ -3,18 +3,27 @@ package pool
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/config"
 	"github.com/Colstuwjx/job/env"
 	"github.com/Colstuwjx/job/errs"
-	"github.com/Colstuwjx/job/impl"
+	"github.com/Colstuwjx/job/impl/job"
 	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
 	"github.com/Colstuwjx/job/opm"
+	"github.com/Colstuwjx/job/period"
 	"github.com/Colstuwjx/job/tests"
+	"github.com/Colstuwjx/job/utils"
 )
 
 var rPool = tests.GiveMeRedisPool()
@@ -101,6 +110,61 @@ func TestEnqueueJob(t *testing.T) {
 	sysCtx.WG.Wait()
 }
 
+func TestEnqueueJobWithSettings(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	jd := &models.JobData{
+		Name:       "fake_job",
+		Parameters: map[string]interface{}{"name": "testing:v1"},
+		Metadata: &models.JobMetadata{
+			JobKind:       job.JobKindScheduled,
+			ScheduleDelay: 600,
+			IsUnique:      true,
+		},
+		JobCallbacks: models.JobCallbacks{
+			OnComplete: &models.JobCallback{Name: "fake_job"},
+		},
+	}
+	res, err := wp.EnqueueJob(jd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.JobKind != job.JobKindScheduled || !res.Stats.IsUnique || res.Stats.RunAt < time.Now().Unix()+590 {
+		t.Fatalf("expect unique job scheduled in 600s but got %+v\n", res.Stats)
+	}
+
+	conn := rPool.Get()
+	defer conn.Close()
+
+	if n, err := redis.Int(conn.Do("ZCARD", utils.KeyScheduled(tests.GiveMeTestNamespace()))); err != nil || n != 1 {
+		t.Fatalf("expect 1 scheduled job but got %d: %v\n", n, err)
+	}
+	if ok, err := redis.Bool(conn.Do("HEXISTS", utils.KeyJobCallbacks(tests.GiveMeTestNamespace(), res.Stats.JobID), callbackOnComplete)); err != nil || !ok {
+		t.Fatalf("expect on_complete callback kept with job %s: %v\n", res.Stats.JobID, err)
+	}
+
+	// The duplicated unique job is discarded
+	if _, err := wp.EnqueueJob(jd); err == nil {
+		t.Fatal("expect error of duplicated unique job but got nil")
+	}
+	if _, err := wp.Schedule("fake_job", jd.Parameters, 600, true); err == nil {
+		t.Fatal("expect error of duplicated unique job enqueued by the backend pool but got nil")
+	}
+}
+
 func TestEnqueuePeriodicJob(t *testing.T) {
 	wp, _, cancel := createRedisWorkerPool()
 	defer func() {
@@ -210,178 +274,1807 @@ CHECK:
     }
 }*/
 
-func createRedisWorkerPool() (*GoCraftWorkPool, *env.Context, context.CancelFunc) {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-	envCtx := &env.Context{
-		SystemContext: ctx,
-		WG:            new(sync.WaitGroup),
-		ErrorChan:     make(chan error, 1),
-		JobContext:    newContext(ctx),
+func TestDeadJobs(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	conn := rPool.Get()
+	defer conn.Close()
+
+	now := time.Now().Unix()
+	key := utils.KeyDead(tests.GiveMeTestNamespace())
+	for i := 0; i < 25; i++ {
+		diedAt := now
+		if i < 5 {
+			diedAt = now - 3600
+		}
+
+		rawJSON, err := json.Marshal(map[string]interface{}{
+			"name":      "fake_job",
+			"id":        fmt.Sprintf("dead_%d", i),
+			"t":         diedAt,
+			"args":      map[string]interface{}{},
+			"fails":     4,
+			"err":       "boom",
+			"failed_at": diedAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := conn.Do("ZADD", key, diedAt+int64(i), rawJSON); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	return NewGoCraftWorkPool(envCtx, tests.GiveMeTestNamespace(), 3, rPool), envCtx, cancel
-}
+	list, err := wp.ListDeadJobs(nil, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 25 || len(list.Jobs) != 10 || list.Jobs[0].JobID != "dead_10" || list.Jobs[0].LastError != "boom" {
+		t.Fatalf("expect page 2 of 25 dead jobs starting from 'dead_10' but got %d of %d\n", len(list.Jobs), list.Total)
+	}
 
-type fakeJob struct{}
+	filter := &models.DeadJobFilter{OlderThan: 1800}
+	if list, err = wp.ListDeadJobs(filter, 1, 20); err != nil || list.Total != 5 {
+		t.Fatalf("expect 5 dead jobs older than 30 minutes but got %d: %v\n", list.Total, err)
+	}
 
-func (j *fakeJob) MaxFails() uint {
-	return 3
-}
+	res, err := wp.DeleteDeadJobs(nil, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Matched != 5 || res.Succeeded != 5 {
+		t.Fatalf("expect 5 old dead jobs purged but got %d of %d\n", res.Succeeded, res.Matched)
+	}
 
-func (j *fakeJob) ShouldRetry() bool {
-	return true
+	if res, err = wp.DeleteDeadJobs([]string{"dead_5", "unknown"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if res.Succeeded != 1 || len(res.Failed) != 1 {
+		t.Fatalf("expect 1 dead job deleted and 1 not found but got %d and %d\n", res.Succeeded, len(res.Failed))
+	}
+
+	if list, err = wp.ListDeadJobs(nil, 1, 100); err != nil || list.Total != 19 {
+		t.Fatalf("expect 19 dead jobs left but got %d: %v\n", list.Total, err)
+	}
 }
 
-func (j *fakeJob) Validate(params map[string]interface{}) error {
-	if p, ok := params["name"]; ok {
-		if p == "testing:v1" {
-			return nil
+func TestQueues(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
 		}
-	}
+	}()
+	defer cancel()
 
-	return errors.New("testing error")
-}
+	conn := rPool.Get()
+	defer conn.Close()
 
-func (j *fakeJob) Run(ctx env.JobContext, params map[string]interface{}) error {
-	return nil
-}
+	ns := tests.GiveMeTestNamespace()
+	now := time.Now().Unix()
+	sets := map[string]int{
+		utils.KeyScheduled(ns): 3,
+		utils.KeyRetry(ns):     2,
+		utils.KeyDead(ns):      1,
+	}
+	for key, count := range sets {
+		for i := 0; i < count; i++ {
+			rawJSON, err := json.Marshal(map[string]interface{}{
+				"name": "fake_job",
+				"id":   fmt.Sprintf("%s_%d", key, i),
+				"t":    now,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
 
-type fakeUniqueJob struct{}
+			if _, err := conn.Do("ZADD", key, now+int64(i), rawJSON); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
 
-func (j *fakeUniqueJob) MaxFails() uint {
-	return 3
-}
+	if _, err := conn.Do("SET", utils.KeyJobsPaused(ns, "fake_job"), "1"); err != nil {
+		t.Fatal(err)
+	}
 
-func (j *fakeUniqueJob) ShouldRetry() bool {
-	return true
+	queues, err := wp.Queues()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queues.Queues) != 1 {
+		t.Fatalf("expect 1 queue but got %d\n", len(queues.Queues))
+	}
+	q := queues.Queues[0]
+	if q.JobName != "fake_job" || q.Scheduled != 3 || q.Retry != 2 || q.Dead != 1 || !q.Paused {
+		t.Fatalf("expect 3 scheduled, 2 retry, 1 dead and paused queue of 'fake_job' but got %+v\n", q)
+	}
 }
 
-func (j *fakeUniqueJob) Validate(params map[string]interface{}) error {
-	if p, ok := params["name"]; ok {
-		if p == "testing:v1" {
-			return nil
+func TestPauseQueue(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
 		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
 	}
 
-	return errors.New("testing error")
-}
+	if err := wp.PauseQueue("unknown_job"); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect object not found error but got %v\n", err)
+	}
 
-func (j *fakeUniqueJob) Run(ctx env.JobContext, params map[string]interface{}) error {
-	return nil
-}
+	if err := wp.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
 
-type fakeRunnableJob struct{}
+	queues, err := wp.Queues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queues.Queues) != 1 || !queues.Queues[0].Paused {
+		t.Fatalf("expect the queue of 'fake_job' paused but got %d queues\n", len(queues.Queues))
+	}
 
-func (j *fakeRunnableJob) MaxFails() uint {
-	return 2
-}
+	if err := wp.ResumeQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
 
-func (j *fakeRunnableJob) ShouldRetry() bool {
-	return true
+	if queues, err = wp.Queues(); err != nil {
+		t.Fatal(err)
+	}
+	if queues.Queues[0].Paused {
+		t.Fatal("expect the queue of 'fake_job' resumed but still paused")
+	}
 }
 
-func (j *fakeRunnableJob) Validate(params map[string]interface{}) error {
-	if p, ok := params["name"]; ok {
-		if p == "testing:v1" {
-			return nil
+func TestStopAndCancelWaitingJobs(t *testing.T) {
+	wp, sysCtx, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
 		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	// Keep the enqueued jobs pending
+	if err := wp.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
 	}
 
-	return errors.New("testing error")
-}
+	go wp.Start()
+	time.Sleep(1 * time.Second)
 
-func (j *fakeRunnableJob) Run(ctx env.JobContext, params map[string]interface{}) error {
-	tk := time.NewTicker(1 * time.Second)
-	defer tk.Stop()
+	params := map[string]interface{}{"name": "testing:v1"}
+	pending, err := wp.Enqueue("fake_job", params, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduled, err := wp.Schedule("fake_job", params, 60, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the job stats saved
+	<-time.After(500 * time.Millisecond)
 
-	for {
-		select {
-		case <-tk.C:
-			cmd, ok := ctx.OPCommand()
-			if ok {
-				if cmd == opm.CtlCommandStop {
-					return errs.JobStoppedError()
-				}
+	if err := wp.StopJob(pending.Stats.JobID); err != nil {
+		t.Fatal(err)
+	}
+	if err := wp.CancelJob(scheduled.Stats.JobID); err != nil {
+		t.Fatal(err)
+	}
 
-				return errs.JobCancelledError()
-			}
-		case <-ctx.SystemContext().Done():
-			return nil
-		case <-time.After(1 * time.Minute):
-			return errors.New("fake job timeout")
-		}
+	<-time.After(1 * time.Second)
+
+	queues, err := wp.Queues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := queues.Queues[0]; q.Pending != 0 || q.Scheduled != 0 || q.Dead != 1 {
+		t.Fatalf("expect the jobs withdrawn and the cancelled one in the dead queue but got %+v\n", q)
 	}
-}
 
-type fakeContext struct {
-	// System context
-	sysContext context.Context
+	stopped, err := wp.GetJobStats(pending.Stats.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Stats.Status != job.JobStatusStopped {
+		t.Fatalf("expect job status '%s' but got '%s'\n", job.JobStatusStopped, stopped.Stats.Status)
+	}
 
-	// op command func
-	opCommandFunc impl.CheckOPCmdFunc
+	cancelled, err := wp.GetJobStats(scheduled.Stats.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Stats.Status != job.JobStatusCancelled {
+		t.Fatalf("expect job status '%s' but got '%s'\n", job.JobStatusCancelled, cancelled.Stats.Status)
+	}
 
-	// checkin func
-	checkInFunc impl.CheckInFunc
+	if err := wp.StopJob(pending.Stats.JobID); err == nil {
+		t.Fatal("expect error when stopping the stopped job but got nil")
+	}
 
-	// other required information
-	properties map[string]interface{}
+	// The cancelled job can be resumed
+	if err := wp.RetryJob(scheduled.Stats.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	sysCtx.WG.Wait()
 }
 
-func newContext(sysCtx context.Context) *fakeContext {
-	return &fakeContext{
-		sysContext: sysCtx,
-		properties: make(map[string]interface{}),
+func TestCancelPeriodicExecution(t *testing.T) {
+	wp, sysCtx, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	// Keep the due executions pending
+	if err := wp.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
 	}
-}
 
-// Build implements the same method in env.JobContext interface
-// This func will build the job execution context before running
-func (c *fakeContext) Build(dep env.JobData) (env.JobContext, error) {
-	jContext := &fakeContext{
-		sysContext: c.sysContext,
-		properties: make(map[string]interface{}),
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	params := map[string]interface{}{"name": "testing:v1"}
+	periodic, err := wp.PeriodicallyEnqueue("fake_job", params, "0 * * * * *")
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	// Copy properties
-	if len(c.properties) > 0 {
-		for k, v := range c.properties {
-			jContext.properties[k] = v
+	// Run the periodic enqueuer like another node does
+	enqueue := func() {
+		conn := redisPool.Get()
+		defer conn.Close()
+		if _, err := conn.Do("DEL", utils.RedisKeyLastPeriodicEnqueue(tests.GiveMeTestNamespace())); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, stop := context.WithCancel(context.Background())
+		defer stop()
+		envCtx := &env.Context{
+			SystemContext: ctx,
+			WG:            new(sync.WaitGroup),
+			ErrorChan:     make(chan error, 1),
 		}
+		go period.NewRedisPeriodicScheduler(envCtx, tests.GiveMeTestNamespace(), redisPool).Start()
+		<-time.After(1 * time.Second)
 	}
 
-	if opCommandFunc, ok := dep.ExtraData["opCommandFunc"]; ok {
-		if reflect.TypeOf(opCommandFunc).Kind() == reflect.Func {
-			if funcRef, ok := opCommandFunc.(impl.CheckOPCmdFunc); ok {
-				jContext.opCommandFunc = funcRef
+	// The epochs of the executions waiting in the scheduled set or the pending job list
+	executions := func() map[int64]bool {
+		conn := redisPool.Get()
+		defer conn.Close()
+
+		items, err := redis.ByteSlices(conn.Do("ZRANGE", utils.KeyScheduled(tests.GiveMeTestNamespace()), 0, -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending, err := redis.ByteSlices(conn.Do("LRANGE", utils.KeyJobs(tests.GiveMeTestNamespace(), "fake_job"), 0, -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		epochs := make(map[int64]bool)
+		for _, item := range append(items, pending...) {
+			j := make(map[string]interface{})
+			if err := json.Unmarshal(item, &j); err != nil {
+				t.Fatal(err)
+			}
+			if j["id"] == periodic.Stats.JobID {
+				epochs[int64(j["t"].(float64))] = true
 			}
 		}
+
+		return epochs
 	}
 
-	if jContext.opCommandFunc == nil {
-		return nil, errors.New("failed to inject opCommandFunc")
+	enqueue()
+	before := executions()
+	if len(before) == 0 {
+		t.Fatal("expect executions of the periodic job enqueued but got none")
 	}
 
-	if checkInFunc, ok := dep.ExtraData["checkInFunc"]; ok {
-		if reflect.TypeOf(checkInFunc).Kind() == reflect.Func {
-			if funcRef, ok := checkInFunc.(impl.CheckInFunc); ok {
-				jContext.checkInFunc = funcRef
-			}
+	if err := wp.CancelJob(periodic.Stats.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	after := executions()
+	var cancelled int64
+	for epoch := range before {
+		if !after[epoch] {
+			cancelled = epoch
 		}
 	}
+	if cancelled == 0 || len(after) != len(before)-1 {
+		t.Fatalf("expect one execution cancelled but got %d executions left of %d\n", len(after), len(before))
+	}
 
-	if jContext.checkInFunc == nil {
-		return nil, errors.New("failed to inject checkInFunc")
+	// The cancelled execution is not put back
+	enqueue()
+	if executions()[cancelled] {
+		t.Fatalf("expect the cancelled execution at %d not enqueued again\n", cancelled)
 	}
 
-	return jContext, nil
+	cancel()
+	sysCtx.WG.Wait()
 }
 
-// Get implements the same method in env.JobContext interface
-func (c *fakeContext) Get(prop string) (interface{}, bool) {
-	v, ok := c.properties[prop]
-	return v, ok
-}
+func TestLookupWaitingJob(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	conn := redisPool.Get()
+	defer conn.Close()
+
+	listKey := utils.KeyJobs(tests.GiveMeTestNamespace(), "fake_job")
+	setKey := utils.KeyRetry(tests.GiveMeTestNamespace())
+	now := time.Now().Unix()
+	// More than one round of the lookup
+	for i := 0; i < waitingJobLookupSize+10; i++ {
+		rawJSON, err := json.Marshal(map[string]interface{}{"id": fmt.Sprintf("job-%d", i), "name": "fake_job"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Do("LPUSH", listKey, rawJSON); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Do("ZADD", setKey, now+int64(i), rawJSON); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The earliest pushed job is at the tail of the list
+	place, rawJSON, _, err := wp.lookupWaitingJob("job-0", true, pendingList(listKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawJSON == nil || place.key != listKey {
+		t.Fatal("expect job 'job-0' found in the pending list but got nothing")
+	}
+	if n, err := redis.Int(conn.Do("LLEN", listKey)); err != nil || n != waitingJobLookupSize+9 {
+		t.Fatalf("expect %d jobs left in the pending list but got %d (%v)\n", waitingJobLookupSize+9, n, err)
+	}
+
+	// The job is looked up around the known score
+	if _, rawJSON, _, err = wp.lookupWaitingJob("job-505", false, jobSet(setKey, now)); err != nil || rawJSON != nil {
+		t.Fatalf("expect job 'job-505' not found around score %d but got %s (%v)\n", now, rawJSON, err)
+	}
+	_, rawJSON, score, err := wp.lookupWaitingJob("job-505", true, jobSet(setKey, now+505))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawJSON == nil || score != fmt.Sprintf("%d", now+505) {
+		t.Fatalf("expect job 'job-505' found with score %d but got %s\n", now+505, score)
+	}
+
+	// The removed job can be put back
+	if err := wp.restoreWaitingJob(jobSet(setKey, now+505), rawJSON, score); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := redis.Int(conn.Do("ZCARD", setKey)); err != nil || n != waitingJobLookupSize+10 {
+		t.Fatalf("expect %d jobs in the retry set but got %d (%v)\n", waitingJobLookupSize+10, n, err)
+	}
+}
+
+func TestUpdateJob(t *testing.T) {
+	wp, sysCtx, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	// Keep the enqueued jobs pending
+	if err := wp.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	pending, err := wp.Enqueue("fake_job", map[string]interface{}{"name": "testing:v1"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the job stats saved
+	<-time.After(500 * time.Millisecond)
+
+	if _, err := wp.UpdateJob(pending.Stats.JobID, models.Parameters{"name": "testing:v2"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	runAt := time.Now().Unix() + 600
+	res, err := wp.UpdateJob(pending.Stats.JobID, nil, runAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.JobID != pending.Stats.JobID || res.Stats.RunAt != runAt {
+		t.Fatalf("expect job %s rescheduled at %d but got %s at %d\n", pending.Stats.JobID, runAt, res.Stats.JobID, res.Stats.RunAt)
+	}
+
+	jobs, count, err := wp.client.ScheduledJobs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || jobs[0].ID != pending.Stats.JobID || jobs[0].RunAt != runAt || jobs[0].Args["name"] != "testing:v2" {
+		t.Fatalf("expect the updated job in the scheduled set but got %d jobs\n", count)
+	}
+
+	<-time.After(500 * time.Millisecond)
+	updated, err := wp.GetJobStats(pending.Stats.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Stats.Status != job.JobStatusScheduled || updated.Stats.RunAt != runAt {
+		t.Fatalf("expect status '%s' and run at %d but got '%s' and %d\n", job.JobStatusScheduled, runAt, updated.Stats.Status, updated.Stats.RunAt)
+	}
+
+	if err := wp.StopJob(pending.Stats.JobID); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(500 * time.Millisecond)
+	if _, err := wp.UpdateJob(pending.Stats.JobID, nil, runAt+60); err == nil {
+		t.Fatal("expect error when updating the stopped job but got nil")
+	}
+
+	cancel()
+	sysCtx.WG.Wait()
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_limited_job", (*fakeLimitedJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	for i := 0; i < 3; i++ {
+		if _, err := wp.Enqueue("fake_limited_job", map[string]interface{}{"name": "testing:v1"}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Wait for the jobs fetched
+	<-time.After(2 * time.Second)
+
+	queues, err := wp.Queues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queues.Queues) != 1 {
+		t.Fatalf("expect 1 queue but got %d\n", len(queues.Queues))
+	}
+
+	// The other jobs stay queued though there're idle workers
+	q := queues.Queues[0]
+	if q.Running != 1 || q.MaxConcurrency != 1 || q.Pending != 2 {
+		t.Fatalf("expect 1 running and 2 pending jobs with max concurrency 1 but got %+v\n", q)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	poolConfig := config.DefaultConfig.PoolConfig
+	config.DefaultConfig.PoolConfig = &config.PoolConfig{
+		RateLimits: map[string]*config.RateLimitConfig{
+			"fake_job": {Rate: 1, Period: 60},
+		},
+	}
+	defer func() {
+		config.DefaultConfig.PoolConfig = poolConfig
+	}()
+
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	jobIDs := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		res, err := wp.Enqueue("fake_job", map[string]interface{}{"name": "testing:v1"}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobIDs = append(jobIDs, res.Stats.JobID)
+	}
+	// Wait for the jobs processed
+	<-time.After(2 * time.Second)
+
+	counts := make(map[string]int)
+	for _, jobID := range jobIDs {
+		stats, err := wp.GetJobStats(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[stats.Stats.Status]++
+
+		if stats.Stats.Status == job.JobStatusScheduled && stats.Stats.RunAt <= time.Now().Unix() {
+			t.Fatalf("expect the rate limited job delayed but got run at %d\n", stats.Stats.RunAt)
+		}
+	}
+	if counts[job.JobStatusSuccess] != 1 || counts[job.JobStatusScheduled] != 1 {
+		t.Fatalf("expect 1 succeeded and 1 delayed job but got %v\n", counts)
+	}
+
+	// The delayed job is not counted as a failure
+	conn := rPool.Get()
+	defer conn.Close()
+
+	scheduled, err := redis.Int(conn.Do("ZCARD", utils.KeyScheduled(tests.GiveMeTestNamespace())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduled != 1 {
+		t.Fatalf("expect 1 delayed job in the scheduled set but got %d\n", scheduled)
+	}
+	retries, err := redis.Int(conn.Do("ZCARD", utils.KeyRetry(tests.GiveMeTestNamespace())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retries != 0 {
+		t.Fatalf("expect no retried jobs but got %d\n", retries)
+	}
+}
+
+func TestDelayUniqueJob(t *testing.T) {
+	wp, envCtx, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	uniqueKey, err := utils.RedisKeyUniqueJob(tests.GiveMeTestNamespace(), "fake_job", map[string]interface{}{"name": "testing:v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &work.Job{
+		Name:       "fake_job",
+		ID:         "fake_delayed_unique_job_ID",
+		EnqueuedAt: time.Now().Unix(),
+		Args:       map[string]interface{}{"name": "testing:v1"},
+		Unique:     true,
+		UniqueKey:  uniqueKey,
+		Fails:      2,
+	}
+
+	// The unique key is released when the job is fetched
+	rj := NewRedisJob((*fakeJob)(nil), envCtx, wp.statsManager, tests.GiveMeTestNamespace(), rPool)
+	if _, err := rj.delay(j, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := rPool.Get()
+	defer conn.Close()
+
+	ttl, err := redis.Int64(conn.Do("TTL", uniqueKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 {
+		t.Fatalf("expect the unique key held by the delayed job but got TTL %d\n", ttl)
+	}
+
+	items, err := redis.ByteSlices(conn.Do("ZRANGE", utils.KeyScheduled(tests.GiveMeTestNamespace()), 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expect 1 delayed job in the scheduled set but got %d\n", len(items))
+	}
+	delayed, err := utils.DeSerializeJob(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delayed.ID != j.ID || delayed.Fails != j.Fails || delayed.UniqueKey != uniqueKey {
+		t.Fatalf("expect the delayed job kept with fails and unique key but got %+v\n", delayed)
+	}
+}
+
+func TestPartitionedJobs(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_runnable_job", (*fakeRunnableJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	params := map[string]interface{}{"name": "testing:v1"}
+	jobIDs := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		res, err := wp.EnqueuePartitioned("fake_runnable_job", params, "library/photon")
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobIDs = append(jobIDs, res.Stats.JobID)
+	}
+	other, err := wp.EnqueuePartitioned("fake_runnable_job", params, "library/redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the jobs started
+	<-time.After(2 * time.Second)
+
+	// The jobs with different keys run in parallel
+	for _, jobID := range []string{jobIDs[0], other.Stats.JobID} {
+		stats, err := wp.GetJobStats(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Stats.Status != job.JobStatusRunning || stats.Stats.PartitionKey == "" {
+			t.Fatalf("expect running partitioned job %s but got %+v\n", jobID, stats.Stats)
+		}
+	}
+
+	partition, err := wp.GetPartition("library/photon", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partition.Running != jobIDs[0] || partition.Total != 2 ||
+		partition.Jobs[0].JobID != jobIDs[1] || partition.Jobs[1].JobID != jobIDs[2] {
+		t.Fatalf("expect 1 running and 2 pending jobs in order but got %+v\n", partition)
+	}
+
+	// The next one is started once the in-flight one ends
+	if err := wp.StopJob(jobIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(3 * time.Second)
+
+	partition, err = wp.GetPartition("library/photon", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partition.Running != jobIDs[1] || partition.Total != 1 {
+		t.Fatalf("expect the second job running but got %+v\n", partition)
+	}
+
+	// The job waiting in the backlog can be withdrawn
+	if err := wp.StopJob(jobIDs[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := wp.StopJob(jobIDs[1]); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(3 * time.Second)
+
+	if _, err := wp.GetPartition("library/photon", 0, 0); !errs.IsObjectNotFoundError(err) {
+		t.Fatalf("expect object not found error of the drained partition but got %v\n", err)
+	}
+	for _, jobID := range jobIDs {
+		stats, err := wp.GetJobStats(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Stats.Status != job.JobStatusStopped {
+			t.Fatalf("expect job %s stopped but got %s\n", jobID, stats.Stats.Status)
+		}
+	}
+}
+
+func TestChildJobs(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	jobs := map[string]interface{}{
+		"fake_parent_job":   (*fakeParentJob)(nil),
+		"fake_job":          (*fakeJob)(nil),
+		"fake_runnable_job": (*fakeRunnableJob)(nil),
+	}
+	if err := wp.RegisterJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	parent, err := wp.Enqueue("fake_parent_job", map[string]interface{}{"name": "testing:v1"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	select {
+	case ids = <-childJobIDs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expect the child jobs enqueued by the parent job")
+	}
+	// Wait for the child jobs started
+	<-time.After(2 * time.Second)
+
+	for _, id := range ids {
+		child, err := wp.GetJobStats(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if child.Stats.ParentID != parent.Stats.JobID {
+			t.Fatalf("expect parent job %s but got %s\n", parent.Stats.JobID, child.Stats.ParentID)
+		}
+	}
+
+	// The parent job is kept running until all the child jobs are finished
+	stats, err := wp.GetJobStats(parent.Stats.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.Status != job.JobStatusRunning {
+		t.Fatalf("expect the parent job running but got %s\n", stats.Stats.Status)
+	}
+
+	if err := wp.StopJob(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(3 * time.Second)
+
+	stats, err = wp.GetJobStats(parent.Stats.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.Status != job.JobStatusError || stats.Stats.LastError == "" {
+		t.Fatalf("expect the parent job failed because of the stopped child job but got %+v\n", stats.Stats)
+	}
+}
+
+func TestClassifiedErrors(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_classified_job", (*fakeClassifiedJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	jobIDs := make(map[string]string)
+	for _, e := range []string{"permanent", "retry_after", "rate_limited", "panic"} {
+		res, err := wp.Enqueue("fake_classified_job", map[string]interface{}{"name": "testing:v1", "error": e}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobIDs[e] = res.Stats.JobID
+	}
+	// Wait for the jobs processed
+	<-time.After(3 * time.Second)
+
+	conn := rPool.Get()
+	defer conn.Close()
+
+	now := time.Now().Unix()
+	scoreOf := func(key string, jobID string) int64 {
+		items, err := redis.Values(conn.Do("ZRANGE", key, 0, -1, "WITHSCORES"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i+1 < len(items); i += 2 {
+			j := make(map[string]interface{})
+			if err := json.Unmarshal(items[i].([]byte), &j); err != nil {
+				t.Fatal(err)
+			}
+			if j["id"] == jobID {
+				score, _ := redis.Int64(items[i+1], nil)
+				return score
+			}
+		}
+		return 0
+	}
+
+	// The permanent failure dies without retrying
+	if scoreOf(utils.KeyDead(tests.GiveMeTestNamespace()), jobIDs["permanent"]) == 0 {
+		t.Fatalf("expect job %s with permanent error in the dead queue\n", jobIDs["permanent"])
+	}
+
+	// The job is retried after the asked delay
+	if runAt := scoreOf(utils.KeyRetry(tests.GiveMeTestNamespace()), jobIDs["retry_after"]); runAt < now+95 || runAt > now+100 {
+		t.Fatalf("expect job %s retried after 100s but got %d at %d\n", jobIDs["retry_after"], runAt, now)
+	}
+
+	// The throttled job is delayed without failing
+	stats, err := wp.GetJobStats(jobIDs["rate_limited"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.Status != job.JobStatusScheduled || stats.Stats.RunAt < now+95 {
+		t.Fatalf("expect throttled job delayed for 100s but got %+v\n", stats.Stats)
+	}
+	if scoreOf(utils.KeyScheduled(tests.GiveMeTestNamespace()), jobIDs["rate_limited"]) != stats.Stats.RunAt {
+		t.Fatalf("expect throttled job %s in the scheduled set\n", jobIDs["rate_limited"])
+	}
+
+	// The panicking job fails and is retried
+	stats, err = wp.GetJobStats(jobIDs["panic"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.Status != job.JobStatusError {
+		t.Fatalf("expect panicking job with status %s but got %s\n", job.JobStatusError, stats.Stats.Status)
+	}
+	if scoreOf(utils.KeyRetry(tests.GiveMeTestNamespace()), jobIDs["panic"]) == 0 {
+		t.Fatalf("expect panicking job %s in the retry queue\n", jobIDs["panic"])
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cases := []struct {
+		policy   *models.RetryPolicy
+		fails    int64
+		expected int64
+	}{
+		{&models.RetryPolicy{Backoff: job.RetryBackoffFixed, Delay: 10}, 3, 10},
+		{&models.RetryPolicy{Backoff: job.RetryBackoffLinear, Delay: 10}, 3, 30},
+		{&models.RetryPolicy{Delay: 10}, 3, 40},
+		{&models.RetryPolicy{}, 1, defaultRetryDelay},
+		{&models.RetryPolicy{Delay: 10, MaxDelay: 60}, 10, 60},
+		{&models.RetryPolicy{Delay: 10}, 100, maxRetryDelay},
+	}
+	for _, c := range cases {
+		if delay := retryBackoff(c.policy, c.fails); delay != c.expected {
+			t.Errorf("expect delay %d of policy %+v after %d fails but got %d\n", c.expected, c.policy, c.fails, delay)
+		}
+	}
+
+	policy := &models.RetryPolicy{Backoff: job.RetryBackoffFixed, Delay: 100, Jitter: 0.1}
+	for i := 0; i < 10; i++ {
+		if delay := retryBackoff(policy, 1); delay < 90 || delay > 110 {
+			t.Fatalf("expect delay in [90, 110] with jitter but got %d\n", delay)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	jobs := map[string]interface{}{
+		"fake_classified_job": (*fakeClassifiedJob)(nil),
+		"fake_retrier_job":    (*fakeRetrierJob)(nil),
+		"fake_no_retry_job":   (*fakeNoRetryJob)(nil),
+	}
+	if err := wp.RegisterJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	params := map[string]interface{}{"name": "testing:v1"}
+	policy := &models.RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     job.RetryBackoffFixed,
+		Delay:       100,
+	}
+
+	// The retry policy of the request overrides the job type, even if the job does not retry in general
+	retriedIDs := make([]string, 0, 2)
+	for _, name := range []string{"fake_classified_job", "fake_no_retry_job"} {
+		res, err := wp.EnqueueJob(&models.JobData{
+			Name:       name,
+			Parameters: params,
+			Metadata: &models.JobMetadata{
+				JobKind:     job.JobKindGeneric,
+				RetryPolicy: policy,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		retriedIDs = append(retriedIDs, res.Stats.JobID)
+	}
+
+	dead, err := wp.Enqueue("fake_retrier_job", params, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the jobs processed
+	<-time.After(3 * time.Second)
+
+	now := time.Now().Unix()
+	for _, jobID := range retriedIDs {
+		stats, err := wp.GetJobStats(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Stats.NextRetryAt < now+95 || stats.Stats.NextRetryAt > now+100 {
+			t.Fatalf("expect job retried after 100s but got %+v at %d\n", stats.Stats, now)
+		}
+	}
+
+	conn := rPool.Get()
+	defer conn.Close()
+
+	if n, err := redis.Int(conn.Do("ZCARD", utils.KeyRetry(tests.GiveMeTestNamespace()))); err != nil || n != 2 {
+		t.Fatalf("expect 2 jobs in the retry queue but got %d: %v\n", n, err)
+	}
+
+	// The job of the type never retried dies after the first failure
+	stats, err := wp.GetJobStats(dead.Stats.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.NextRetryAt != 0 {
+		t.Fatalf("expect job not retried but got next retry at %d\n", stats.Stats.NextRetryAt)
+	}
+	if n, err := redis.Int(conn.Do("ZCARD", utils.KeyDead(tests.GiveMeTestNamespace()))); err != nil || n != 1 {
+		t.Fatalf("expect 1 job in the dead queue but got %d: %v\n", n, err)
+	}
+}
+
+func TestJobExpiry(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	// Keep the jobs pending until they're expired
+	if err := wp.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	now := time.Now().Unix()
+	expireAt := map[string]int64{}
+	for _, metadata := range []*models.JobMetadata{
+		{JobKind: job.JobKindGeneric, ExpireAt: now + 7200, MaxQueueTime: 1},
+		{JobKind: job.JobKindGeneric, ExpireAt: now + 3600, MaxQueueTime: 7200},
+	} {
+		res, err := wp.EnqueueJob(&models.JobData{
+			Name:       "fake_job",
+			Parameters: map[string]interface{}{"name": "testing:v1"},
+			Metadata:   metadata,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The earlier one takes effect
+		at := metadata.ExpireAt
+		if metadata.MaxQueueTime < 3600 {
+			at = res.Stats.EnqueueTime + metadata.MaxQueueTime
+		}
+		if res.Stats.ExpireAt != at {
+			t.Fatalf("expect job %s expired at %d but got %d\n", res.Stats.JobID, at, res.Stats.ExpireAt)
+		}
+		expireAt[res.Stats.JobID] = at
+	}
+
+	<-time.After(2 * time.Second)
+	if err := wp.ResumeQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the jobs processed, the idle workers check the queue every 5s
+	<-time.After(6 * time.Second)
+
+	for jobID, at := range expireAt {
+		stats, err := wp.GetJobStats(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Stats.ExpireAt != at {
+			t.Fatalf("expect job %s expired at %d but got %d\n", jobID, at, stats.Stats.ExpireAt)
+		}
+
+		expected := job.JobStatusSuccess
+		if at < now+3600 {
+			expected = job.JobStatusExpired
+		}
+		if stats.Stats.Status != expected {
+			t.Fatalf("expect job %s with status %s but got %s\n", jobID, expected, stats.Stats.Status)
+		}
+	}
+}
+
+func TestJobCallbacks(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	jobs := map[string]interface{}{
+		"fake_job":          (*fakeJob)(nil),
+		"fake_runnable_job": (*fakeRunnableJob)(nil),
+		"fake_callback_job": (*fakeCallbackJob)(nil),
+	}
+	if err := wp.RegisterJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	params := map[string]interface{}{"name": "testing:v1"}
+	callback := &models.JobCallback{
+		Name:       "fake_callback_job",
+		Parameters: models.Parameters{"name": "testing:v1"},
+	}
+
+	// The callbacks are kept before the job is possible to be run
+	res, err := wp.EnqueueJob(&models.JobData{
+		Name:       "fake_job",
+		Parameters: params,
+		Metadata:   &models.JobMetadata{JobKind: job.JobKindGeneric},
+		JobCallbacks: models.JobCallbacks{
+			OnSuccess: callback,
+			OnFailure: callback,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case p := <-callbackParams:
+		if p["parent_id"] != res.Stats.JobID || p["parent_status"] != job.JobStatusSuccess || p["name"] != "testing:v1" {
+			t.Fatalf("expect on_success callback of job %s but got params %v\n", res.Stats.JobID, p)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expect on_success callback run")
+	}
+
+	res, err = wp.EnqueueJob(&models.JobData{
+		Name:       "fake_runnable_job",
+		Parameters: params,
+		Metadata:   &models.JobMetadata{JobKind: job.JobKindGeneric},
+		JobCallbacks: models.JobCallbacks{
+			OnSuccess:  callback,
+			OnComplete: callback,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(2 * time.Second)
+
+	if err := wp.StopJob(res.Stats.JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case p := <-callbackParams:
+		if p["parent_id"] != res.Stats.JobID || p["parent_status"] != job.JobStatusStopped {
+			t.Fatalf("expect on_complete callback of stopped job %s but got params %v\n", res.Stats.JobID, p)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expect on_complete callback run")
+	}
+
+	// The callbacks are fired only once
+	select {
+	case p := <-callbackParams:
+		t.Fatalf("expect no more callback run but got params %v\n", p)
+	case <-time.After(2 * time.Second):
+	}
+}
+
+func TestEnqueueBatch(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	// Keep the enqueued jobs pending
+	if err := wp.PauseQueue("fake_job"); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	jobs := []*models.JobData{
+		{
+			Name:       "fake_job",
+			Parameters: map[string]interface{}{"name": "testing:v1"},
+			Metadata:   &models.JobMetadata{JobKind: job.JobKindGeneric},
+		},
+		{
+			Name:       "fake_job",
+			Parameters: map[string]interface{}{"name": "testing:v2"},
+			Metadata:   &models.JobMetadata{JobKind: job.JobKindGeneric},
+		},
+		{
+			Name:       "fake_job",
+			Parameters: map[string]interface{}{"name": "testing:v3"},
+			Metadata:   &models.JobMetadata{JobKind: job.JobKindScheduled, ScheduleDelay: 600},
+		},
+	}
+	batch, err := wp.EnqueueBatch(jobs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Total != 3 || len(batch.Jobs) != 3 {
+		t.Fatalf("expect 3 jobs in the batch but got %d\n", batch.Total)
+	}
+	if batch.Jobs[2].JobKind != job.JobKindScheduled || batch.Jobs[2].RunAt == 0 {
+		t.Fatalf("expect the third job scheduled but got %+v\n", batch.Jobs[2])
+	}
+
+	conn := rPool.Get()
+	defer conn.Close()
+
+	ns := tests.GiveMeTestNamespace()
+	if n, err := redis.Int(conn.Do("LLEN", utils.KeyJobs(ns, "fake_job"))); err != nil || n != 2 {
+		t.Fatalf("expect 2 pending jobs but got %d: %v\n", n, err)
+	}
+	if n, err := redis.Int(conn.Do("ZCARD", utils.KeyScheduled(ns))); err != nil || n != 1 {
+		t.Fatalf("expect 1 scheduled job but got %d: %v\n", n, err)
+	}
+
+	// Wait for the job stats saved
+	<-time.After(500 * time.Millisecond)
+
+	got, err := wp.GetBatch(batch.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Total != 3 || got.Completed || got.Counts[job.JobStatusPending]+got.Counts[job.JobStatusScheduled] != 3 {
+		t.Fatalf("expect 3 waiting jobs in the uncompleted batch but got %+v\n", got)
+	}
+
+	stats, err := wp.GetJobStats(batch.Jobs[0].JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.BatchID != batch.BatchID {
+		t.Fatalf("expect job in batch %s but got '%s'\n", batch.BatchID, stats.Stats.BatchID)
+	}
+}
+
+func TestJobLocks(t *testing.T) {
+	ns := tests.GiveMeTestNamespace()
+	defer func() {
+		if err := tests.ClearAll(ns, redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	locks1 := newJobLocks(ns, rPool, "fake_job_ID_1")
+	locks2 := newJobLocks(ns, rPool, "fake_job_ID_2")
+	locks3 := newJobLocks(ns, rPool, "fake_job_ID_3")
+	defer locks1.releaseAll()
+	defer locks2.releaseAll()
+	defer locks3.releaseAll()
+
+	if _, err := locks1.acquire("fake_lock", 1, time.Second); err == nil {
+		t.Fatal("expect error of too short ttl but got nil")
+	}
+
+	// Lock
+	if ok, err := locks1.acquire("fake_lock", 1, 3*time.Second); err != nil || !ok {
+		t.Fatalf("expect lock acquired but got %v, %v\n", ok, err)
+	}
+	if ok, err := locks2.acquire("fake_lock", 1, 3*time.Second); err != nil || ok {
+		t.Fatalf("expect lock not acquired but got %v, %v\n", ok, err)
+	}
+
+	// The held lock is renewed
+	<-time.After(4 * time.Second)
+	if ok, err := locks2.acquire("fake_lock", 1, 3*time.Second); err != nil || ok {
+		t.Fatalf("expect renewed lock not acquired but got %v, %v\n", ok, err)
+	}
+
+	if err := locks1.release("fake_lock"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := locks2.acquire("fake_lock", 1, 3*time.Second); err != nil || !ok {
+		t.Fatalf("expect released lock acquired but got %v, %v\n", ok, err)
+	}
+
+	// Semaphore
+	for _, l := range []*jobLocks{locks1, locks2} {
+		if ok, err := l.acquire("fake_semaphore", 2, 0); err != nil || !ok {
+			t.Fatalf("expect semaphore acquired but got %v, %v\n", ok, err)
+		}
+	}
+	if ok, err := locks3.acquire("fake_semaphore", 2, 0); err != nil || ok {
+		t.Fatalf("expect exhausted semaphore not acquired but got %v, %v\n", ok, err)
+	}
+
+	// All are released when the job exits
+	locks2.releaseAll()
+	if _, err := locks2.acquire("fake_semaphore", 2, 0); err == nil {
+		t.Fatal("expect error of acquiring after exited but got nil")
+	}
+	if ok, err := locks3.acquire("fake_semaphore", 2, 0); err != nil || !ok {
+		t.Fatalf("expect semaphore acquired but got %v, %v\n", ok, err)
+	}
+	if ok, err := locks3.acquire("fake_lock", 1, 0); err != nil || !ok {
+		t.Fatalf("expect lock acquired but got %v, %v\n", ok, err)
+	}
+}
+
+func TestPeriodicExecutionLocks(t *testing.T) {
+	ns := tests.GiveMeTestNamespace()
+	defer func() {
+		if err := tests.ClearAll(ns, redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// The overlapping executions of the periodic job share the policy ID
+	now := time.Now().Unix()
+	execution1 := &work.Job{Name: "fake_job", ID: "fake_policy_ID", EnqueuedAt: now}
+	execution2 := &work.Job{Name: "fake_job", ID: "fake_policy_ID", EnqueuedAt: now + 60}
+
+	locks1 := newJobLocks(ns, rPool, lockHolder(execution1))
+	locks2 := newJobLocks(ns, rPool, lockHolder(execution2))
+	defer locks1.releaseAll()
+	defer locks2.releaseAll()
+
+	if ok, err := locks1.acquire("fake_lock", 1, 0); err != nil || !ok {
+		t.Fatalf("expect lock acquired but got %v, %v\n", ok, err)
+	}
+	if ok, err := locks2.acquire("fake_lock", 1, 0); err != nil || ok {
+		t.Fatalf("expect lock held by the other execution not acquired but got %v, %v\n", ok, err)
+	}
+
+	// The rerun of the same execution is another holder as well
+	if lockHolder(execution1) == lockHolder(execution1) {
+		t.Fatal("expect different holders of the runs of the same execution")
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	wp, _, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	original, err := wp.ClaimIdempotencyKey("key", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original != nil {
+		t.Fatal("expect the key claimed but got the original submission")
+	}
+
+	if _, err := wp.ClaimIdempotencyKey("key", "digest"); !errs.IsIdempotencyConflictError(err) {
+		t.Fatalf("expect conflict error of the in progress submission but got %v\n", err)
+	}
+
+	stats := models.JobStats{
+		Stats: &models.JobStatData{
+			JobID:  "fake_ID",
+			Status: job.JobStatusPending,
+		},
+	}
+	if err := wp.SaveIdempotencyKey("key", "digest", stats); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err = wp.ClaimIdempotencyKey("key", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original == nil || original.Stats.JobID != "fake_ID" {
+		t.Fatalf("expect the original submission 'fake_ID' but got %v\n", original)
+	}
+
+	if _, err := wp.ClaimIdempotencyKey("key", "another_digest"); !errs.IsIdempotencyConflictError(err) {
+		t.Fatalf("expect conflict error of the different request but got %v\n", err)
+	}
+
+	if err := wp.ReleaseIdempotencyKey("key"); err != nil {
+		t.Fatal(err)
+	}
+	if original, err = wp.ClaimIdempotencyKey("key", "another_digest"); err != nil || original != nil {
+		t.Fatalf("expect the released key claimed again but got %v, %v\n", original, err)
+	}
+}
+
+func TestListSchedule(t *testing.T) {
+	wp, sysCtx, cancel := createRedisWorkerPool()
+	defer func() {
+		if err := tests.ClearAll(tests.GiveMeTestNamespace(), redisPool.Get()); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer cancel()
+
+	if err := wp.RegisterJob("fake_job", (*fakeJob)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	go wp.Start()
+	time.Sleep(1 * time.Second)
+
+	params := map[string]interface{}{"name": "testing:v1"}
+	scheduled, err := wp.Schedule("fake_job", params, 600, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	periodic, err := wp.PeriodicallyEnqueue("fake_job", params, "0 0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(1 * time.Second)
+
+	now := time.Now().Unix()
+	schedule, err := wp.ListSchedule(now, now+3*3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var oneOff, projected int
+	for i, e := range schedule.Entries {
+		if i > 0 && e.RunAt < schedule.Entries[i-1].RunAt {
+			t.Fatal("expect the entries ordered by the run time")
+		}
+
+		switch e.JobKind {
+		case job.JobKindScheduled:
+			oneOff++
+			if e.JobID != scheduled.Stats.JobID {
+				t.Errorf("expect one-off run of job %s but got %s\n", scheduled.Stats.JobID, e.JobID)
+			}
+		case job.JobKindPeriodic:
+			projected++
+			if e.PolicyID != periodic.Stats.JobID || e.RunAt%3600 != 0 {
+				t.Errorf("expect hourly run of policy %s but got %s at %d\n", periodic.Stats.JobID, e.PolicyID, e.RunAt)
+			}
+		}
+
+		if e.ParamsDigest != schedule.Entries[0].ParamsDigest {
+			t.Error("expect the same digest of the same parameters")
+		}
+	}
+
+	if oneOff != 1 || projected < 3 {
+		t.Fatalf("expect 1 one-off run and at least 3 periodic runs but got %d and %d\n", oneOff, projected)
+	}
+
+	cancel()
+	sysCtx.WG.Wait()
+}
+
+func createRedisWorkerPool() (*GoCraftWorkPool, *env.Context, context.CancelFunc) {
+	ctx := context.Background()
+	ctx, cancel := context.WithCancel(ctx)
+	envCtx := &env.Context{
+		SystemContext: ctx,
+		WG:            new(sync.WaitGroup),
+		ErrorChan:     make(chan error, 1),
+		JobContext:    newContext(ctx),
+	}
+
+	return NewGoCraftWorkPool(envCtx, tests.GiveMeTestNamespace(), 3, rPool), envCtx, cancel
+}
+
+type fakeJob struct{}
+
+func (j *fakeJob) MaxFails() uint {
+	return 3
+}
+
+func (j *fakeJob) ShouldRetry() bool {
+	return true
+}
+
+func (j *fakeJob) Validate(params map[string]interface{}) error {
+	if p, ok := params["name"]; ok {
+		if p == "testing:v1" {
+			return nil
+		}
+	}
+
+	return errors.New("testing error")
+}
+
+func (j *fakeJob) Run(ctx env.JobContext, params map[string]interface{}) error {
+	return nil
+}
+
+type fakeUniqueJob struct{}
+
+func (j *fakeUniqueJob) MaxFails() uint {
+	return 3
+}
+
+func (j *fakeUniqueJob) ShouldRetry() bool {
+	return true
+}
+
+func (j *fakeUniqueJob) Validate(params map[string]interface{}) error {
+	if p, ok := params["name"]; ok {
+		if p == "testing:v1" {
+			return nil
+		}
+	}
+
+	return errors.New("testing error")
+}
+
+func (j *fakeUniqueJob) Run(ctx env.JobContext, params map[string]interface{}) error {
+	return nil
+}
+
+type fakeRunnableJob struct{}
+
+func (j *fakeRunnableJob) MaxFails() uint {
+	return 2
+}
+
+func (j *fakeRunnableJob) ShouldRetry() bool {
+	return true
+}
+
+func (j *fakeRunnableJob) Validate(params map[string]interface{}) error {
+	if p, ok := params["name"]; ok {
+		if p == "testing:v1" {
+			return nil
+		}
+	}
+
+	return errors.New("testing error")
+}
+
+func (j *fakeRunnableJob) Run(ctx env.JobContext, params map[string]interface{}) error {
+	tk := time.NewTicker(1 * time.Second)
+	defer tk.Stop()
+
+	for {
+		select {
+		case <-tk.C:
+			cmd, ok := ctx.OPCommand()
+			if ok {
+				if cmd == opm.CtlCommandStop {
+					return errs.JobStoppedError()
+				}
+
+				return errs.JobCancelledError()
+			}
+		case <-ctx.SystemContext().Done():
+			return nil
+		case <-time.After(1 * time.Minute):
+			return errors.New("fake job timeout")
+		}
+	}
+}
+
+type fakeLimitedJob struct {
+	fakeRunnableJob
+}
+
+func (j *fakeLimitedJob) MaxConcurrency() uint {
+	return 1
+}
+
+// The IDs of the child jobs enqueued by the fakeParentJob
+var childJobIDs = make(chan []string, 1)
+
+type fakeParentJob struct {
+	fakeJob
+}
+
+func (j *fakeParentJob) Run(ctx env.JobContext, params map[string]interface{}) error {
+	if _, err := ctx.Enqueue("fake_unknown_job", params, true); err == nil {
+		return errors.New("expect error of enqueuing unknown job")
+	}
+
+	ids := make([]string, 0, 2)
+	for _, name := range []string{"fake_job", "fake_runnable_job"} {
+		id, err := ctx.Enqueue(name, params, true)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	childJobIDs <- ids
+
+	return nil
+}
+
+// fakeClassifiedJob fails with the error classified by the parameter
+type fakeClassifiedJob struct {
+	fakeJob
+}
+
+func (j *fakeClassifiedJob) Run(ctx env.JobContext, params map[string]interface{}) error {
+	cause := errors.New("fake failure")
+	switch params["error"] {
+	case "permanent":
+		return errs.PermanentError(cause)
+	case "retry_after":
+		return errs.RetryAfterError(cause, 100*time.Second)
+	case "rate_limited":
+		return errs.RateLimitedError(cause, 100*time.Second)
+	case "panic":
+		panic(cause)
+	default:
+		return cause
+	}
+}
+
+// fakeRetrierJob is never retried by its retry policy
+type fakeRetrierJob struct {
+	fakeClassifiedJob
+}
+
+func (j *fakeRetrierJob) RetryPolicy() *models.RetryPolicy {
+	return &models.RetryPolicy{MaxAttempts: 1}
+}
+
+// fakeNoRetryJob does not retry in general
+type fakeNoRetryJob struct {
+	fakeClassifiedJob
+}
+
+func (j *fakeNoRetryJob) ShouldRetry() bool {
+	return false
+}
+
+// The parameters of the follow-up jobs run
+var callbackParams = make(chan map[string]interface{}, 2)
+
+type fakeCallbackJob struct {
+	fakeJob
+}
+
+func (j *fakeCallbackJob) Run(ctx env.JobContext, params map[string]interface{}) error {
+	callbackParams <- params
+	return nil
+}
+
+type fakeContext struct {
+	// System context
+	sysContext context.Context
+
+	// op command func
+	opCommandFunc job.CheckOPCmdFunc
+
+	// checkin func
+	checkInFunc job.CheckInFunc
+
+	// result func
+	resultFunc job.ResultFunc
+
+	// progress func
+	progressFunc job.ProgressFunc
+
+	// checkpoint funcs
+	saveCheckpointFunc job.SaveCheckpointFunc
+	loadCheckpointFunc job.LoadCheckpointFunc
+
+	// lock funcs
+	acquireLockFunc job.AcquireLockFunc
+	releaseLockFunc job.ReleaseLockFunc
+
+	// enqueue func
+	enqueueFunc job.EnqueueFunc
+
+	// other required information
+	properties map[string]interface{}
+}
+
+func newContext(sysCtx context.Context) *fakeContext {
+	return &fakeContext{
+		sysContext: sysCtx,
+		properties: make(map[string]interface{}),
+	}
+}
+
+// Build implements the same method in env.JobContext interface
+// This func will build the job execution context before running
+func (c *fakeContext) Build(dep env.JobData) (env.JobContext, error) {
+	jContext := &fakeContext{
+		sysContext: c.sysContext,
+		properties: make(map[string]interface{}),
+	}
+
+	// Copy properties
+	if len(c.properties) > 0 {
+		for k, v := range c.properties {
+			jContext.properties[k] = v
+		}
+	}
+
+	if opCommandFunc, ok := dep.ExtraData["opCommandFunc"]; ok {
+		if reflect.TypeOf(opCommandFunc).Kind() == reflect.Func {
+			if funcRef, ok := opCommandFunc.(job.CheckOPCmdFunc); ok {
+				jContext.opCommandFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.opCommandFunc == nil {
+		return nil, errors.New("failed to inject opCommandFunc")
+	}
+
+	if checkInFunc, ok := dep.ExtraData["checkInFunc"]; ok {
+		if reflect.TypeOf(checkInFunc).Kind() == reflect.Func {
+			if funcRef, ok := checkInFunc.(job.CheckInFunc); ok {
+				jContext.checkInFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.checkInFunc == nil {
+		return nil, errors.New("failed to inject checkInFunc")
+	}
+
+	if resultFunc, ok := dep.ExtraData["resultFunc"]; ok {
+		if reflect.TypeOf(resultFunc).Kind() == reflect.Func {
+			if funcRef, ok := resultFunc.(job.ResultFunc); ok {
+				jContext.resultFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.resultFunc == nil {
+		return nil, errors.New("failed to inject resultFunc")
+	}
+
+	if progressFunc, ok := dep.ExtraData["progressFunc"]; ok {
+		if reflect.TypeOf(progressFunc).Kind() == reflect.Func {
+			if funcRef, ok := progressFunc.(job.ProgressFunc); ok {
+				jContext.progressFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.progressFunc == nil {
+		return nil, errors.New("failed to inject progressFunc")
+	}
+
+	if saveCheckpointFunc, ok := dep.ExtraData["saveCheckpointFunc"]; ok {
+		if reflect.TypeOf(saveCheckpointFunc).Kind() == reflect.Func {
+			if funcRef, ok := saveCheckpointFunc.(job.SaveCheckpointFunc); ok {
+				jContext.saveCheckpointFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.saveCheckpointFunc == nil {
+		return nil, errors.New("failed to inject saveCheckpointFunc")
+	}
+
+	if loadCheckpointFunc, ok := dep.ExtraData["loadCheckpointFunc"]; ok {
+		if reflect.TypeOf(loadCheckpointFunc).Kind() == reflect.Func {
+			if funcRef, ok := loadCheckpointFunc.(job.LoadCheckpointFunc); ok {
+				jContext.loadCheckpointFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.loadCheckpointFunc == nil {
+		return nil, errors.New("failed to inject loadCheckpointFunc")
+	}
+
+	if acquireLockFunc, ok := dep.ExtraData["acquireLockFunc"]; ok {
+		if reflect.TypeOf(acquireLockFunc).Kind() == reflect.Func {
+			if funcRef, ok := acquireLockFunc.(job.AcquireLockFunc); ok {
+				jContext.acquireLockFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.acquireLockFunc == nil {
+		return nil, errors.New("failed to inject acquireLockFunc")
+	}
+
+	if releaseLockFunc, ok := dep.ExtraData["releaseLockFunc"]; ok {
+		if reflect.TypeOf(releaseLockFunc).Kind() == reflect.Func {
+			if funcRef, ok := releaseLockFunc.(job.ReleaseLockFunc); ok {
+				jContext.releaseLockFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.releaseLockFunc == nil {
+		return nil, errors.New("failed to inject releaseLockFunc")
+	}
+
+	if enqueueFunc, ok := dep.ExtraData["enqueueFunc"]; ok {
+		if reflect.TypeOf(enqueueFunc).Kind() == reflect.Func {
+			if funcRef, ok := enqueueFunc.(job.EnqueueFunc); ok {
+				jContext.enqueueFunc = funcRef
+			}
+		}
+	}
+
+	if jContext.enqueueFunc == nil {
+		return nil, errors.New("failed to inject enqueueFunc")
+	}
+
+	return jContext, nil
+}
+
+// Get implements the same method in env.JobContext interface
+func (c *fakeContext) Get(prop string) (interface{}, bool) {
+	v, ok := c.properties[prop]
+	return v, ok
+}
 
 // SystemContext implements the same method in env.JobContext interface
 func (c *fakeContext) SystemContext() context.Context {
@@ -399,6 +2092,96 @@ func (c *fakeContext) Checkin(status string) error {
 	return nil
 }
 
+// SetResult sets the structured result object of the job
+func (c *fakeContext) SetResult(result interface{}) error {
+	if c.resultFunc != nil {
+		return c.resultFunc(result)
+	}
+
+	return errors.New("nil result function")
+}
+
+// ReportProgress reports the structured progress of the job
+func (c *fakeContext) ReportProgress(current, total uint64, phase, message string) error {
+	if c.progressFunc != nil {
+		return c.progressFunc(current, total, phase, message)
+	}
+
+	return errors.New("nil progress function")
+}
+
+// SaveCheckpoint persists the checkpoint of the job with the specified key
+func (c *fakeContext) SaveCheckpoint(key string, value interface{}) error {
+	if c.saveCheckpointFunc != nil {
+		return c.saveCheckpointFunc(key, value)
+	}
+
+	return errors.New("nil save checkpoint function")
+}
+
+// LoadCheckpoint loads the checkpoint saved by the previous attempts of the job
+func (c *fakeContext) LoadCheckpoint(key string, value interface{}) (bool, error) {
+	if c.loadCheckpointFunc == nil {
+		return false, errors.New("nil load checkpoint function")
+	}
+
+	data, err := c.loadCheckpointFunc(key)
+	if err != nil || data == nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, value)
+}
+
+// AcquireLock tries to acquire the named lock without blocking
+func (c *fakeContext) AcquireLock(name string, ttl time.Duration) (bool, error) {
+	return c.AcquireSemaphore(name, 1, ttl)
+}
+
+// ReleaseLock releases the named lock held by the job
+func (c *fakeContext) ReleaseLock(name string) error {
+	return c.ReleaseSemaphore(name)
+}
+
+// AcquireSemaphore tries to acquire the named counting semaphore without blocking
+func (c *fakeContext) AcquireSemaphore(name string, limit uint, ttl time.Duration) (bool, error) {
+	if c.acquireLockFunc == nil {
+		return false, errors.New("nil acquire lock function")
+	}
+
+	return c.acquireLockFunc(name, limit, ttl)
+}
+
+// ReleaseSemaphore releases the named semaphore held by the job
+func (c *fakeContext) ReleaseSemaphore(name string) error {
+	if c.releaseLockFunc == nil {
+		return errors.New("nil release lock function")
+	}
+
+	return c.releaseLockFunc(name)
+}
+
+// Enqueue enqueues the child job
+func (c *fakeContext) Enqueue(jobName string, params map[string]interface{}, await bool) (string, error) {
+	if c.enqueueFunc == nil {
+		return "", errors.New("nil enqueue function")
+	}
+
+	return c.enqueueFunc(jobName, params, 0, await)
+}
+
+// Schedule enqueues the child job to run after the specified seconds
+func (c *fakeContext) Schedule(jobName string, params map[string]interface{}, runAfterSeconds uint64, await bool) (string, error) {
+	if c.enqueueFunc == nil {
+		return "", errors.New("nil enqueue function")
+	}
+	if runAfterSeconds == 0 {
+		return "", errors.New("delay of the scheduled job should be greater than 0")
+	}
+
+	return c.enqueueFunc(jobName, params, runAfterSeconds, await)
+}
+
 // OPCommand return the control operational command like stop/cancel if have
 func (c *fakeContext) OPCommand() (string, bool) {
 	if c.opCommandFunc != nil {
@@ -0,0 +1,135 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/errs"
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	// Default delay (seconds) before the first retry of the retry policy
+	defaultRetryDelay = 15
+	// Max delay (seconds) before the retry if the retry policy has no cap
+	maxRetryDelay = math.MaxInt32
+	// The failed jobs are retried or put into the dead queue by the RedisJob regarding of the retry policy,
+	// so the backend pool is not going to kill them by the fails.
+	unlimitedFails = math.MaxInt32
+)
+
+// sendRetryPolicy sends the command keeping the retry policy of the job which overrides the one of the job type.
+func sendRetryPolicy(conn redis.Conn, namespace string, jobID string, policy *models.RetryPolicy) error {
+	if policy == nil {
+		return nil
+	}
+
+	rawJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	return conn.Send("HSET", utils.KeyJobStats(namespace, jobID), "retry_policy", rawJSON)
+}
+
+// retryPolicy returns the retry policy of the job, nil if neither the job nor its type has one.
+// The policy requested with the job takes precedence and it's reported as well.
+func (rj *RedisJob) retryPolicy(jobID string) (*models.RetryPolicy, bool) {
+	conn := rj.redisPool.Get()
+	defer conn.Close()
+
+	rawJSON, err := redis.Bytes(conn.Do("HGET", utils.KeyJobStats(rj.namespace, jobID), "retry_policy"))
+	if err == nil {
+		policy := &models.RetryPolicy{}
+		if err := json.Unmarshal(rawJSON, policy); err == nil {
+			return policy, true
+		}
+		logger.Errorf("Failed to decode retry policy of job %s: %s\n", jobID, err)
+	} else if err != redis.ErrNil {
+		logger.Errorf("Failed to get retry policy of job %s: %s\n", jobID, err)
+	}
+
+	return rj.policy, false
+}
+
+// maxFails returns the max number of the fails of the job before it's put into the dead queue.
+func maxFails(j job.Interface, policy *models.RetryPolicy) int64 {
+	if policy != nil && policy.MaxAttempts > 0 {
+		return int64(policy.MaxAttempts)
+	}
+
+	n := j.MaxFails()
+	if n == 0 {
+		n = 4 // Consistent with backend worker pool
+	}
+
+	return int64(n)
+}
+
+// scheduleRetry decides when the failed job is retried and records it with the job stats.
+// The delay is applied by the backoff of the job when the job is put into the retry queue.
+func (rj *RedisJob) scheduleRetry(j *work.Job, err error, policy *models.RetryPolicy) {
+	wait := retryBackoff(policy, j.Fails+1) // the fail is not returned to backend pool yet
+	if delay, ok := errs.RetryDelay(err); ok && delay > 0 && errs.IsRetryAfterError(err) {
+		wait = int64((delay + time.Second - 1) / time.Second)
+	}
+
+	rj.retryDelays.Store(j.ID, wait)
+	if err := rj.statsManager.SetNextRetryAt(j.ID, time.Now().Unix()+wait); err != nil {
+		logger.Errorf("Failed to record the next retry time of job %s: %s\n", j.ID, err)
+	}
+}
+
+// backoff returns the seconds to wait before retrying the failed job.
+func (rj *RedisJob) backoff(j *work.Job) int64 {
+	if v, ok := rj.retryDelays.Load(j.ID); ok {
+		rj.retryDelays.Delete(j.ID)
+		return v.(int64)
+	}
+
+	return retryBackoff(nil, j.Fails)
+}
+
+// retryBackoff returns the seconds to wait before the retry after the specified fails.
+func retryBackoff(policy *models.RetryPolicy, fails int64) int64 {
+	if policy == nil {
+		// Consistent with the default backoff of backend worker pool
+		return (fails * fails * fails * fails) + 15 + (rand.Int63n(30) * (fails + 1))
+	}
+
+	base := float64(policy.Delay)
+	if base <= 0 {
+		base = defaultRetryDelay
+	}
+
+	delay := base
+	switch policy.Backoff {
+	case job.RetryBackoffFixed:
+		// Keep the base delay
+	case job.RetryBackoffLinear:
+		delay = base * float64(fails)
+	default:
+		delay = base * math.Pow(2, float64(fails-1))
+	}
+
+	if policy.Jitter > 0 {
+		delay += delay * policy.Jitter * (2*rand.Float64() - 1)
+	}
+
+	limit := float64(maxRetryDelay)
+	if policy.MaxDelay > 0 && float64(policy.MaxDelay) < limit {
+		limit = float64(policy.MaxDelay)
+	}
+
+	return int64(math.Ceil(math.Max(0, math.Min(delay, limit))))
+}
@@ -0,0 +1,178 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"github.com/robfig/cron"
+
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/period"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	// The max number of the entries returned in one schedule listing
+	maxScheduleEntries = 1000
+	// The batch size of reading the scheduled set
+	scheduledReadCount = 500
+)
+
+// ListSchedule lists the upcoming runs in the time window [from, to] (unix seconds).
+// The one-off runs come from the scheduled set and the runs of the periodic jobs
+// are projected from the cron spec of the policies.
+func (gcwp *GoCraftWorkPool) ListSchedule(from, to int64) (models.JobSchedule, error) {
+	schedule := models.JobSchedule{
+		From:    from,
+		To:      to,
+		Entries: make([]*models.ScheduleEntry, 0),
+	}
+
+	policies := gcwp.scheduler.Policies()
+	policyIDs := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		policyIDs[p.PolicyID] = true
+	}
+
+	entries, err := gcwp.scheduledEntries(from, to, policyIDs)
+	if err != nil {
+		return schedule, err
+	}
+
+	for _, p := range policies {
+		entries = append(entries, projectPolicy(p, from, to)...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RunAt != entries[j].RunAt {
+			return entries[i].RunAt < entries[j].RunAt
+		}
+		if entries[i].JobName != entries[j].JobName {
+			return entries[i].JobName < entries[j].JobName
+		}
+
+		return entries[i].JobID+entries[i].PolicyID < entries[j].JobID+entries[j].PolicyID
+	})
+
+	if len(entries) > maxScheduleEntries {
+		entries = entries[:maxScheduleEntries]
+		schedule.Truncated = true
+	}
+	schedule.Entries = entries
+
+	return schedule, nil
+}
+
+// scheduledEntries reads the one-off runs in the time window from the scheduled set.
+// The instances enqueued ahead for the periodic policies are skipped as they're projected from the policies.
+// At most maxScheduleEntries+1 entries are read to tell if the result is truncated.
+func (gcwp *GoCraftWorkPool) scheduledEntries(from, to int64, policyIDs map[string]bool) ([]*models.ScheduleEntry, error) {
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	entries := make([]*models.ScheduleEntry, 0)
+	for offset := 0; len(entries) <= maxScheduleEntries; offset += scheduledReadCount {
+		vals, err := redis.Values(conn.Do(
+			"ZRANGEBYSCORE",
+			utils.KeyScheduled(gcwp.namespace),
+			from,
+			to,
+			"WITHSCORES",
+			"LIMIT",
+			offset,
+			scheduledReadCount,
+		))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i+1 < len(vals); i += 2 {
+			rawJSON, err := redis.Bytes(vals[i], nil)
+			if err != nil {
+				return nil, err
+			}
+			runAt, err := redis.Int64(vals[i+1], nil)
+			if err != nil {
+				return nil, err
+			}
+
+			j := &work.Job{}
+			if err := json.Unmarshal(rawJSON, j); err != nil {
+				// Skip the malformed ones
+				continue
+			}
+			if policyIDs[j.ID] {
+				continue
+			}
+
+			entries = append(entries, &models.ScheduleEntry{
+				RunAt:        runAt,
+				JobName:      j.Name,
+				JobKind:      job.JobKindScheduled,
+				JobID:        j.ID,
+				ParamsDigest: paramsDigest(j.Args),
+			})
+		}
+
+		if len(vals) < scheduledReadCount*2 {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// projectPolicy projects the runs of the periodic policy in the time window.
+// At most maxScheduleEntries+1 runs are projected to tell if the result is truncated.
+func projectPolicy(p *period.PeriodicJobPolicy, from, to int64) []*models.ScheduleEntry {
+	entries := make([]*models.ScheduleEntry, 0)
+
+	schedule, err := cron.Parse(p.CronSpec)
+	if err != nil {
+		logger.Errorf("cron spec '%s' of periodic policy %s is not valid", p.CronSpec, p.PolicyID)
+		return entries
+	}
+
+	digest := paramsDigest(p.JobParameters)
+	// The next run is strictly after the specified time
+	for t := schedule.Next(time.Unix(from, 0).Add(-time.Second)); !t.IsZero() && t.Unix() <= to; t = schedule.Next(t) {
+		entries = append(entries, &models.ScheduleEntry{
+			RunAt:        t.Unix(),
+			JobName:      p.JobName,
+			JobKind:      job.JobKindPeriodic,
+			PolicyID:     p.PolicyID,
+			ParamsDigest: digest,
+		})
+
+		if len(entries) > maxScheduleEntries {
+			break
+		}
+	}
+
+	return entries
+}
+
+// paramsDigest returns the sha256 digest of the json of the job parameters.
+// The map keys are sorted by the json encoding so the same parameters get the same digest.
+func paramsDigest(params map[string]interface{}) string {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,43 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/opm"
+)
+
+// jobTracker handles the jobs which are not going to run again.
+// The awaiting parent jobs are completed and the follow-up jobs are enqueued.
+type jobTracker struct {
+	namespace    string
+	redisPool    *redis.Pool
+	statsManager opm.JobStatsManager
+	backendPool  Interface
+}
+
+// newJobTracker is constructor of jobTracker
+func newJobTracker(namespace string, redisPool *redis.Pool, statsManager opm.JobStatsManager, backendPool Interface) *jobTracker {
+	return &jobTracker{
+		namespace:    namespace,
+		redisPool:    redisPool,
+		statsManager: statsManager,
+		backendPool:  backendPool,
+	}
+}
+
+// ended tracks the job ended with the terminal status.
+func (jt *jobTracker) ended(jobID string, status string) {
+	conn := jt.redisPool.Get()
+	defer conn.Close()
+
+	jt.jobEnded(conn, jobID, status)
+}
+
+func (jt *jobTracker) jobEnded(conn redis.Conn, jobID string, status string) {
+	jt.childEnded(conn, jobID, status == job.JobStatusSuccess)
+	jt.fireCallbacks(conn, jobID, status)
+	jt.statsManager.BatchJobEnded(jobID)
+}
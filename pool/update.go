@@ -0,0 +1,152 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// replaceWaitingJobScript replaces the job in the pending job list or the job set
+// only if it's still the same one as found before.
+// The job is moved to the scheduled set if the score is specified.
+//
+// KEYS[1]: key of the pending job list or the job set where the job is found
+// KEYS[2]: key of the scheduled set
+// ARGV[1]: the raw json of the job found before
+// ARGV[2]: the raw json of the updated job
+// ARGV[3]: the score in the scheduled set, empty to keep the job in the pending job list
+//
+// Returns: 1 if replaced or 0 if the job has been changed or fetched
+var replaceWaitingJobScript = redis.NewScript(2, `
+local t = redis.call('TYPE', KEYS[1])
+if type(t) == 'table' then
+  t = t['ok']
+end
+
+if t == 'zset' then
+  if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+    return 0
+  end
+
+  redis.call('ZREM', KEYS[1], ARGV[1])
+  redis.call('ZADD', KEYS[2], ARGV[3], ARGV[2])
+  return 1
+end
+
+if t == 'list' then
+  if ARGV[3] == '' then
+    -- Keep the position of the job in the list
+    if redis.call('LINSERT', KEYS[1], 'BEFORE', ARGV[1], ARGV[2]) <= 0 then
+      return 0
+    end
+    redis.call('LREM', KEYS[1], 1, ARGV[1])
+    return 1
+  end
+
+  if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+    return 0
+  end
+  redis.call('ZADD', KEYS[2], ARGV[3], ARGV[2])
+  return 1
+end
+
+return 0
+`)
+
+// UpdateJob changes the parameters and/or the run time of the job which has not started yet.
+// The job keeps its ID and the pending job moves to the scheduled set if the run time is changed.
+func (gcwp *GoCraftWorkPool) UpdateJob(jobID string, params models.Parameters, runAt int64) (models.JobStats, error) {
+	if utils.IsEmptyStr(jobID) {
+		return models.JobStats{}, errors.New("empty job ID")
+	}
+
+	theJob, err := gcwp.statsManager.Retrieve(jobID)
+	if err != nil {
+		return models.JobStats{}, err
+	}
+
+	stats := theJob.Stats
+	if stats.JobKind == job.JobKindPeriodic {
+		return models.JobStats{}, fmt.Errorf("periodic job '%s' can not be updated, submit a new policy instead", jobID)
+	}
+	if stats.Status != job.JobStatusPending && stats.Status != job.JobStatusScheduled {
+		return models.JobStats{}, fmt.Errorf("job '%s' with status '%s' can not be updated", jobID, stats.Status)
+	}
+	if params != nil && stats.IsUnique {
+		// The uniqueness is bound to the parameters
+		return models.JobStats{}, fmt.Errorf("parameters of the unique job '%s' can not be changed", jobID)
+	}
+
+	places := []waitingPlace{pendingList(utils.KeyJobs(gcwp.namespace, stats.JobName))}
+	if !utils.IsEmptyStr(stats.PartitionKey) {
+		if runAt > 0 {
+			// The job can not leave the order of the partition
+			return models.JobStats{}, fmt.Errorf("run time of the partitioned job '%s' can not be changed", jobID)
+		}
+		places = append(places, partitionBacklog(utils.KeyPartition(gcwp.namespace, stats.PartitionKey)))
+	}
+
+	scheduledKey := utils.KeyScheduled(gcwp.namespace)
+	places = append(places, jobSet(scheduledKey, stats.RunAt))
+	place, rawJSON, score, err := gcwp.lookupWaitingJob(jobID, false, places...)
+	if err != nil {
+		return models.JobStats{}, err
+	}
+	if rawJSON == nil {
+		return models.JobStats{}, fmt.Errorf("job '%s' is not waiting in the pending queue or the scheduled set", jobID)
+	}
+
+	j := &work.Job{}
+	if err := json.Unmarshal(rawJSON, j); err != nil {
+		return models.JobStats{}, err
+	}
+	if params != nil {
+		j.Args = params
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return models.JobStats{}, err
+	}
+
+	if runAt > 0 {
+		score = strconv.FormatInt(runAt, 10)
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	replaced, err := redis.Int(replaceWaitingJobScript.Do(conn, place.key, scheduledKey, rawJSON, data, score))
+	if err != nil {
+		return models.JobStats{}, err
+	}
+	if replaced == 0 {
+		return models.JobStats{}, fmt.Errorf("job '%s' has been started or changed by others", jobID)
+	}
+
+	if runAt > 0 {
+		if err := gcwp.statsManager.SetRunAt(jobID, runAt); err != nil {
+			return models.JobStats{}, err
+		}
+		stats.RunAt = runAt
+
+		if stats.Status == job.JobStatusPending {
+			gcwp.statsManager.SetJobStatus(jobID, job.JobStatusScheduled)
+			stats.Status = job.JobStatusScheduled
+		}
+	}
+	stats.UpdateTime = time.Now().Unix()
+
+	return theJob, nil
+}
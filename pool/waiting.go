@@ -0,0 +1,210 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/utils"
+)
+
+const (
+	// The max number of the jobs checked by one lookup, the list is looked up round by round
+	waitingJobLookupSize = 500
+	// The tolerance (seconds) of the score of the job in the job set as the score recorded
+	// with the job stats may be a bit earlier than the one in the set, e.g: 'next_retry_at' and 'die_at'
+	waitingJobScoreSlack = 5
+)
+
+// lookupWaitingJobScript finds the first job with the ID in the index range of the list
+// or the score range of the job set, and removes it if asked.
+//
+// KEYS[1]: key of the pending job list, the partition backlog or the job set
+// ARGV[1]: the job ID
+// ARGV[2]: the start index of the list or the min score of the job set
+// ARGV[3]: the stop index of the list or the max score of the job set
+// ARGV[4]: the max number of the jobs checked in the job set
+// ARGV[5]: '1' to remove the found job
+//
+// Returns: {raw json of the job, score} if found, the score is empty for the list,
+// otherwise the number of the checked jobs
+var lookupWaitingJobScript = redis.NewScript(1, `
+local t = redis.call('TYPE', KEYS[1])
+if type(t) == 'table' then
+  t = t['ok']
+end
+
+local items
+if t == 'list' then
+  items = redis.call('LRANGE', KEYS[1], ARGV[2], ARGV[3])
+elseif t == 'zset' then
+  items = redis.call('ZRANGEBYSCORE', KEYS[1], ARGV[2], ARGV[3], 'LIMIT', 0, ARGV[4])
+else
+  return 0
+end
+
+for _, raw in ipairs(items) do
+  local ok, j = pcall(cjson.decode, raw)
+  if ok and j['id'] == ARGV[1] then
+    local score = ''
+    if t == 'zset' then
+      score = redis.call('ZSCORE', KEYS[1], raw)
+      if ARGV[5] == '1' then
+        redis.call('ZREM', KEYS[1], raw)
+      end
+    elseif ARGV[5] == '1' then
+      redis.call('LREM', KEYS[1], 1, raw)
+    end
+    return {raw, score}
+  end
+end
+
+return #items
+`)
+
+// waitingPlace is the list or the job set where the job may be waiting.
+type waitingPlace struct {
+	key string
+	// The score range of the job set, empty for the list
+	min string
+	max string
+	// The jobs of the list are fetched from the head, like the partition backlog
+	headFirst bool
+}
+
+// pendingList returns the place of the pending job list, the jobs are fetched from the tail.
+func pendingList(key string) waitingPlace {
+	return waitingPlace{key: key}
+}
+
+// partitionBacklog returns the place of the partition backlog, the jobs are fetched from the head.
+func partitionBacklog(key string) waitingPlace {
+	return waitingPlace{key: key, headFirst: true}
+}
+
+// jobSet returns the place of the job set where the job is expected with the score,
+// the earliest jobs of the set are looked up if the score is unknown.
+func jobSet(key string, score int64) waitingPlace {
+	if score <= 0 {
+		return jobSetBefore(key, 0)
+	}
+
+	return waitingPlace{
+		key: key,
+		min: strconv.FormatInt(score-waitingJobScoreSlack, 10),
+		max: strconv.FormatInt(score+waitingJobScoreSlack, 10),
+	}
+}
+
+// jobSetBefore returns the place of the job set where the job is expected not later than the score,
+// 0 for any time.
+func jobSetBefore(key string, score int64) waitingPlace {
+	max := "+inf"
+	if score > 0 {
+		max = strconv.FormatInt(score, 10)
+	}
+
+	return waitingPlace{key: key, min: "-inf", max: max}
+}
+
+// isList checks if the place is a list.
+func (wp waitingPlace) isList() bool {
+	return utils.IsEmptyStr(wp.min)
+}
+
+// lookupWaitingJob finds the job in the first place where it's existing and removes it if asked.
+// The list is looked up round by round from the end opposite to the one the jobs are fetched from,
+// so it does not block redis and the jobs are not missed when the list changes meanwhile.
+// Returns the place, the raw json of the job and the score (empty for the list), or nil raw json if not found.
+func (gcwp *GoCraftWorkPool) lookupWaitingJob(jobID string, remove bool, places ...waitingPlace) (waitingPlace, []byte, string, error) {
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	for _, place := range places {
+		if !place.isList() {
+			rawJSON, score, _, err := lookupWaitingJobIn(conn, place.key, jobID, place.min, place.max, remove)
+			if err != nil || rawJSON != nil {
+				return place, rawJSON, score, err
+			}
+
+			continue
+		}
+
+		for offset := 0; ; offset += waitingJobLookupSize {
+			start, stop := offset, offset+waitingJobLookupSize-1
+			if place.headFirst {
+				start, stop = -stop-1, -offset-1
+			}
+
+			rawJSON, score, checked, err := lookupWaitingJobIn(conn, place.key, jobID, start, stop, remove)
+			if err != nil || rawJSON != nil {
+				return place, rawJSON, score, err
+			}
+
+			if checked < waitingJobLookupSize {
+				break
+			}
+		}
+	}
+
+	return waitingPlace{}, nil, "", nil
+}
+
+// lookupWaitingJobIn finds the job in the range of the list or the job set and removes it if asked.
+// Returns the raw json of the job and the score if found, otherwise the number of the checked jobs.
+func lookupWaitingJobIn(conn redis.Conn, key string, jobID string, start, stop interface{}, remove bool) ([]byte, string, int, error) {
+	flag := "0"
+	if remove {
+		flag = "1"
+	}
+
+	reply, err := lookupWaitingJobScript.Do(conn, key, jobID, start, stop, waitingJobLookupSize, flag)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if checked, ok := reply.(int64); ok {
+		return nil, "", int(checked), nil
+	}
+
+	vals, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	var (
+		rawJSON []byte
+		score   string
+	)
+	if _, err := redis.Scan(vals, &rawJSON, &score); err != nil {
+		return nil, "", 0, err
+	}
+
+	if remove {
+		logger.Debugf("Job %s is removed from %s\n", jobID, key)
+	}
+
+	return rawJSON, score, 0, nil
+}
+
+// restoreWaitingJob puts the removed job back to the place where it's waiting,
+// the job is put to the end of the list where the jobs are fetched from.
+func (gcwp *GoCraftWorkPool) restoreWaitingJob(place waitingPlace, rawJSON []byte, score string) error {
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	var err error
+	switch {
+	case !place.isList():
+		_, err = conn.Do("ZADD", place.key, score, rawJSON)
+	case place.headFirst:
+		_, err = conn.Do("LPUSH", place.key, rawJSON)
+	default:
+		_, err = conn.Do("RPUSH", place.key, rawJSON)
+	}
+
+	return err
+}
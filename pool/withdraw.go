@@ -0,0 +1,183 @@
+// Copyright Project Harbor Authors. All rights reserved.
+
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/robfig/cron"
+
+	"github.com/Colstuwjx/job/impl/job"
+	"github.com/Colstuwjx/job/logger"
+	"github.com/Colstuwjx/job/models"
+	"github.com/Colstuwjx/job/opm"
+	"github.com/Colstuwjx/job/period"
+	"github.com/Colstuwjx/job/utils"
+)
+
+// The error message kept with the cancelled job in the dead queue
+const cancelledJobErr = "cancelled before running"
+
+// withdrawJob removes the job which is not running yet from the place where it's waiting
+// and updates the status with the command.
+// The cancelled job is put into the dead queue to be resumed later like the cancelled running job.
+func (gcwp *GoCraftWorkPool) withdrawJob(stats *models.JobStatData, command string) error {
+	places := []waitingPlace{
+		pendingList(utils.KeyJobs(gcwp.namespace, stats.JobName)),
+		jobSet(utils.KeyScheduled(gcwp.namespace), stats.RunAt),
+		jobSet(utils.KeyRetry(gcwp.namespace), stats.NextRetryAt),
+	}
+	if !utils.IsEmptyStr(stats.PartitionKey) {
+		// The job may be waiting in the partition backlog
+		places = append([]waitingPlace{partitionBacklog(utils.KeyPartition(gcwp.namespace, stats.PartitionKey))}, places...)
+	}
+	if command == opm.CtlCommandStop {
+		// The cancelled job waiting for resuming is in the dead queue
+		places = append(places, jobSet(utils.KeyDead(gcwp.namespace), stats.DieAt))
+	}
+
+	place, rawJSON, score, err := gcwp.lookupWaitingJob(stats.JobID, true, places...)
+	if err != nil {
+		return err
+	}
+
+	if rawJSON == nil {
+		if stats.Status == job.JobStatusPending {
+			// The job may be fetched by a worker but not started yet,
+			// the command is checked right before running it.
+			return gcwp.statsManager.SendCommand(stats.JobID, command)
+		}
+
+		return fmt.Errorf("job '%s' with status '%s' is not waiting in any queue", stats.JobID, stats.Status)
+	}
+
+	status := job.JobStatusStopped
+	if command == opm.CtlCommandCancel {
+		status = job.JobStatusCancelled
+
+		diedAt, err := gcwp.buryJob(rawJSON)
+		if err != nil {
+			// Put the job back, otherwise it's lost
+			if rerr := gcwp.restoreWaitingJob(place, rawJSON, score); rerr != nil {
+				logger.Errorf("Failed to restore job %s to %s: %s\n", stats.JobID, place.key, rerr)
+			}
+
+			return err
+		}
+
+		gcwp.statsManager.DieAt(stats.JobID, diedAt)
+	}
+
+	gcwp.statsManager.SetJobStatus(stats.JobID, status)
+
+	if !utils.IsEmptyStr(stats.PartitionKey) {
+		// Let the next job of the partition run if the withdrawn one is the dispatched one
+		if err := gcwp.releasePartition(stats.PartitionKey, stats.JobID); err != nil {
+			logger.Errorf("Failed to release partition %s held by job %s: %s\n", stats.PartitionKey, stats.JobID, err)
+		}
+	}
+
+	// The withdrawn job is not going to run, let the parent job know if it's awaited
+	// and enqueue the follow-up jobs
+	gcwp.tracker.ended(stats.JobID, status)
+
+	return nil
+}
+
+// withdrawPeriodicExecution removes the current execution of the periodic job from the pending job list
+// or the earliest one from the scheduled set, the periodic job policy is not touched.
+// Returns true if the execution is found and removed.
+func (gcwp *GoCraftWorkPool) withdrawPeriodicExecution(stats *models.JobStatData) (bool, error) {
+	// The earliest execution is not later than the next time of the cron spec,
+	// all of them are scheduled within the horizon anyway.
+	next := time.Now().Add(periodicEnqueuerHorizon)
+	if schedule, err := cron.Parse(stats.CronSpec); err == nil {
+		next = schedule.Next(time.Now())
+	}
+
+	pendingKey, scheduledKey := utils.KeyJobs(gcwp.namespace, stats.JobName), utils.KeyScheduled(gcwp.namespace)
+	_, rawJSON, _, err := gcwp.lookupWaitingJob(
+		stats.JobID,
+		false,
+		pendingList(pendingKey),
+		jobSetBefore(scheduledKey, next.Unix()),
+	)
+	if err != nil || rawJSON == nil {
+		return false, err
+	}
+
+	// Mark the execution withdrawn before removing it, so the periodic enqueuer does not put it back
+	j := &work.Job{}
+	if err := json.Unmarshal(rawJSON, j); err != nil {
+		return false, err
+	}
+	if err := gcwp.withdrawExecution(stats.JobID, j.EnqueuedAt); err != nil {
+		return false, err
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("ZREM", scheduledKey, rawJSON)
+	conn.Send("LREM", pendingKey, 1, rawJSON)
+	if _, err := conn.Do("EXEC"); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// withdrawExecution marks the execution of the periodic job at the epoch withdrawn,
+// so it's neither scheduled again nor run.
+func (gcwp *GoCraftWorkPool) withdrawExecution(policyID string, epoch int64) error {
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	return period.WithdrawExecution(conn, gcwp.namespace, policyID, epoch)
+}
+
+// withdrawn checks if the job is the withdrawn execution of the periodic job.
+// The job is run if it can not be checked.
+func (rj *RedisJob) withdrawn(j *work.Job) bool {
+	conn := rj.redisPool.Get()
+	defer conn.Close()
+
+	yes, err := period.IsExecutionWithdrawn(conn, rj.namespace, j.ID, j.EnqueuedAt)
+	if err != nil {
+		logger.Errorf("Failed to check if job '%s:%s' is withdrawn: %s\n", j.Name, j.ID, err)
+		return false
+	}
+
+	return yes
+}
+
+// buryJob puts the withdrawn job into the dead queue.
+// Returns the time when the job dies.
+func (gcwp *GoCraftWorkPool) buryJob(rawJSON []byte) (int64, error) {
+	j := &work.Job{}
+	if err := json.Unmarshal(rawJSON, j); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().Unix()
+	j.LastErr = cancelledJobErr
+	j.FailedAt = now
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return 0, err
+	}
+
+	conn := gcwp.redisPool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("ZADD", utils.KeyDead(gcwp.namespace), now, data); err != nil {
+		return 0, err
+	}
+
+	return now, nil
+}
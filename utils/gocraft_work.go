@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
@@ -48,6 +49,25 @@ func RedisKeyDead(namespace string) string {
 	return RedisNamespacePrefix(namespace) + "dead"
 }
 
+// RedisKeyUniqueJob returns key of the unique job with the arguments.
+func RedisKeyUniqueJob(namespace, jobName string, args map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(RedisNamespacePrefix(namespace))
+	buf.WriteString("unique:")
+	buf.WriteString(jobName)
+	buf.WriteRune(':')
+
+	if args != nil {
+		err := json.NewEncoder(&buf).Encode(args)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return buf.String(), nil
+}
+
 var nowMock int64
 
 // NowEpochSeconds ...
@@ -31,6 +31,51 @@ func KeyNamespacePrefix(namespace string) string {
 	return ns
 }
 
+// KeyJobs returns the key of the pending job list of the job name
+func KeyJobs(namespace string, jobName string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "jobs", jobName)
+}
+
+// KeyJobsPaused returns the key of the paused flag of the job name
+func KeyJobsPaused(namespace string, jobName string) string {
+	return fmt.Sprintf("%s:%s", KeyJobs(namespace, jobName), "paused")
+}
+
+// KeyJobsLock returns the key of the count of the running jobs of the job name
+func KeyJobsLock(namespace string, jobName string) string {
+	return fmt.Sprintf("%s:%s", KeyJobs(namespace, jobName), "lock")
+}
+
+// KeyJobsMaxConcurrency returns the key of the max number of the running jobs of the job name
+func KeyJobsMaxConcurrency(namespace string, jobName string) string {
+	return fmt.Sprintf("%s:%s", KeyJobs(namespace, jobName), "max_concurrency")
+}
+
+// KeyJobsRateLimit returns the key of the token bucket limiting the starts of the jobs of the job name
+func KeyJobsRateLimit(namespace string, jobName string) string {
+	return fmt.Sprintf("%s:%s", KeyJobs(namespace, jobName), "rate_limit")
+}
+
+// KeyKnownJobs returns the key of the set of the job names ever enqueued
+func KeyKnownJobs(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "known_jobs")
+}
+
+// KeyScheduled returns the key of the scheduled job set
+func KeyScheduled(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "scheduled")
+}
+
+// KeyRetry returns the key of the retry job set
+func KeyRetry(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "retry")
+}
+
+// KeyDead returns the key of the dead job set
+func KeyDead(namespace string) string {
+	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "dead")
+}
+
 // KeyPeriod returns the key of period
 func KeyPeriod(namespace string) string {
 	return fmt.Sprintf("%s%s", KeyNamespacePrefix(namespace), "period")
@@ -56,11 +101,81 @@ func KeyPeriodicLock(namespace string) string {
 	return fmt.Sprintf("%s:%s", KeyPeriod(namespace), "lock")
 }
 
+// KeyPeriodicExecutionWithdrawn returns the key of the flag of the withdrawn execution of the periodic job policy
+func KeyPeriodicExecutionWithdrawn(namespace string, policyID string, epoch int64) string {
+	return fmt.Sprintf("%s:%s:%s:%d", KeyPeriod(namespace), "withdrawn", policyID, epoch)
+}
+
 // KeyJobStats returns the key of job stats
 func KeyJobStats(namespace string, jobID string) string {
 	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_stats", jobID)
 }
 
+// KeyJobCheckIns returns the key of job check in history
+func KeyJobCheckIns(namespace string, jobID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_checkins", jobID)
+}
+
+// KeyJobStatusTimeline returns the key of job status timeline
+func KeyJobStatusTimeline(namespace string, jobID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_timeline", jobID)
+}
+
+// KeyJobResult returns the key of job result
+func KeyJobResult(namespace string, jobID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_result", jobID)
+}
+
+// KeyJobCheckpoints returns the key of job checkpoints
+func KeyJobCheckpoints(namespace string, jobID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_checkpoints", jobID)
+}
+
+// KeyJobChildren returns the key of the set of the unfinished child jobs awaited by the job
+func KeyJobChildren(namespace string, jobID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_children", jobID)
+}
+
+// KeyJobCallbacks returns the key of the follow-up jobs enqueued when the job ends
+func KeyJobCallbacks(namespace string, jobID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "job_callbacks", jobID)
+}
+
+// KeyIdempotency returns the key of the job submission with the idempotency key
+func KeyIdempotency(namespace string, idempotencyKey string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "idempotency", idempotencyKey)
+}
+
+// KeyBatch returns the key of the batch of jobs
+func KeyBatch(namespace string, batchID string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "batches", batchID)
+}
+
+// KeyBatchJobs returns the key of the job ID list of the batch
+func KeyBatchJobs(namespace string, batchID string) string {
+	return fmt.Sprintf("%s:%s", KeyBatch(namespace, batchID), "jobs")
+}
+
+// KeyBatchEnded returns the key of the set of the ended job IDs of the batch
+func KeyBatchEnded(namespace string, batchID string) string {
+	return fmt.Sprintf("%s:%s", KeyBatch(namespace, batchID), "ended")
+}
+
+// KeyLock returns the key of the holders of the named lock or semaphore
+func KeyLock(namespace string, name string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "locks", name)
+}
+
+// KeyPartition returns the key of the backlog of the jobs with the partition key
+func KeyPartition(namespace string, partitionKey string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "partitions", partitionKey)
+}
+
+// KeyPartitionRunning returns the key of the ID of the in-flight job with the partition key
+func KeyPartitionRunning(namespace string, partitionKey string) string {
+	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "partition_running", partitionKey)
+}
+
 // KeyJobCtlCommands give the key for publishing ctl commands like 'stop' etc.
 func KeyJobCtlCommands(namespace string, jobID string) string {
 	return fmt.Sprintf("%s%s:%s", KeyNamespacePrefix(namespace), "ctl_commands", jobID)